@@ -0,0 +1,230 @@
+package audiocache
+
+import (
+	"Twilight/utils"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Strum355/log"
+	"github.com/spf13/viper"
+)
+
+// Partial downloads older than this were abandoned by a crashed or killed download
+const abandonedAfter = time.Hour
+
+// Cache tracks the audio files downloaded into a folder and evicts the least recently played once it grows too large
+type Cache struct {
+	dir     string
+	maxSize int64         // Largest total size in bytes, 0 for no limit
+	maxAge  time.Duration // Files not played for longer are evicted, 0 to keep them until space runs out
+
+	// InUse returns the files currently queued or playing, which are never evicted
+	InUse func() []string
+
+	mu      sync.Mutex
+	entries map[string]*entry // Maps file name within dir to its entry
+	size    int64
+}
+
+// entry is a file within the cache
+type entry struct {
+	size     int64
+	lastUsed time.Time
+}
+
+// Default is the cache of downloaded audio, nil until Init is called
+var Default *Cache
+
+// New returns an empty cache of the files within dir
+func New(dir string, maxSize int64, maxAge time.Duration) *Cache {
+	return &Cache{
+		dir:     filepath.Clean(dir),
+		maxSize: maxSize,
+		maxAge:  maxAge,
+		entries: map[string]*entry{},
+	}
+}
+
+// Init sets up the default cache from the configuration and indexes the files already on disk
+func Init(inUse func() []string) *Cache {
+	c := New("cache",
+		int64(viper.GetInt("cache.max_size"))<<20,
+		time.Duration(viper.GetInt("cache.max_age"))*time.Hour)
+	c.InUse = inUse
+	if err := c.Load(); err != nil {
+		log.WithError(err).Error("Failed to index audio cache")
+	}
+	Default = c
+	return c
+}
+
+// Load rebuilds the index from the files on disk, removing abandoned partial downloads
+func (c *Cache) Load() error {
+	files, err := os.ReadDir(c.dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = map[string]*entry{}
+	c.size = 0
+	for _, file := range files {
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+		if utils.IsPartial(file.Name()) {
+			if time.Since(info.ModTime()) > abandonedAfter {
+				os.RemoveAll(filepath.Join(c.dir, file.Name()))
+			}
+			continue
+		}
+		if file.IsDir() {
+			continue
+		}
+		c.entries[file.Name()] = &entry{size: info.Size(), lastUsed: info.ModTime()}
+		c.size += info.Size()
+	}
+	return nil
+}
+
+// Add records a file which was just downloaded and evicts older files if the cache is now too large
+func (c *Cache) Add(filename string) {
+	name, ok := c.name(filename)
+	if !ok {
+		return
+	}
+	info, err := os.Stat(filename)
+	if err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	if e, exists := c.entries[name]; exists {
+		c.size -= e.size
+	}
+	c.entries[name] = &entry{size: info.Size(), lastUsed: time.Now()}
+	c.size += info.Size()
+	c.mu.Unlock()
+
+	c.Evict()
+}
+
+// Touch marks a file as played now
+func (c *Cache) Touch(filename string) {
+	name, ok := c.name(filename)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	c.mu.Lock()
+	if e, exists := c.entries[name]; exists {
+		e.lastUsed = now
+	}
+	c.mu.Unlock()
+
+	// Kept on disk as the modification time, which Load reads back so the order survives a restart
+	os.Chtimes(filename, now, now)
+}
+
+// Size returns the total size of the cached files in bytes
+func (c *Cache) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
+
+// Evict removes files not played within the max age, then the least recently played until the cache fits its max
+// size, skipping files in use, and returns the names of the removed files
+func (c *Cache) Evict() []string {
+	pinned := map[string]bool{}
+	if c.InUse != nil {
+		for _, filename := range c.InUse() {
+			if name, ok := c.name(filename); ok {
+				pinned[name] = true
+			}
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	names := make([]string, 0, len(c.entries))
+	for name := range c.entries {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return c.entries[names[i]].lastUsed.Before(c.entries[names[j]].lastUsed)
+	})
+
+	var evicted []string
+	for _, name := range names {
+		e := c.entries[name]
+		expired := c.maxAge > 0 && time.Since(e.lastUsed) > c.maxAge
+		if !expired && (c.maxSize <= 0 || c.size <= c.maxSize) {
+			if c.maxAge <= 0 {
+				break // Entries are in order, nothing later is over the size limit or expired either
+			}
+			continue
+		}
+		if pinned[name] {
+			continue
+		}
+
+		if err := os.Remove(filepath.Join(c.dir, name)); err != nil && !os.IsNotExist(err) {
+			log.WithError(err).WithFields(log.Fields{"file": name}).Warn("Failed to evict cached audio")
+			continue
+		}
+		delete(c.entries, name)
+		c.size -= e.size
+		evicted = append(evicted, name)
+	}
+	return evicted
+}
+
+// StartEviction periodically evicts files, picking up any which were removed or added outside of the cache
+func (c *Cache) StartEviction(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := c.Load(); err != nil {
+				log.WithError(err).Error("Failed to index audio cache")
+				continue
+			}
+			if evicted := c.Evict(); len(evicted) > 0 {
+				log.WithFields(log.Fields{"files": len(evicted), "size": c.Size()}).Info("Evicted cached audio")
+			}
+		}
+	}()
+}
+
+// name returns the name of a file within the cache folder, files elsewhere aren't cached
+func (c *Cache) name(filename string) (string, bool) {
+	if filepath.Dir(filepath.Clean(filename)) != c.dir {
+		return "", false
+	}
+	return filepath.Base(filename), true
+}
+
+// Add records a downloaded file within the default cache
+func Add(filename string) {
+	if Default != nil {
+		Default.Add(filename)
+	}
+}
+
+// Touch marks a file within the default cache as played now
+func Touch(filename string) {
+	if Default != nil {
+		Default.Touch(filename)
+	}
+}
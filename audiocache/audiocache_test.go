@@ -0,0 +1,120 @@
+package audiocache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeFile creates a cache file of the given size last played the given time ago
+func writeFile(t *testing.T, dir, name string, size int, age time.Duration) string {
+	filename := filepath.Join(dir, name)
+	assert.NoError(t, os.WriteFile(filename, make([]byte, size), 0o644))
+	played := time.Now().Add(-age)
+	assert.NoError(t, os.Chtimes(filename, played, played))
+	return filename
+}
+
+func TestLoadIndexesFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "a.opus", 10, time.Minute)
+	writeFile(t, dir, "b.opus", 20, time.Minute)
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, ".c.opus-1.part"), 0o755))
+	abandoned := filepath.Join(dir, ".d.opus-2.part")
+	assert.NoError(t, os.Mkdir(abandoned, 0o755))
+	old := time.Now().Add(-2 * time.Hour)
+	assert.NoError(t, os.Chtimes(abandoned, old, old))
+
+	c := New(dir, 0, 0)
+	assert.NoError(t, c.Load())
+
+	assert.Equal(t, int64(30), c.Size())
+	assert.DirExists(t, filepath.Join(dir, ".c.opus-1.part"))
+	assert.NoDirExists(t, abandoned)
+}
+
+func TestEvictLeastRecentlyPlayed(t *testing.T) {
+	dir := t.TempDir()
+	oldest := writeFile(t, dir, "a.opus", 10, 3*time.Hour)
+	middle := writeFile(t, dir, "b.opus", 10, 2*time.Hour)
+	newest := writeFile(t, dir, "c.opus", 10, time.Hour)
+
+	c := New(dir, 20, 0)
+	assert.NoError(t, c.Load())
+	evicted := c.Evict()
+
+	assert.Equal(t, []string{"a.opus"}, evicted)
+	assert.NoFileExists(t, oldest)
+	assert.FileExists(t, middle)
+	assert.FileExists(t, newest)
+	assert.Equal(t, int64(20), c.Size())
+}
+
+func TestEvictSkipsFilesInUse(t *testing.T) {
+	dir := t.TempDir()
+	playing := writeFile(t, dir, "a.opus", 10, 3*time.Hour)
+	writeFile(t, dir, "b.opus", 10, 2*time.Hour)
+	writeFile(t, dir, "c.opus", 10, time.Hour)
+
+	c := New(dir, 20, 0)
+	c.InUse = func() []string { return []string{playing, "/music/elsewhere.flac"} }
+	assert.NoError(t, c.Load())
+	evicted := c.Evict()
+
+	assert.Equal(t, []string{"b.opus"}, evicted)
+	assert.FileExists(t, playing)
+}
+
+func TestEvictExpiredFiles(t *testing.T) {
+	dir := t.TempDir()
+	stale := writeFile(t, dir, "a.opus", 10, 48*time.Hour)
+	fresh := writeFile(t, dir, "b.opus", 10, time.Hour)
+
+	c := New(dir, 0, 24*time.Hour)
+	assert.NoError(t, c.Load())
+	evicted := c.Evict()
+
+	assert.Equal(t, []string{"a.opus"}, evicted)
+	assert.NoFileExists(t, stale)
+	assert.FileExists(t, fresh)
+}
+
+func TestTouchKeepsFileAcrossRestarts(t *testing.T) {
+	dir := t.TempDir()
+	replayed := writeFile(t, dir, "a.opus", 10, 3*time.Hour)
+	other := writeFile(t, dir, "b.opus", 10, time.Hour)
+
+	c := New(dir, 10, 0)
+	assert.NoError(t, c.Load())
+	c.Touch(replayed)
+
+	// A fresh index reads the play time back from disk
+	restarted := New(dir, 10, 0)
+	assert.NoError(t, restarted.Load())
+	evicted := restarted.Evict()
+
+	assert.Equal(t, []string{"b.opus"}, evicted)
+	assert.FileExists(t, replayed)
+	assert.NoFileExists(t, other)
+}
+
+func TestAddEvictsOverMaxSize(t *testing.T) {
+	dir := t.TempDir()
+	old := writeFile(t, dir, "a.opus", 10, time.Hour)
+
+	c := New(dir, 15, 0)
+	assert.NoError(t, c.Load())
+	added := writeFile(t, dir, "b.opus", 10, 0)
+	c.Add(added)
+
+	assert.NoFileExists(t, old)
+	assert.FileExists(t, added)
+	assert.Equal(t, int64(10), c.Size())
+
+	// Files outside the cache folder are ignored
+	c.Add(filepath.Join(t.TempDir(), "c.opus"))
+	assert.Equal(t, int64(10), c.Size())
+}
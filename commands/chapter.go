@@ -0,0 +1,179 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"Twilight/queue"
+	"Twilight/source"
+	"Twilight/utils"
+	"Twilight/yt"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/spf13/viper"
+)
+
+// chapterCommand lists the chapters of the current song, jumps to one of them and loops it
+func chapterCommand(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) *interactionError {
+	// Check if user is in a voice channel and bot is not in a different one
+	if !checkUserVoiceChannel(s, i) {
+		return nil
+	}
+
+	gq, ok := queue.GetGuildQueue(i.GuildID)
+	if !ok || gq.Session.VC == nil || gq.CurrentSong == nil {
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{Content: "Nothing is playing right now 😶"},
+		})
+		return nil
+	}
+
+	_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+	})
+
+	track, err := songMetadata(ctx, gq.CurrentSong)
+	if err != nil {
+		content := "❌ Failed to fetch video details."
+		if reason := yt.Describe(err); reason != "" {
+			content = "❌ Failed to fetch video details, " + reason + "."
+		}
+		s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{Content: content})
+		return nil
+	}
+	chapters := songChapters(gq.CurrentSong, track)
+	if len(chapters) == 0 || track.Live {
+		s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+			Content: "❌ This song has no chapters!",
+		})
+		return nil
+	}
+
+	opts := optionMap(i.ApplicationCommandData().Options)
+	query := stringOption(opts, "chapter")
+	loopOpt, loopSet := opts["loop"]
+	if query == "" && !loopSet {
+		s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+			Embeds: []*discordgo.MessageEmbed{chaptersEmbed(track, chapters, gq.Session)},
+		})
+		return nil
+	}
+
+	index, found := yt.ChapterAt(chapters, gq.Session.Position())
+	if query != "" {
+		index, found = yt.FindChapter(chapters, query)
+		if !found {
+			s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+				Content: fmt.Sprintf("❌ No chapter matches `%s`! Use `/chapter` to list them.", query),
+			})
+			return nil
+		}
+	} else if !found {
+		s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+			Content: "❌ No chapter is playing right now!",
+		})
+		return nil
+	}
+	chapter := chapters[index]
+
+	var content string
+	switch {
+	case loopSet && loopOpt.BoolValue():
+		err = gq.Session.LoopSection(chapter.Start, chapter.End)
+		content = fmt.Sprintf("🔂 Looping chapter %d: **%s**", index+1, chapter.Title)
+	case loopSet:
+		gq.Session.StopLoop()
+		content = "➡️ Stopped looping the chapter"
+		if query != "" {
+			err = gq.Session.Seek(chapter.Start)
+			content = fmt.Sprintf("➡️ Stopped looping and jumped to chapter %d: **%s**", index+1, chapter.Title)
+		}
+	default:
+		gq.Session.StopLoop()
+		err = gq.Session.Seek(chapter.Start)
+		content = fmt.Sprintf("⏭️ Jumped to chapter %d: **%s**", index+1, chapter.Title)
+	}
+	if errors.Is(err, queue.ErrNotSeekable) {
+		content = "❌ The current song can't jump between chapters!"
+	}
+
+	s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+		Content: content,
+	})
+	return nil
+}
+
+// songChapters returns the chapters of a track which fall within the part of it a queued song plays
+func songChapters(song *queue.QueueSong, track *source.Track) []yt.Chapter {
+	var chapters []yt.Chapter
+	for _, chapter := range track.Chapters {
+		if chapter.Start >= song.Start && (song.End == 0 || chapter.Start < song.End) {
+			chapters = append(chapters, chapter)
+		}
+	}
+	return chapters
+}
+
+// currentChapter describes the chapter a session is playing, empty when the song has no chapters
+func currentChapter(song *queue.QueueSong, track *source.Track, session *queue.AudioSession) string {
+	chapters := songChapters(song, track)
+	position := session.Position()
+	index, ok := yt.ChapterAt(chapters, position)
+	if !ok {
+		return ""
+	}
+
+	chapter := chapters[index]
+	text := fmt.Sprintf("%d. %s (`%s`", index+1, chapter.Title, utils.FormatYtDuration(position-chapter.Start))
+	if chapter.End > chapter.Start {
+		text += " / `" + utils.FormatYtDuration(chapter.End-chapter.Start) + "`"
+	}
+	text += ")"
+	if start, end, looping := session.LoopedSection(); looping && start == chapter.Start && end == chapter.End {
+		text += " 🔂"
+	}
+	return text
+}
+
+// chaptersEmbed lists the chapters of a song, marking the one playing
+func chaptersEmbed(track *source.Track, chapters []yt.Chapter, session *queue.AudioSession) *discordgo.MessageEmbed {
+	current, playing := yt.ChapterAt(chapters, session.Position())
+	loopStart, loopEnd, looping := session.LoopedSection()
+
+	const limit = 25
+	description := ""
+	for idx, chapter := range chapters[:min(len(chapters), limit)] {
+		description += fmt.Sprintf("%d. `%s` %s", idx+1, utils.FormatYtDuration(chapter.Start), chapter.Title)
+		if playing && idx == current {
+			description += " ▶️"
+		}
+		if looping && chapter.Start == loopStart && chapter.End == loopEnd {
+			description += " 🔂"
+		}
+		description += "\n"
+	}
+	if len(chapters) > limit {
+		description += fmt.Sprintf("...and %d more", len(chapters)-limit)
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("📖 Chapters of %s", track.Title),
+		URL:         track.URL,
+		Description: description,
+		Color:       viper.GetInt("theme"),
+	}
+	if track.Thumbnail != "" {
+		embed.Thumbnail = &discordgo.MessageEmbedThumbnail{URL: track.Thumbnail}
+	}
+	return embed
+}
+
+// splitChapters reports whether /play should queue each chapter of a song as its own entry
+func splitChapters(opts map[string]*discordgo.ApplicationCommandInteractionDataOption) bool {
+	if opt, ok := opts["chapters"]; ok {
+		return opt.BoolValue()
+	}
+	return viper.GetBool("queue.split_chapters")
+}
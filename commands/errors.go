@@ -1,6 +1,8 @@
 package commands
 
 import (
+	"Twilight/yt"
+
 	"github.com/Strum355/log"
 	"github.com/bwmarrin/discordgo"
 )
@@ -30,9 +32,14 @@ func sendErrorResponse(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	})
 }
 
-func sendFetchErrorResponse(s *discordgo.Session, i *discordgo.InteractionCreate) {
+// sendFetchErrorResponse tells the user video details couldn't be fetched, along with why when it is known
+func sendFetchErrorResponse(s *discordgo.Session, i *discordgo.InteractionCreate, err error) {
+	content := "❌ Failed to fetch video details."
+	if reason := yt.Describe(err); reason != "" {
+		content = "❌ Failed to fetch video details, " + reason + "."
+	}
 	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
-		Data: &discordgo.InteractionResponseData{Content: "❌ Failed to fetch video details."},
+		Data: &discordgo.InteractionResponseData{Content: content},
 	})
 }
@@ -0,0 +1,134 @@
+package commands
+
+import (
+	"Twilight/db_client"
+	"Twilight/library"
+	"Twilight/queue"
+	"Twilight/source"
+	"Twilight/utils"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/spf13/viper"
+)
+
+const maxLibraryResults = 15
+
+// musicLibrary handles the library subcommands for searching and playing tracks from the local music library
+func musicLibrary(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) *interactionError {
+	options := i.ApplicationCommandData().Options
+	subCmd := options[0].Name
+	opts := optionMap(options[0].Options)
+
+	// Check if user is in a voice channel and bot is not in a different one
+	if subCmd == "play" && !checkUserVoiceChannel(s, i) {
+		return nil
+	}
+
+	_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+	})
+
+	lib := library.New(db_client.DB)
+	if !lib.Enabled() {
+		s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+			Content: "📁 The music library isn't set up on this bot",
+		})
+		return nil
+	}
+
+	query := stringOption(opts, "query")
+	limit := maxLibraryResults
+	if subCmd == "play" && !boolOption(opts, "all") {
+		limit = 1
+	}
+	tracks, err := lib.Search(query, limit)
+	if err != nil {
+		s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+			Content: "Oops! Something went wrong while searching the library. 😅",
+		})
+		return nil
+	}
+	if len(tracks) == 0 {
+		s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+			Content: "No tracks in the library match `" + query + "` 🔍",
+		})
+		return nil
+	}
+
+	switch subCmd {
+	case "search":
+		s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+			Embeds: []*discordgo.MessageEmbed{createLibraryEmbed(query, tracks)},
+		})
+	case "play":
+		vc, err := connectUserVoiceChannel(s, i.GuildID, i.Member.User.ID)
+		if err != nil {
+			return nil
+		}
+
+		gq := queue.EnqueueSongs(i.GuildID, librarySongs(lib, tracks, i)...)
+		if gq.Session.VC == nil {
+			go queue.PlayNext(s, i.GuildID, vc)
+		}
+
+		content := fmt.Sprintf("🎵 Queued `%d` tracks from the library!", len(tracks))
+		if len(tracks) == 1 {
+			content = fmt.Sprintf("🎵 **%s** added to the queue (`%s`)", trackLabel(tracks[0]), utils.FormatYtDuration(time.Duration(tracks[0].Duration)*time.Second))
+		}
+		s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+			Content: content,
+		})
+	}
+	return nil
+}
+
+// librarySongs turns library tracks into songs requested by the user of an interaction, which are played in place
+// and never downloaded or cached
+func librarySongs(lib *library.Library, tracks []library.Track, i *discordgo.InteractionCreate) []*queue.QueueSong {
+	songs := make([]*queue.QueueSong, 0, len(tracks))
+	for _, track := range tracks {
+		filename := lib.FullPath(track)
+		songs = append(songs, queue.NewTrackSong(&source.Track{
+			Source:   source.LocalName,
+			ID:       filename,
+			URL:      filename,
+			Title:    trackLabel(track),
+			Author:   track.Artist,
+			Album:    track.Album,
+			Duration: time.Duration(track.Duration) * time.Second,
+		}, i.Member.User.Username, i.ChannelID))
+	}
+	return songs
+}
+
+// createLibraryEmbed lists library search results
+func createLibraryEmbed(query string, tracks []library.Track) *discordgo.MessageEmbed {
+	text := ""
+	for idx, track := range tracks {
+		text += fmt.Sprintf("%d. `%s`", idx+1, trackLabel(track))
+		if track.Album != "" {
+			text += " from " + track.Album
+		}
+		text += fmt.Sprintf(" `[%s]`\n", utils.FormatYtDuration(time.Duration(track.Duration)*time.Second))
+	}
+
+	return &discordgo.MessageEmbed{
+		Title:       "📁 Library: " + query,
+		Description: text,
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: fmt.Sprintf("%d result(s), play them with /library play", len(tracks)),
+		},
+		Color: viper.GetInt("theme"),
+	}
+}
+
+// trackLabel names a library track as artist - title when the artist is known
+func trackLabel(track library.Track) string {
+	if track.Artist == "" {
+		return track.Title
+	}
+	return track.Artist + " - " + track.Title
+}
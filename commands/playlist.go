@@ -18,27 +18,47 @@ func playList(ctx context.Context, s *discordgo.Session, i *discordgo.Interactio
 	data := i.ApplicationCommandData()
 	options := data.Options
 
-	value := ""
-
 	subCmd := options[0].Name
-	if len(options[0].Options) > 0 {
-		value = options[0].Options[0].StringValue()
-	}
+	opts := optionMap(options[0].Options)
 
 	pm := playlist.NewManager(s, redis_client.RDB, db_client.DB)
 	pm.EnsureUserExists(i)
 
+	name := stringOption(opts, "playlist")
+
 	switch subCmd {
+	case "guild":
+		guildPlaylist(pm, i, options[0].Options[0])
 	case "view":
-		pm.ShowPlaylist(i)
+		pm.ShowPlaylist(i, name)
 	case "add":
-		pm.AddSong(i, value)
+		pm.AddSong(ctx, i, stringOption(opts, "song"), intOption(opts, "position"), name)
 	case "addplaylist":
-		pm.AddPlaylist(i, value)
+		pm.AddPlaylist(ctx, i, stringOption(opts, "url"), name)
 	case "remove":
-		pm.RemoveSong(i, value)
+		pm.RemoveSong(i, stringOption(opts, "song"), name)
+	case "move":
+		pm.MoveSong(i, intOption(opts, "from"), intOption(opts, "to"), name)
 	case "clear":
-		pm.ClearPlaylist(i)
+		pm.ClearPlaylist(i, name)
+	case "export":
+		pm.ExportPlaylist(i, playlist.Format(stringOption(opts, "format")), name)
+	case "import":
+		pm.ImportPlaylist(ctx, i, attachmentOption(data, opts, "file"), name)
+	case "share":
+		pm.SharePlaylist(i, intOption(opts, "expires"), name)
+	case "clone":
+		pm.ClonePlaylist(i, stringOption(opts, "code"), name)
+	case "link":
+		pm.LinkSource(ctx, i, stringOption(opts, "url"), boolOption(opts, "prune"))
+	case "unlink":
+		pm.UnlinkSource(i)
+	case "sync":
+		pm.SyncPlaylist(ctx, i)
+	case "search":
+		searchPlaylist(s, pm, i, opts, name)
+	case "check":
+		pm.CheckPlaylist(ctx, i, name)
 	case "play":
 		// Check if user is in a voice channel and bot is not in a different one
 		if !checkUserVoiceChannel(s, i) {
@@ -48,9 +68,90 @@ func playList(ctx context.Context, s *discordgo.Session, i *discordgo.Interactio
 		if err != nil {
 			return nil
 		}
-		pm.PlaySong(i, value, vc)
+		pm.PlaySong(i, stringOption(opts, "song"), playlist.PlayOptions{
+			Shuffle: boolOption(opts, "shuffle"),
+			Start:   stringOption(opts, "start"),
+			Count:   intOption(opts, "count"),
+			Replace: stringOption(opts, "mode") == "replace",
+		}, name, vc)
 	default:
-		pm.ShowPlaylist(i)
+		pm.ShowPlaylist(i, name)
+	}
+	return nil
+}
+
+// playlistCleanup handles the button removing unavailable songs from a checked playlist
+func playlistCleanup(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) *interactionError {
+	_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredMessageUpdate,
+	})
+
+	pm := playlist.NewManager(s, redis_client.RDB, db_client.DB)
+	pm.RemoveUnavailable(i)
+	return nil
+}
+
+// searchPlaylist parses the search filters and searches a playlist
+func searchPlaylist(s *discordgo.Session, pm *playlist.PlaylistManager, i *discordgo.InteractionCreate, opts map[string]*discordgo.ApplicationCommandInteractionDataOption, name string) {
+	minDuration, minErr := playlist.ParseDuration(stringOption(opts, "min"))
+	maxDuration, maxErr := playlist.ParseDuration(stringOption(opts, "max"))
+	if minErr != nil || maxErr != nil {
+		s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+			Content: "Durations look like `3:30` or `1:02:00` ⏱️",
+		})
+		return
+	}
+
+	pm.SearchPlaylist(i, playlist.SearchFilter{
+		Query:       stringOption(opts, "query"),
+		Author:      stringOption(opts, "author"),
+		MinDuration: minDuration,
+		MaxDuration: maxDuration,
+	}, name)
+}
+
+// playlistSearch handles the select menus for queuing or removing playlist search results
+func playlistSearch(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) *interactionError {
+	queueing := playlist.IsSearchQueue(i.MessageComponentData().CustomID)
+
+	// Check if user is in a voice channel and bot is not in a different one
+	if queueing && !checkUserVoiceChannel(s, i) {
+		return nil
+	}
+
+	_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredMessageUpdate,
+	})
+
+	pm := playlist.NewManager(s, redis_client.RDB, db_client.DB)
+	if !queueing {
+		pm.RemoveSelected(i)
+		return nil
+	}
+
+	vc, err := connectUserVoiceChannel(s, i.GuildID, i.Member.User.ID)
+	if err != nil {
+		return nil
 	}
+	pm.QueueSelected(i, vc)
 	return nil
 }
+
+// guildPlaylist handles the guild playlist management sub commands
+func guildPlaylist(pm *playlist.PlaylistManager, i *discordgo.InteractionCreate, subCmd *discordgo.ApplicationCommandInteractionDataOption) {
+	opts := optionMap(subCmd.Options)
+	name := stringOption(opts, "name")
+
+	switch subCmd.Name {
+	case "create":
+		pm.CreateGuildPlaylist(i, name, playlist.Role(stringOption(opts, "access")))
+	case "delete":
+		pm.DeleteGuildPlaylist(i, name)
+	case "grant":
+		pm.GrantAccess(i, name, opts["member"].UserValue(nil).ID, playlist.Role(stringOption(opts, "role")))
+	case "revoke":
+		pm.RevokeAccess(i, name, opts["member"].UserValue(nil).ID)
+	default:
+		pm.ListGuildPlaylists(i)
+	}
+}
@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"Twilight/playlist"
 	"context"
 	"errors"
 
@@ -11,16 +12,36 @@ import (
 
 // RegisterSlashCommands adds all slash commands to the session.
 func RegisterSlashCommands(s *discordgo.Session) {
+	minPosition := 1.0
+	guildPlaylistOption := &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionString,
+		Name:        "playlist",
+		Description: "Name of a guild playlist, defaults to your personal playlist",
+		Required:    false,
+	}
+
 	commands.Add(
 		&discordgo.ApplicationCommand{
 			Name:        "play",
-			Description: "Play a song from a YouTube URL.",
+			Description: "Play a song from a supported site, a media file, an upload, a live stream or internet radio.",
 			Options: []*discordgo.ApplicationCommandOption{
 				{
 					Type:        discordgo.ApplicationCommandOptionString,
 					Name:        "url",
-					Description: "YouTube link for the song",
-					Required:    true,
+					Description: "Link to the song or media file",
+					Required:    false,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionAttachment,
+					Name:        "file",
+					Description: "Audio or video file to play",
+					Required:    false,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+					Name:        "chapters",
+					Description: "Queue each chapter of the song as its own entry",
+					Required:    false,
 				},
 			},
 		},
@@ -30,12 +51,12 @@ func RegisterSlashCommands(s *discordgo.Session) {
 	commands.Add(
 		&discordgo.ApplicationCommand{
 			Name:        "playplaylist",
-			Description: "Play a playlist from a YouTube URL.",
+			Description: "Play a playlist from a YouTube, SoundCloud, Bandcamp or other supported URL.",
 			Options: []*discordgo.ApplicationCommandOption{
 				{
 					Type:        discordgo.ApplicationCommandOptionString,
 					Name:        "url",
-					Description: "YouTube link for the playlist",
+					Description: "Link to the playlist, set or album",
 					Required:    true,
 				},
 			},
@@ -123,6 +144,28 @@ func RegisterSlashCommands(s *discordgo.Session) {
 		currentSong,
 	)
 
+	commands.Add(
+		&discordgo.ApplicationCommand{
+			Name:        "chapter",
+			Description: "List the chapters of the current song, jump to one or loop it.",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "chapter",
+					Description: "Name or number of the chapter to jump to",
+					Required:    false,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+					Name:        "loop",
+					Description: "Loop the chapter, or stop looping it",
+					Required:    false,
+				},
+			},
+		},
+		chapterCommand,
+	)
+
 	commands.Add(
 		&discordgo.ApplicationCommand{
 			Name:        "playlist",
@@ -132,6 +175,9 @@ func RegisterSlashCommands(s *discordgo.Session) {
 					Type:        discordgo.ApplicationCommandOptionSubCommand,
 					Name:        "view",
 					Description: "View your current playlist",
+					Options: []*discordgo.ApplicationCommandOption{
+						guildPlaylistOption,
+					},
 				},
 				{
 					Type:        discordgo.ApplicationCommandOptionSubCommand,
@@ -144,6 +190,14 @@ func RegisterSlashCommands(s *discordgo.Session) {
 							Description: "YouTube video ID",
 							Required:    true,
 						},
+						{
+							Type:        discordgo.ApplicationCommandOptionInteger,
+							Name:        "position",
+							Description: "Position to insert the song at, defaults to the end",
+							Required:    false,
+							MinValue:    &minPosition,
+						},
+						guildPlaylistOption,
 					},
 				},
 				{
@@ -157,6 +211,7 @@ func RegisterSlashCommands(s *discordgo.Session) {
 							Description: "YouTube playlist URL",
 							Required:    true,
 						},
+						guildPlaylistOption,
 					},
 				},
 				{
@@ -170,12 +225,38 @@ func RegisterSlashCommands(s *discordgo.Session) {
 							Description: "YouTube video ID",
 							Required:    true,
 						},
+						guildPlaylistOption,
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "move",
+					Description: "Move a song to a different position within your playlist",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionInteger,
+							Name:        "from",
+							Description: "Current position of the song",
+							Required:    true,
+							MinValue:    &minPosition,
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionInteger,
+							Name:        "to",
+							Description: "New position of the song",
+							Required:    true,
+							MinValue:    &minPosition,
+						},
+						guildPlaylistOption,
 					},
 				},
 				{
 					Type:        discordgo.ApplicationCommandOptionSubCommand,
 					Name:        "clear",
 					Description: "Clear your entire playlist",
+					Options: []*discordgo.ApplicationCommandOption{
+						guildPlaylistOption,
+					},
 				},
 				{
 					Type:        discordgo.ApplicationCommandOptionSubCommand,
@@ -188,6 +269,265 @@ func RegisterSlashCommands(s *discordgo.Session) {
 							Description: "YouTube video ID",
 							Required:    false,
 						},
+						{
+							Type:        discordgo.ApplicationCommandOptionBoolean,
+							Name:        "shuffle",
+							Description: "Shuffle the playlist instead of playing it in order",
+							Required:    false,
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "start",
+							Description: "Position or YouTube video ID to start the playlist from",
+							Required:    false,
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionInteger,
+							Name:        "count",
+							Description: "Maximum number of songs to queue",
+							Required:    false,
+							MinValue:    &minPosition,
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "mode",
+							Description: "Add to the current queue or replace it, defaults to append",
+							Required:    false,
+							Choices: []*discordgo.ApplicationCommandOptionChoice{
+								{Name: "append", Value: "append"},
+								{Name: "replace", Value: "replace"},
+							},
+						},
+						guildPlaylistOption,
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "export",
+					Description: "Download your playlist as a file",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "format",
+							Description: "File format to export as",
+							Required:    true,
+							Choices: []*discordgo.ApplicationCommandOptionChoice{
+								{Name: "M3U", Value: "m3u"},
+								{Name: "JSON", Value: "json"},
+								{Name: "CSV", Value: "csv"},
+							},
+						},
+						guildPlaylistOption,
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "import",
+					Description: "Add the songs from an M3U, JSON or CSV file to your playlist",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionAttachment,
+							Name:        "file",
+							Description: "Playlist file to import",
+							Required:    true,
+						},
+						guildPlaylistOption,
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "share",
+					Description: "Create a code friends can use to copy your playlist",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionInteger,
+							Name:        "expires",
+							Description: "Hours until the code expires, never expires if omitted",
+							Required:    false,
+							MinValue:    &minPosition,
+						},
+						guildPlaylistOption,
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "clone",
+					Description: "Copy a shared playlist into your playlist",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "code",
+							Description: "Share code from /playlist share",
+							Required:    true,
+						},
+						guildPlaylistOption,
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "link",
+					Description: "Keep your playlist in sync with a YouTube playlist",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "url",
+							Description: "YouTube playlist URL",
+							Required:    true,
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionBoolean,
+							Name:        "prune",
+							Description: "Remove songs from your playlist when they are removed from the YouTube playlist",
+							Required:    false,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "search",
+					Description: "Search your playlist by title and author",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "query",
+							Description: "Words to match against song titles and authors",
+							Required:    true,
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "author",
+							Description: "Only match songs by this author",
+							Required:    false,
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "min",
+							Description: "Minimum duration, e.g. 2:30",
+							Required:    false,
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "max",
+							Description: "Maximum duration, e.g. 10:00",
+							Required:    false,
+						},
+						guildPlaylistOption,
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "check",
+					Description: "Check your playlist for videos which are no longer available",
+					Options: []*discordgo.ApplicationCommandOption{
+						guildPlaylistOption,
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "unlink",
+					Description: "Stop syncing your playlist with its YouTube playlist",
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "sync",
+					Description: "Sync your playlist with its linked YouTube playlist now",
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommandGroup,
+					Name:        "guild",
+					Description: "Manage playlists shared with the server",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionSubCommand,
+							Name:        "list",
+							Description: "List the guild playlists you have access to",
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionSubCommand,
+							Name:        "create",
+							Description: "Create a playlist owned by you and shared with the server",
+							Options: []*discordgo.ApplicationCommandOption{
+								{
+									Type:        discordgo.ApplicationCommandOptionString,
+									Name:        "name",
+									Description: "Name of the guild playlist",
+									Required:    true,
+									MaxLength:   50,
+								},
+								{
+									Type:        discordgo.ApplicationCommandOptionString,
+									Name:        "access",
+									Description: "Access every server member has, defaults to viewer",
+									Required:    false,
+									Choices: []*discordgo.ApplicationCommandOptionChoice{
+										{Name: "none", Value: "none"},
+										{Name: "viewer", Value: "viewer"},
+										{Name: "editor", Value: "editor"},
+									},
+								},
+							},
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionSubCommand,
+							Name:        "delete",
+							Description: "Delete a guild playlist you own",
+							Options: []*discordgo.ApplicationCommandOption{
+								{
+									Type:        discordgo.ApplicationCommandOptionString,
+									Name:        "name",
+									Description: "Name of the guild playlist",
+									Required:    true,
+								},
+							},
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionSubCommand,
+							Name:        "grant",
+							Description: "Give a member access to a guild playlist",
+							Options: []*discordgo.ApplicationCommandOption{
+								{
+									Type:        discordgo.ApplicationCommandOptionString,
+									Name:        "name",
+									Description: "Name of the guild playlist",
+									Required:    true,
+								},
+								{
+									Type:        discordgo.ApplicationCommandOptionUser,
+									Name:        "member",
+									Description: "Member to give access to",
+									Required:    true,
+								},
+								{
+									Type:        discordgo.ApplicationCommandOptionString,
+									Name:        "role",
+									Description: "Access to give the member",
+									Required:    true,
+									Choices: []*discordgo.ApplicationCommandOptionChoice{
+										{Name: "viewer", Value: "viewer"},
+										{Name: "editor", Value: "editor"},
+									},
+								},
+							},
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionSubCommand,
+							Name:        "revoke",
+							Description: "Remove a members access to a guild playlist",
+							Options: []*discordgo.ApplicationCommandOption{
+								{
+									Type:        discordgo.ApplicationCommandOptionString,
+									Name:        "name",
+									Description: "Name of the guild playlist",
+									Required:    true,
+								},
+								{
+									Type:        discordgo.ApplicationCommandOptionUser,
+									Name:        "member",
+									Description: "Member to remove access from",
+									Required:    true,
+								},
+							},
+						},
 					},
 				},
 			},
@@ -195,6 +535,48 @@ func RegisterSlashCommands(s *discordgo.Session) {
 		playList,
 	)
 
+	commands.Add(
+		&discordgo.ApplicationCommand{
+			Name:        "library",
+			Description: "Search and play tracks from the local music library.",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "search",
+					Description: "Search the library by title, artist or album",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "query",
+							Description: "Title, artist or album to search for",
+							Required:    true,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "play",
+					Description: "Play the best match from the library, or every match",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "query",
+							Description: "Title, artist or album to play",
+							Required:    true,
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionBoolean,
+							Name:        "all",
+							Description: "Queue every match instead of only the best one",
+							Required:    false,
+						},
+					},
+				},
+			},
+		},
+		musicLibrary,
+	)
+
 	commands.Add(
 		&discordgo.ApplicationCommand{
 			Name:        "disconnect",
@@ -211,6 +593,9 @@ func RegisterSlashCommands(s *discordgo.Session) {
 		stopSong,
 	)
 
+	commands.AddComponent(playlist.CleanupPrefix, playlistCleanup)
+	commands.AddComponent(playlist.SearchPrefix, playlistSearch)
+
 	if err := commands.Register(s); err != nil {
 		log.WithError(err).Error("Failed to register slash commands")
 	}
@@ -0,0 +1,119 @@
+package commands
+
+import (
+	"Twilight/queue"
+	"Twilight/resolver"
+	"Twilight/source"
+	"Twilight/utils"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	lowConfidence       = 0.6 // Matches below this are listed so members can check them
+	maxLowConfidenceRow = 5
+)
+
+// playCatalogLink matches the tracks behind a Spotify or Apple Music link to YouTube videos and queues them like a playlist
+func playCatalogLink(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, r *resolver.Resolver, rawURL string) {
+	initialMsg, err := s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+		Content: "🔎 Finding these tracks on YouTube...",
+	})
+	if err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Matches are queued as they are found, so the first songs play while the rest of a long album is still searched
+	var vc *discordgo.VoiceConnection
+	connectFailed, playing := false, false
+	result, err := r.Resolve(ctx, rawURL, func(matches []resolver.Match) {
+		if connectFailed {
+			return
+		}
+		if vc == nil {
+			vc, _ = connectUserVoiceChannel(s, i.GuildID, i.Member.User.ID)
+			if vc == nil {
+				connectFailed = true
+				cancel()
+				return
+			}
+		}
+
+		gq := queue.EnqueueSongs(i.GuildID, matchSongs(matches, i)...)
+		if gq.Session.VC == nil && !playing {
+			playing = true
+			go queue.PlayNext(s, i.GuildID, vc)
+		}
+	})
+	if connectFailed {
+		return
+	}
+
+	// Edited as a channel message, the interaction token can expire before long playlists finish being searched
+	if err != nil || len(result.Matches) == 0 {
+		content := "❌ Could not fetch the tracks behind that link."
+		switch {
+		case errors.Is(err, resolver.ErrNoCredentials):
+			content = "❌ Spotify links aren't set up on this bot."
+		case errors.Is(err, resolver.ErrApplePlaylist):
+			content = "❌ Apple Music playlists can't be played, try a song or album link."
+		case err == nil:
+			content = "❌ None of those tracks could be found on YouTube."
+		}
+		s.ChannelMessageEdit(initialMsg.ChannelID, initialMsg.ID, content)
+		return
+	}
+
+	s.ChannelMessageEdit(initialMsg.ChannelID, initialMsg.ID, catalogMessage(result))
+}
+
+// matchSongs turns matched catalog tracks into songs requested by the user of an interaction
+func matchSongs(matches []resolver.Match, i *discordgo.InteractionCreate) []*queue.QueueSong {
+	songs := make([]*queue.QueueSong, 0, len(matches))
+	for _, m := range matches {
+		songs = append(songs, queue.NewTrackSong(&source.Track{
+			Source: source.YouTubeName,
+			ID:     m.Candidate.ID,
+			URL:    "https://www.youtube.com/watch?v=" + m.Candidate.ID,
+			Title:  m.Candidate.Title,
+		}, i.Member.User.Username, i.ChannelID))
+	}
+	return songs
+}
+
+// catalogMessage describes the queued matches with their confidence, listing the least certain ones
+func catalogMessage(result *resolver.Result) string {
+	if len(result.Matches) == 1 && len(result.Unmatched) == 0 {
+		m := result.Matches[0]
+		return fmt.Sprintf("🎵 **%s** added to the queue (`%s`)\nMatched `%s` from %s with `%.0f%%` confidence",
+			m.Candidate.Title, utils.FormatYtDuration(m.Candidate.Duration), m.Track.Query(), result.Catalog, m.Confidence*100)
+	}
+
+	total := 0.0
+	var low []resolver.Match
+	for _, m := range result.Matches {
+		total += m.Confidence
+		if m.Confidence < lowConfidence {
+			low = append(low, m)
+		}
+	}
+
+	content := fmt.Sprintf("🎵 Queued `%d` songs from %s with `%.0f%%` average confidence!", len(result.Matches), result.Catalog, total/float64(len(result.Matches))*100)
+	for idx, m := range low {
+		if idx == maxLowConfidenceRow {
+			content += fmt.Sprintf("\n...and %d more low confidence matches", len(low)-maxLowConfidenceRow)
+			break
+		}
+		content += fmt.Sprintf("\n⚠️ `%s` → `%s` (`%.0f%%`)", m.Track.Query(), m.Candidate.Title, m.Confidence*100)
+	}
+	if len(result.Unmatched) > 0 {
+		content += fmt.Sprintf("\n• `%d` track(s) couldn't be found on YouTube", len(result.Unmatched))
+	}
+	return content
+}
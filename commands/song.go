@@ -2,15 +2,16 @@ package commands
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"os"
 
-	"Twilight/playlist"
 	"Twilight/queue"
 	"Twilight/redis_client"
-	"Twilight/utils"
+	"Twilight/resolver"
+	"Twilight/source"
 	"Twilight/yt"
 
+	"github.com/Strum355/log"
 	"github.com/bwmarrin/discordgo"
 	"github.com/kkdai/youtube/v2"
 	"github.com/spf13/viper"
@@ -29,9 +30,9 @@ func songInfo(ctx context.Context, s *discordgo.Session, i *discordgo.Interactio
 	}
 	ytManager := yt.NewYouTubeManager(redis_client.RDB)
 
-	videoMetadata, err := ytManager.GetVideoMetadata(videoID)
+	videoMetadata, err := ytManager.GetVideoMetadata(ctx, videoID)
 	if err != nil {
-		sendFetchErrorResponse(s, i)
+		sendFetchErrorResponse(s, i, err)
 		return nil
 	}
 	embed := &discordgo.MessageEmbed{
@@ -62,11 +63,35 @@ func playSong(ctx context.Context, s *discordgo.Session, i *discordgo.Interactio
 		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
 	})
 
-	videoURL := i.ApplicationCommandData().Options[0].StringValue()
-	videoID, err := youtube.ExtractVideoID(videoURL)
+	// Uploaded files are played directly, links are dispatched on their host to the provider which handles them
+	data := i.ApplicationCommandData()
+	opts := optionMap(data.Options)
+	songURL := stringOption(opts, "url")
+	var provider source.Provider
+	var err error
+	if attachment := attachmentOption(data, opts, "file"); attachment != nil {
+		if !isMediaAttachment(attachment) {
+			s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+				Content: "❌ That file doesn't look like audio or video!",
+			})
+			return nil
+		}
+		songURL = attachment.URL
+		provider, err = source.Get(source.DirectName)
+	} else if r := resolver.Default(); r.Handles(songURL) {
+		playCatalogLink(ctx, s, i, r, songURL)
+		return nil
+	} else if songURL != "" {
+		provider, err = source.Detect(ctx, songURL)
+	} else {
+		s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+			Content: "❌ Give a link or upload a file to play!",
+		})
+		return nil
+	}
 	if err != nil {
 		s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
-			Content: "❌ Invalid YouTube link!",
+			Content: "❌ Unsupported link! Try YouTube, another supported site or a link to a media file.",
 		})
 		return nil
 	}
@@ -75,33 +100,36 @@ func playSong(ctx context.Context, s *discordgo.Session, i *discordgo.Interactio
 	if err != nil {
 		return nil
 	}
-	ytManager := yt.NewYouTubeManager(redis_client.RDB)
 
-	filename := utils.GetAudioFile(videoID)
-	if _, err := os.Stat(filename); os.IsNotExist(err) {
-		err := ytManager.DownloadAudio(videoID)
-		if err != nil {
-			fmt.Printf("DEBUG: Download error: %v\n", err)
-			s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
-				Content: "❌ Could not fetch the video. It may be private or removed.",
-			})
-			return nil
-		}
-	}
-
-	currentVideo, err := ytManager.GetVideoMetadata(videoID)
+	track, err := provider.Resolve(ctx, songURL)
 	if err != nil {
+		content := "❌ Could not fetch the song. It may be private or removed."
+		switch {
+		case yt.Describe(err) != "":
+			content = "❌ Could not fetch the song, " + yt.Describe(err) + "."
+		case errors.Is(err, source.ErrNotAudio):
+			content = "❌ That file has no audio to play!"
+		case errors.Is(err, source.ErrTooLarge):
+			content = fmt.Sprintf("❌ That file is too large! The limit is `%dMB`.", viper.GetInt("sources.direct.max_size"))
+		}
 		s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
-			Content: "❌ Could not fetch video metadata.",
+			Content: content,
 		})
 		return nil
 	}
 
+	songs := []*queue.QueueSong{queue.NewTrackSong(track, i.Member.User.Username, i.ChannelID)}
+	content := fmt.Sprintf("🎵 **%s** added to the queue (`%s`)", track.Title, trackLength(track))
+	if splitChapters(opts) && len(track.Chapters) > 1 && !track.Live {
+		songs = queue.NewChapterSongs(track, i.Member.User.Username, i.ChannelID)
+		content = fmt.Sprintf("🎵 **%s** added to the queue as `%d` chapters (`%s`)", track.Title, len(songs), trackLength(track))
+	}
 	s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
-		Content: fmt.Sprintf("🎵 **%s** added to the queue (`%s`)", currentVideo.Title, utils.FormatYtDuration(currentVideo.Duration)),
+		Content: content,
 	})
 
-	gq := queue.Enqueue(i.GuildID, filename, i.Member.User.Username)
+	// Audio is downloaded by the queue just ahead of playback
+	gq := queue.EnqueueSongs(i.GuildID, songs...)
 	if gq.Session.VC == nil {
 		go queue.PlayNext(s, i.GuildID, vc)
 	}
@@ -120,18 +148,34 @@ func playPlaylistSong(ctx context.Context, s *discordgo.Session, i *discordgo.In
 		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
 	})
 
-	ytManager := yt.NewYouTubeManager(redis_client.RDB)
+	playlistURL := i.ApplicationCommandData().Options[0].StringValue()
+	if r := resolver.Default(); r.Handles(playlistURL) {
+		playCatalogLink(ctx, s, i, r, playlistURL)
+		return nil
+	}
 
-	videoURL := i.ApplicationCommandData().Options[0].StringValue()
-	videoIDs, err := ytManager.GetPlaylistVideoIDs(videoURL)
+	provider, err := source.ForURL(playlistURL)
 	if err != nil {
+		s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+			Content: "❌ Unsupported link! Try YouTube or another supported site.",
+		})
+		return nil
+	}
+
+	if pager, ok := provider.(source.Pager); ok {
+		playPagedPlaylist(ctx, s, i, pager, playlistURL)
+		return nil
+	}
+
+	tracks, err := provider.Playlist(ctx, playlistURL)
+	if err != nil || len(tracks) == 0 {
 		s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
 			Content: "❌ Invalid Playlist link!",
 		})
 		return nil
 	}
 	initialMsg, err := s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
-		Content: fmt.Sprintf("Queuing %d song(s) from the playlist...", len(videoIDs)),
+		Content: fmt.Sprintf("Queuing %d song(s) from the playlist...", len(tracks)),
 	})
 
 	vc, err := connectUserVoiceChannel(s, i.GuildID, i.Member.User.ID)
@@ -139,48 +183,132 @@ func playPlaylistSong(ctx context.Context, s *discordgo.Session, i *discordgo.In
 		return nil
 	}
 
-	concurrencyLimit := viper.GetInt("youtube.concurrency")
-	filenames, successCount := playlist.DownloadVideosConcurrently(videoIDs, ytManager, concurrencyLimit)
+	// Songs are queued straight away and downloaded just ahead of playback
+	songs := trackSongs(tracks, i)
+	gq := queue.EnqueueSongs(i.GuildID, songs...)
 
-	if successCount == 0 {
-		failMsg := "Oops! Couldn't download any songs from the playlist. 😅"
-		s.FollowupMessageEdit(i.Interaction, initialMsg.ID, &discordgo.WebhookEdit{
-			Content: &failMsg,
-		})
-		return nil
+	if gq.Session.VC == nil {
+		go queue.PlayNext(s, i.GuildID, vc)
 	}
 
-	for _, filename := range filenames {
-		queue.Enqueue(i.GuildID, filename, i.Member.User.Username)
+	finalContent := fmt.Sprintf("🎵 All `%d` songs added to queue!", len(songs))
+	if len(songs) == 1 {
+		finalContent = "🎵 Song added to queue!"
+	}
+
+	s.FollowupMessageEdit(i.Interaction, initialMsg.ID, &discordgo.WebhookEdit{
+		Content: &finalContent,
+	})
+	return nil
+}
+
+// playPagedPlaylist queues the first page of a playlist straight away with a summary of it, expanding the rest of the
+// playlist in the background
+func playPagedPlaylist(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, pager source.Pager, playlistURL string) {
+	pageSize := max(viper.GetInt("youtube.playlist_page"), 1)
+	page, err := pager.PlaylistPage(ctx, playlistURL, 1, pageSize)
+	if err != nil || len(page.Tracks) == 0 {
+		content := "❌ Invalid Playlist link!"
+		if reason := yt.Describe(err); reason != "" {
+			content = "❌ Could not fetch the playlist, " + reason + "."
+		}
+		s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+			Content: content,
+		})
+		return
 	}
 
-	gq, _ := queue.GetGuildQueue(i.GuildID)
+	vc, err := connectUserVoiceChannel(s, i.GuildID, i.Member.User.ID)
+	if err != nil {
+		return
+	}
 
+	gq := queue.EnqueueSongs(i.GuildID, trackSongs(page.Tracks, i)...)
 	if gq.Session.VC == nil {
 		go queue.PlayNext(s, i.GuildID, vc)
 	}
 
-	// Update user with final status by editing the initial message
-	var finalContent string
-	if successCount < len(videoIDs) {
-		failedCount := len(videoIDs) - successCount
-		if failedCount == 1 {
-			finalContent = fmt.Sprintf("✅ Added %d songs to queue! (1 song couldn't be played)", successCount)
-		} else {
-			finalContent = fmt.Sprintf("✅ Added %d songs to queue! (%d songs couldn't be played)", successCount, failedCount)
-		}
-	} else {
-		if successCount == 1 {
-			finalContent = "🎵 Song added to queue!"
-		} else {
-			finalContent = fmt.Sprintf("🎵 All `%d` songs added to queue!", successCount)
+	queued := len(page.Tracks)
+	more := queued == pageSize && (page.Total == 0 || page.Total > queued)
+	msg, err := s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+		Embeds: []*discordgo.MessageEmbed{playlistEmbed(page, playlistURL, queued, more)},
+	})
+	if err != nil || !more {
+		return
+	}
+
+	// Clearing or stopping the queue ends the expansion, rather than refilling the queue afterwards
+	go expandPlaylist(queue.Context(i.GuildID), s, i, pager, playlistURL, page, msg)
+}
+
+// expandPlaylist queues the rest of a playlist after its first page as it is listed, updating the summary once done
+func expandPlaylist(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, pager source.Pager, playlistURL string, first *source.PlaylistPage, msg *discordgo.Message) {
+	batchSize := len(first.Tracks)
+	queued := batchSize
+	var batch []*source.Track
+	flush := func() {
+		if len(batch) > 0 && ctx.Err() == nil {
+			queue.EnqueueSongs(i.GuildID, trackSongs(batch, i)...)
+			queued += len(batch)
 		}
+		batch = nil
 	}
 
-	s.FollowupMessageEdit(i.Interaction, initialMsg.ID, &discordgo.WebhookEdit{
-		Content: &finalContent,
+	err := pager.StreamPlaylist(ctx, playlistURL, queued+1, func(track *source.Track) bool {
+		batch = append(batch, track)
+		if len(batch) >= batchSize {
+			flush()
+		}
+		return ctx.Err() == nil
 	})
-	return nil
+	flush()
+	if err != nil && ctx.Err() == nil {
+		log.WithError(err).WithFields(log.Fields{"url": playlistURL, "queued": queued}).Error("Failed to expand playlist")
+	}
+
+	// Edited as a channel message, the interaction token expires before huge playlists finish being listed
+	s.ChannelMessageEditEmbed(msg.ChannelID, msg.ID, playlistEmbed(first, playlistURL, queued, false))
+}
+
+// trackSongs turns the tracks of a playlist into songs requested by the user of an interaction
+func trackSongs(tracks []*source.Track, i *discordgo.InteractionCreate) []*queue.QueueSong {
+	songs := make([]*queue.QueueSong, 0, len(tracks))
+	for _, track := range tracks {
+		songs = append(songs, queue.NewTrackSong(track, i.Member.User.Username, i.ChannelID))
+	}
+	return songs
+}
+
+// playlistEmbed summarises a playlist being queued, noting whether the rest of it is still being added
+func playlistEmbed(page *source.PlaylistPage, playlistURL string, queued int, expanding bool) *discordgo.MessageEmbed {
+	title := page.Title
+	if title == "" {
+		title = "Playlist"
+	}
+
+	description := ""
+	if page.Author != "" {
+		description = fmt.Sprintf("By **%s**\n", page.Author)
+	}
+	if page.Total > 0 {
+		description += fmt.Sprintf("Queued `%d` of `%d` songs", queued, page.Total)
+	} else {
+		description += fmt.Sprintf("Queued `%d` songs", queued)
+	}
+	if expanding {
+		description += ", adding the rest in the background..."
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       "🎵 " + title,
+		URL:         playlistURL,
+		Description: description,
+		Color:       viper.GetInt("theme"),
+	}
+	if page.Thumbnail != "" {
+		embed.Thumbnail = &discordgo.MessageEmbedThumbnail{URL: page.Thumbnail}
+	}
+	return embed
 }
 
 // pauseSong pauses the current song
@@ -311,26 +439,24 @@ func currentSong(ctx context.Context, s *discordgo.Session, i *discordgo.Interac
 	if gq.Session.IsPaused() {
 		status = "⏸️ Paused"
 	}
-	ytManager := yt.NewYouTubeManager(redis_client.RDB)
-
-	currentID := utils.GetAudioID(currentSong.Filename)
-	currentVideo, err := ytManager.GetVideoMetadata(currentID)
-	if err != nil {
-		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{Content: "🎶 The queue is empty 😶"},
-		})
-		return nil
-	}
+	currentVideo := songTrack(ctx, currentSong)
 
 	thumbnailURL := currentVideo.Thumbnail
 
-	videoURL := fmt.Sprintf("https://www.youtube.com/watch?v=%s", currentID)
+	description := fmt.Sprintf("Requested by: %s\nStatus: %s", currentSong.RequestedBy, status)
+	if currentVideo.Live {
+		description += "\nLength: 🔴 LIVE"
+		if title := currentSong.NowPlaying(); title != "" {
+			description += "\nOn air: " + title
+		}
+	} else if chapter := currentChapter(currentSong, currentVideo, gq.Session); chapter != "" {
+		description += "\nChapter: " + chapter
+	}
 
 	embed := &discordgo.MessageEmbed{
 		Title:       fmt.Sprintf("🎵 Now Playing: %s", currentVideo.Title),
-		URL:         videoURL,
-		Description: fmt.Sprintf("Requested by: %s\nStatus: %s", currentSong.RequestedBy, status),
+		URL:         currentVideo.URL,
+		Description: description,
 		Thumbnail:   &discordgo.MessageEmbedThumbnail{URL: thumbnailURL},
 		Color:       viper.GetInt("theme"),
 	}
@@ -342,16 +468,7 @@ func currentSong(ctx context.Context, s *discordgo.Session, i *discordgo.Interac
 		queueLen = queueLimit
 	}
 	for idx, item := range gq.Songs[:queueLen] {
-		itemID := utils.GetAudioID(item.Filename)
-		video, err := ytManager.GetVideoMetadata(itemID)
-		if err != nil {
-			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-				Type: discordgo.InteractionResponseChannelMessageWithSource,
-				Data: &discordgo.InteractionResponseData{Content: "🎶 The queue is empty 😶"},
-			})
-			return nil
-		}
-		queueText += fmt.Sprintf("%d. `%s` (requested by %s)\n", idx+1, video.Title, item.RequestedBy)
+		queueText += fmt.Sprintf("%d. `%s` (requested by %s)\n", idx+1, songTitle(item, songTrack(ctx, item)), item.RequestedBy)
 	}
 	if len(gq.Songs) > queueLimit {
 		queueText += fmt.Sprintf("...and %d more", len(gq.Songs)-queueLimit)
@@ -398,18 +515,8 @@ func currentQueue(ctx context.Context, s *discordgo.Session, i *discordgo.Intera
 		Title: fmt.Sprintf("🎶 Queue for `%s`", guild.Name),
 		Color: viper.GetInt("theme"),
 	}
-	ytManager := yt.NewYouTubeManager(redis_client.RDB)
-
 	queueText := ""
-	currentID := utils.GetAudioID(gq.CurrentSong.Filename)
-	currentVideo, err := ytManager.GetVideoMetadata(currentID)
-	if err != nil {
-		s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
-			Content: "❌ Failed to fetch video details.",
-		})
-		return nil
-	}
-	queueText += fmt.Sprintf("1. `%s` (requested by %s) ▶️\n", currentVideo.Title, gq.CurrentSong.RequestedBy)
+	queueText += fmt.Sprintf("1. `%s` (requested by %s) ▶️\n", songTitle(gq.CurrentSong, songTrack(ctx, gq.CurrentSong)), gq.CurrentSong.RequestedBy)
 
 	queueLen := len(gq.Songs)
 	queueLimit := 10
@@ -418,15 +525,7 @@ func currentQueue(ctx context.Context, s *discordgo.Session, i *discordgo.Intera
 	}
 
 	for idx, item := range gq.Songs[:queueLen] {
-		itemID := utils.GetAudioID(item.Filename)
-		video, err := ytManager.GetVideoMetadata(itemID)
-		if err != nil {
-			s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
-				Content: "❌ Failed to fetch video details.",
-			})
-			return nil
-		}
-		queueText += fmt.Sprintf("%d. `%s` (requested by %s)\n", idx+2, video.Title, item.RequestedBy)
+		queueText += fmt.Sprintf("%d. `%s` (requested by %s)\n", idx+2, songTitle(item, songTrack(ctx, item)), item.RequestedBy)
 	}
 
 	if len(gq.Songs) > queueLimit {
@@ -1,6 +1,12 @@
 package commands
 
 import (
+	"Twilight/queue"
+	"Twilight/source"
+	"Twilight/utils"
+	"context"
+	"strings"
+
 	"github.com/bwmarrin/discordgo"
 )
 
@@ -53,3 +59,88 @@ func checkUserVoiceChannel(s *discordgo.Session, i *discordgo.InteractionCreate)
 
 	return true
 }
+
+// optionMap maps the options of a command or sub command by name
+func optionMap(options []*discordgo.ApplicationCommandInteractionDataOption) map[string]*discordgo.ApplicationCommandInteractionDataOption {
+	opts := make(map[string]*discordgo.ApplicationCommandInteractionDataOption, len(options))
+	for _, opt := range options {
+		opts[opt.Name] = opt
+	}
+	return opts
+}
+
+// stringOption returns the string value of a named option, or empty if it was not given
+func stringOption(opts map[string]*discordgo.ApplicationCommandInteractionDataOption, name string) string {
+	if opt, ok := opts[name]; ok {
+		return opt.StringValue()
+	}
+	return ""
+}
+
+// intOption returns the integer value of a named option, or 0 if it was not given
+func intOption(opts map[string]*discordgo.ApplicationCommandInteractionDataOption, name string) int {
+	if opt, ok := opts[name]; ok {
+		return int(opt.IntValue())
+	}
+	return 0
+}
+
+// boolOption returns the boolean value of a named option, or false if it was not given
+func boolOption(opts map[string]*discordgo.ApplicationCommandInteractionDataOption, name string) bool {
+	if opt, ok := opts[name]; ok {
+		return opt.BoolValue()
+	}
+	return false
+}
+
+// attachmentOption returns the attachment referenced by a named option, or nil if it was not given
+func attachmentOption(data discordgo.ApplicationCommandInteractionData, opts map[string]*discordgo.ApplicationCommandInteractionDataOption, name string) *discordgo.MessageAttachment {
+	opt, ok := opts[name]
+	if !ok || data.Resolved == nil {
+		return nil
+	}
+	return data.Resolved.Attachments[opt.Value.(string)]
+}
+
+// isMediaAttachment reports whether an uploaded file is audio or video, going by its content type
+func isMediaAttachment(attachment *discordgo.MessageAttachment) bool {
+	return strings.HasPrefix(attachment.ContentType, "audio/") || strings.HasPrefix(attachment.ContentType, "video/")
+}
+
+// trackLength returns the duration of a track for display, or LIVE for live streams
+func trackLength(track *source.Track) string {
+	if track.Live {
+		return "🔴 LIVE"
+	}
+	return utils.FormatYtDuration(track.Duration)
+}
+
+// songTitle returns the title of a queued song for display, which for a chapter queued on its own names the chapter
+func songTitle(song *queue.QueueSong, track *source.Track) string {
+	if (song.Start > 0 || song.End > 0) && song.Title != "" {
+		return song.Title
+	}
+	return track.Title
+}
+
+// songMetadata fetches the metadata of a queued song from its source provider
+func songMetadata(ctx context.Context, song *queue.QueueSong) (*source.Track, error) {
+	provider, err := source.Get(song.Source)
+	if err != nil {
+		return nil, err
+	}
+	return provider.Metadata(ctx, song.Track())
+}
+
+// songTrack returns the metadata of a queued song for listings, or what the queue knows of it when fetching fails
+func songTrack(ctx context.Context, song *queue.QueueSong) *source.Track {
+	track, err := songMetadata(ctx, song)
+	if err == nil {
+		return track
+	}
+	track = song.Track()
+	if track.Title == "" {
+		track.Title = song.VideoID
+	}
+	return track
+}
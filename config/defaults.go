@@ -13,8 +13,53 @@ func initDefaults() {
 	viper.SetDefault("theme", os.Getenv("theme")) // Main theme of discord embeds
 
 	// Redis TTL timers in seconds
-	viper.SetDefault("cache.audio", 7200)   // 2 hour
-	viper.SetDefault("cache.youtube", 3600) // 1 hour
+	viper.SetDefault("cache.audio", 7200)     // 2 hour
+	viper.SetDefault("cache.youtube", 3600)   // 1 hour
+	viper.SetDefault("cache.segments", 86400) // 1 day
 
-	viper.SetDefault("youtube.concurrency", 3) // Max concurrent downloads when downloading from YouTube concurrently
+	viper.SetDefault("cache.max_size", 2048) // Largest size of the audio cache in MB before the least recently played files are evicted, 0 for no limit
+	viper.SetDefault("cache.max_age", 168)   // Hours a cached file is kept without being played, 0 to keep it until space runs out
+
+	viper.SetDefault("youtube.concurrency", 3)          // Max concurrent downloads when downloading from YouTube concurrently
+	viper.SetDefault("youtube.lock", 600)               // Seconds a download lock is held before other instances may take over
+	viper.SetDefault("youtube.timeout.metadata", 30)    // Seconds before a metadata fetch, search or playlist listing is given up
+	viper.SetDefault("youtube.timeout.download", 300)   // Seconds before a single audio download is given up
+	viper.SetDefault("youtube.retries", 3)              // Times a rate limited or temporarily failing YouTube fetch is tried again
+	viper.SetDefault("youtube.format.codec", "opus")    // Audio codec preferred when picking what to download, opus or aac
+	viper.SetDefault("youtube.format.max_bitrate", 160) // Highest audio bitrate in kbps downloaded when a lower one is offered, 0 for the best available
+	viper.SetDefault("youtube.playlist_page", 100)      // Videos queued before replying to a playlist, the rest are queued in batches this size as they are listed
+
+	viper.SetDefault("sources.hosts", []string{"soundcloud.com", "bandcamp.com", "mixcloud.com", "vimeo.com"}) // Sites played through yt-dlp besides YouTube
+	viper.SetDefault("sources.radio.ports", []int{80, 443})                                                    // Explicit ports probed for radio streams when a link isn't recognised, add 8000 for most Icecast servers
+	viper.SetDefault("sources.direct.max_size", 100)                                                           // Largest media file in MB played from a link or attachment
+
+	// Spotify Web API client credentials for resolving Spotify links
+	viper.SetDefault("spotify.client.id", os.Getenv("spotify_client_id"))
+	viper.SetDefault("spotify.client.secret", os.Getenv("spotify_client_secret"))
+
+	viper.SetDefault("resolver.candidates", 5)   // YouTube search results ranked for each Spotify or Apple Music track
+	viper.SetDefault("resolver.tolerance", 30)   // Seconds of duration difference at which a match gets no credit for its length
+	viper.SetDefault("resolver.max_tracks", 200) // Tracks resolved from a single album or playlist link
+
+	viper.SetDefault("queue.prefetch", 2)           // Upcoming songs in the queue downloaded ahead of playback
+	viper.SetDefault("queue.live.retries", 5)       // Reconnect attempts when a live stream drops before moving on
+	viper.SetDefault("queue.split_chapters", false) // Queue each chapter of a song as its own entry unless /play says otherwise
+
+	viper.SetDefault("segments.enabled", false)                                                                                  // Skip sponsor reads, talking intros and other non-music sections of YouTube videos
+	viper.SetDefault("segments.api", "https://sponsor.ajay.app")                                                                 // SponsorBlock compatible API skip segments are loaded from
+	viper.SetDefault("segments.categories", []string{"sponsor", "selfpromo", "interaction", "intro", "outro", "music_offtopic"}) // Segment categories skipped
+	viper.SetDefault("segments.min_length", 2)                                                                                   // Seconds a segment has to last to be skipped, shorter ones play through
+
+	viper.SetDefault("library.root", os.Getenv("library_root")) // Folder of music files on the host, empty disables the library
+	viper.SetDefault("library.index.interval", 60)              // Minutes between indexing the library for new files, 0 only indexes on startup
+
+	viper.SetDefault("metadata.memory_size", 2000)    // Videos whose metadata is kept within the bot's memory, least recently read dropped first
+	viper.SetDefault("metadata.stale_after", 24)      // Hours before stored metadata is refreshed in the background, while still being served, 0 never refreshes
+	viper.SetDefault("metadata.refresh.interval", 60) // Minutes between refreshing the view counts of stored songs, 0 disables
+	viper.SetDefault("metadata.refresh.batch", 50)    // Stale playlist songs refreshed per interval, least recently fetched first
+	viper.SetDefault("metadata.retention", 30)        // Days the metadata of songs outside playlists is kept unread, checked every refresh interval, 0 keeps it forever
+
+	viper.SetDefault("playlist.sync.interval", 0)    // Minutes between syncing linked playlists with their source, 0 disables
+	viper.SetDefault("playlist.check.interval", 360) // Minutes between availability checks of stored songs, 0 disables
+	viper.SetDefault("playlist.check.batch", 50)     // Songs checked per availability check, least recently checked first
 }
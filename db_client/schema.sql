@@ -21,4 +21,118 @@ CREATE TABLE IF NOT EXISTS playlists (
 );
 
 CREATE INDEX IF NOT EXISTS playlists_by_user ON playlists(user_id);
-CREATE INDEX IF NOT EXISTS playlists_by_song ON playlists(song_id);
\ No newline at end of file
+CREATE INDEX IF NOT EXISTS playlists_by_song ON playlists(song_id);
+
+-- Playlist ordering, positions are 1-based and kept dense per user
+ALTER TABLE playlists ADD COLUMN IF NOT EXISTS position INT NOT NULL DEFAULT 0;
+
+-- Only playlists holding unpositioned songs are renumbered, keeping the order of songs already positioned and
+-- appending the rest, so the backfill does nothing once every song has a position
+UPDATE playlists p
+SET position = o.rn
+FROM (
+    SELECT user_id, song_id, ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY position = 0, position, song_id) AS rn
+    FROM playlists
+    WHERE user_id IN (SELECT user_id FROM playlists WHERE position = 0)
+) o
+WHERE p.user_id = o.user_id AND p.song_id = o.song_id;
+
+CREATE INDEX IF NOT EXISTS playlists_by_position ON playlists(user_id, position);
+
+-- Guild owned playlists shared between members of a server
+CREATE TABLE IF NOT EXISTS guild_playlists (
+    id SERIAL PRIMARY KEY,
+    guild_id BIGINT NOT NULL,
+    name TEXT NOT NULL,
+    owner_id BIGINT NOT NULL,
+    default_role TEXT NOT NULL DEFAULT 'viewer', -- Role granted to every member of the guild
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (guild_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS guild_playlist_members (
+    playlist_id INT REFERENCES guild_playlists(id) ON DELETE CASCADE,
+    user_id BIGINT,
+    role TEXT NOT NULL,
+    PRIMARY KEY (playlist_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS guild_playlist_songs (
+    playlist_id INT REFERENCES guild_playlists(id) ON DELETE CASCADE,
+    song_id TEXT REFERENCES songs(id) ON DELETE CASCADE,
+    position INT NOT NULL DEFAULT 0,
+    added_by BIGINT,
+    added_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (playlist_id, song_id)
+);
+
+CREATE INDEX IF NOT EXISTS guild_playlists_by_guild ON guild_playlists(guild_id);
+CREATE INDEX IF NOT EXISTS guild_playlist_songs_by_position ON guild_playlist_songs(playlist_id, position);
+CREATE INDEX IF NOT EXISTS guild_playlist_songs_by_song ON guild_playlist_songs(song_id);
+
+-- Share codes for copying a personal or guild playlist into another users playlist
+CREATE TABLE IF NOT EXISTS playlist_shares (
+    code TEXT PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    guild_playlist_id INT REFERENCES guild_playlists(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP
+);
+
+-- YouTube playlists linked to a users personal playlist for syncing
+CREATE TABLE IF NOT EXISTS playlist_sources (
+    user_id BIGINT PRIMARY KEY REFERENCES users(user_id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    prune BOOLEAN NOT NULL DEFAULT FALSE, -- Remove songs which were removed from the source
+    last_synced_at TIMESTAMP
+);
+
+-- Videos seen within the source at the last sync
+CREATE TABLE IF NOT EXISTS playlist_source_songs (
+    user_id BIGINT REFERENCES playlist_sources(user_id) ON DELETE CASCADE,
+    song_id TEXT,
+    PRIMARY KEY (user_id, song_id)
+);
+
+-- Availability of stored songs, unavailable_reason is set once a video can no longer be played
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS unavailable_reason TEXT NOT NULL DEFAULT '';
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS unavailable_at TIMESTAMP;
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS checked_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS songs_by_checked_at ON songs(checked_at NULLS FIRST);
+
+-- Metadata of every video fetched from YouTube is kept here, not only songs within playlists
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS thumbnail TEXT NOT NULL DEFAULT '';
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS live BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS fetched_at TIMESTAMP; -- Unset for songs stored before, which are refreshed first
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS chapters TEXT NOT NULL DEFAULT ''; -- JSON list of chapter markers, empty when the video has none
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS format TEXT NOT NULL DEFAULT ''; -- JSON of the format the cached audio was downloaded in, empty until downloaded
+
+CREATE INDEX IF NOT EXISTS songs_by_fetched_at ON songs(fetched_at NULLS FIRST);
+
+-- Songs outside every playlist are deleted once unread for metadata.retention days
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS read_at TIMESTAMP; -- Unset for songs only ever added to playlists
+
+CREATE INDEX IF NOT EXISTS songs_by_read_at ON songs(read_at NULLS FIRST);
+
+-- Trigram indexes for searching songs by title and author
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS songs_title_trgm ON songs USING GIN (title gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS songs_author_trgm ON songs USING GIN (author gin_trgm_ops);
+
+-- Tracks within the local music library, paths are relative to the library root
+CREATE TABLE IF NOT EXISTS library_tracks (
+    path TEXT PRIMARY KEY,
+    title TEXT NOT NULL DEFAULT '',
+    artist TEXT NOT NULL DEFAULT '',
+    album TEXT NOT NULL DEFAULT '',
+    duration INT NOT NULL DEFAULT 0,
+    size BIGINT NOT NULL DEFAULT 0,
+    modified_at TIMESTAMP NOT NULL, -- Modification time of the file when it was indexed, unchanged files are skipped
+    indexed_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS library_tracks_title_trgm ON library_tracks USING GIN (title gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS library_tracks_artist_trgm ON library_tracks USING GIN (artist gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS library_tracks_album_trgm ON library_tracks USING GIN (album gin_trgm_ops);
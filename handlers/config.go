@@ -10,5 +10,6 @@ import (
 func HandlerConfig(s *discordgo.Session) {
 	s.Identify.Intents = discordgo.IntentsGuildMessages | discordgo.IntentsGuildMessageReactions | discordgo.IntentsGuilds | discordgo.IntentsGuildVoiceStates | discordgo.IntentsMessageContent
 	s.AddHandler(MessageHandler)
+	s.AddHandler(VoiceStateUpdate)
 	s.AddHandler(playlist.HandlePlaylistReactions)
 }
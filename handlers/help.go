@@ -18,17 +18,19 @@ func HelpEmbedding(s *discordgo.Session, m *discordgo.MessageCreate) {
 		Fields: []*discordgo.MessageEmbedField{
 			{
 				Name: "__Music Commands__",
-				Value: "`/play <url>` - Play a song from a YouTube URL.\n" +
-					"`/playplaylist <url>` - Play a playlist from a YouTube URL.\n" +
+				Value: "`/play [url] [file] [chapters]` - Play a song from a supported site, a media file link, an upload or a live stream or radio.\n" +
+					"`/playplaylist <url>` - Play a playlist, set or album from a supported site, Spotify or Apple Music.\n" +
 					"`/pause` - Pause the current song.\n" +
 					"`/resume` - Resume the paused song.\n" +
 					"`/skip` - Skip the current song.\n" +
 					"`/shuffle` - Shuffle the current song queue.\n" +
 					"`/queue` - Show the current song queue.\n" +
 					"`/np` - Show the song that's now playing.\n" +
+					"`/chapter [chapter] [loop]` - List the chapters of the current song, jump to one by name or number or loop it.\n" +
 					"`/sinfo` - Show the song info from a YouTube URL.\n" +
 					"`/loop` - Toggle loop for the current song queue.\n" +
 					"`/clear` - Clear the song queue and stop the current song.\n" +
+					"`/library search|play <query>` - Search or play tracks from the local music library.\n" +
 					"`/disconnect` - Stop playback and disconnect the bot from the voice channel.\n" +
 					"`/leave` - Stop playback and disconnect the bot from the voice channel.",
 				Inline: false,
@@ -36,11 +38,27 @@ func HelpEmbedding(s *discordgo.Session, m *discordgo.MessageCreate) {
 			{
 				Name: "__Playlist Commands__",
 				Value: "`/playlist view` - View your playlist.\n" +
-					"`/playlist add <song>` - Add a song to your playlist (YouTube video ID).\n" +
+					"`/playlist add <song> [position]` - Add a song to your playlist (YouTube video ID), optionally at a position.\n" +
 					"`/playlist remove <song>` - Remove a song from your playlist (YouTube video ID).\n" +
+					"`/playlist move <from> <to>` - Move a song to a different position within your playlist.\n" +
 					"`/playlist addplaylist <playlist>` - Add all songs from a YouTube playlist.\n" +
 					"`/playlist clear` - Clear your playlist.\n" +
-					"`/playlist play [song]` - Play a song from your playlist or the entire playlist (optional YouTube video ID).",
+					"`/playlist search <query>` - Search your playlist, then queue or remove the results.\n" +
+					"`/playlist check` - Find songs in your playlist which are no longer available.\n" +
+					"`/playlist play [song] [shuffle] [start] [count] [mode]` - Play a song or your playlist, optionally shuffled, from a position, limited to some songs, or replacing the queue.",
+				Inline: false,
+			},
+			{
+				Name: "__Playlist Sharing__",
+				Value: "`/playlist export <format>` - Download your playlist as an M3U, JSON or CSV file.\n" +
+					"`/playlist import <file>` - Add the songs from an attached M3U, JSON or CSV file.\n" +
+					"`/playlist share [expires]` - Create a share code for your playlist, optionally expiring after some hours.\n" +
+					"`/playlist clone <code>` - Copy a shared playlist into your playlist.\n" +
+					"`/playlist link <url> [prune]` - Keep your playlist in sync with a YouTube playlist.\n" +
+					"`/playlist unlink` - Stop syncing your playlist with its YouTube playlist.\n" +
+					"`/playlist sync` - Sync your playlist with its linked YouTube playlist now.\n" +
+					"`/playlist guild list|create|delete|grant|revoke` - Manage playlists shared with the server.\n" +
+					"Add `playlist:<name>` to any playlist command to use a guild playlist.",
 				Inline: false,
 			},
 		},
@@ -0,0 +1,15 @@
+package handlers
+
+import (
+	"Twilight/queue"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// VoiceStateUpdate drops the queue of a guild once the bot leaves voice there, whether it left or was disconnected
+func VoiceStateUpdate(s *discordgo.Session, v *discordgo.VoiceStateUpdate) {
+	if s.State.User == nil || v.UserID != s.State.User.ID || v.ChannelID != "" {
+		return
+	}
+	queue.DeleteGuildQueue(v.GuildID)
+}
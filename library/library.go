@@ -0,0 +1,247 @@
+package library
+
+import (
+	"Twilight/source"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Strum355/log"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrDisabled = errors.New("library root is not configured")
+
+// Track is a file within the local music library
+type Track struct {
+	Path       string `gorm:"primaryKey"` // Relative to the library root
+	Title      string
+	Artist     string
+	Album      string
+	Duration   int64 // Seconds
+	Size       int64
+	ModifiedAt time.Time
+	IndexedAt  time.Time
+}
+
+// TableName returns the table library tracks are stored in
+func (Track) TableName() string {
+	return "library_tracks"
+}
+
+// Library indexes and searches the music files below a root directory
+type Library struct {
+	db   *gorm.DB
+	root string
+}
+
+// IndexResult counts the files handled by an index run
+type IndexResult struct {
+	Indexed   int // New or changed files which were probed
+	Unchanged int // Files skipped as they haven't changed since the last run
+	Removed   int // Tracks whose files no longer exist
+	Failed    int // Files which couldn't be probed
+}
+
+// fileStat is the size and modification time of a file found while scanning the library
+type fileStat struct {
+	Size       int64
+	ModifiedAt time.Time
+}
+
+var indexMu sync.Mutex // Only one index run at a time
+
+// New returns the library rooted at the configured library.root
+func New(db *gorm.DB) *Library {
+	return &Library{db: db, root: viper.GetString("library.root")}
+}
+
+// Enabled reports whether a library root is configured
+func (l *Library) Enabled() bool {
+	return l.root != ""
+}
+
+// FullPath returns the path of a tracks file on the host
+func (l *Library) FullPath(t Track) string {
+	return filepath.Join(l.root, filepath.FromSlash(t.Path))
+}
+
+// Index probes new and changed files below the root for their tags and removes tracks whose files are gone
+func (l *Library) Index() (IndexResult, error) {
+	if !l.Enabled() {
+		return IndexResult{}, ErrDisabled
+	}
+	indexMu.Lock()
+	defer indexMu.Unlock()
+
+	found, err := scanLibrary(l.root)
+	if err != nil {
+		return IndexResult{}, err
+	}
+
+	var indexed []Track
+	if err := l.db.Select("path", "size", "modified_at").Find(&indexed).Error; err != nil {
+		return IndexResult{}, err
+	}
+	toIndex, toRemove := diffIndex(found, indexed)
+
+	result := IndexResult{Unchanged: len(found) - len(toIndex)}
+	for _, path := range toIndex {
+		track, err := source.Probe(filepath.Join(l.root, filepath.FromSlash(path)))
+		if err != nil {
+			log.WithError(err).WithFields(log.Fields{"path": path}).Warn("Failed to probe library file")
+			result.Failed++
+			continue
+		}
+
+		row := Track{
+			Path:       path,
+			Title:      track.Title,
+			Artist:     track.Author,
+			Album:      track.Album,
+			Duration:   int64(track.Duration.Seconds()),
+			Size:       found[path].Size,
+			ModifiedAt: found[path].ModifiedAt,
+			IndexedAt:  time.Now(),
+		}
+		if row.Title == "" {
+			row.Title = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		}
+		if err := l.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&row).Error; err != nil {
+			return result, err
+		}
+		result.Indexed++
+	}
+
+	if len(toRemove) > 0 {
+		if err := l.db.Where("path IN ?", toRemove).Delete(&Track{}).Error; err != nil {
+			return result, err
+		}
+		result.Removed = len(toRemove)
+	}
+	return result, nil
+}
+
+// StartIndexing indexes the library straight away and then periodically, an interval of 0 only indexes once
+func (l *Library) StartIndexing(interval time.Duration) {
+	go func() {
+		l.routineIndex()
+		if interval <= 0 {
+			return
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			l.routineIndex()
+		}
+	}()
+}
+
+// routineIndex runs an index and logs the outcome
+func (l *Library) routineIndex() {
+	result, err := l.Index()
+	if err != nil {
+		log.WithError(err).Error("Failed to index library")
+		return
+	}
+	log.WithFields(log.Fields{
+		"indexed":   result.Indexed,
+		"unchanged": result.Unchanged,
+		"removed":   result.Removed,
+		"failed":    result.Failed,
+	}).Info("Indexed library")
+}
+
+// Search finds tracks whose title, artist or album match the query, best matches first
+func (l *Library) Search(query string, limit int) ([]Track, error) {
+	if !l.Enabled() {
+		return nil, ErrDisabled
+	}
+
+	q := l.db.Model(&Track{})
+	if query = strings.TrimSpace(query); query != "" {
+		// Substring matches use the trigram indexes, word similarity catches typos
+		pattern := "%" + escapeLike(query) + "%"
+		q = q.Where(
+			"(title ILIKE ? OR artist ILIKE ? OR album ILIKE ? OR ? <% title OR ? <% artist OR ? <% album)",
+			pattern, pattern, pattern, query, query, query,
+		).Order(clause.Expr{
+			SQL:  "GREATEST(word_similarity(?, title), word_similarity(?, artist), word_similarity(?, album)) DESC",
+			Vars: []any{query, query, query},
+		})
+	}
+
+	var tracks []Track
+	err := q.Order("artist, album, path").Limit(limit).Find(&tracks).Error
+	return tracks, err
+}
+
+// scanLibrary walks the root for media files, keyed by their slash separated path relative to the root
+func scanLibrary(root string) (map[string]fileStat, error) {
+	found := map[string]fileStat{}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			log.WithError(err).WithFields(log.Fields{"path": path}).Warn("Skipping unreadable library path")
+			return nil
+		}
+		if d.IsDir() {
+			if path != root && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || !source.IsMediaFile(d.Name()) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return nil
+		}
+		found[filepath.ToSlash(rel)] = fileStat{Size: info.Size(), ModifiedAt: info.ModTime().UTC().Truncate(time.Microsecond)}
+		return nil
+	})
+	return found, err
+}
+
+// diffIndex works out which files need probing as they are new or changed, and which tracks no longer have a file
+func diffIndex(found map[string]fileStat, indexed []Track) ([]string, []string) {
+	known := make(map[string]fileStat, len(indexed))
+	var toRemove []string
+	for _, t := range indexed {
+		known[t.Path] = fileStat{Size: t.Size, ModifiedAt: t.ModifiedAt}
+		if _, ok := found[t.Path]; !ok {
+			toRemove = append(toRemove, t.Path)
+		}
+	}
+
+	var toIndex []string
+	for path, stat := range found {
+		prev, ok := known[path]
+		if !ok || prev.Size != stat.Size || !prev.ModifiedAt.Equal(stat.ModifiedAt) {
+			toIndex = append(toIndex, path)
+		}
+	}
+	sort.Strings(toIndex)
+	sort.Strings(toRemove)
+	return toIndex, toRemove
+}
+
+// escapeLike escapes the wildcard characters of a LIKE pattern
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
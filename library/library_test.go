@@ -0,0 +1,68 @@
+package library
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScanLibrary(t *testing.T) {
+	root := t.TempDir()
+	write := func(name string) {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		assert.NoError(t, os.WriteFile(path, []byte("audio"), 0o644))
+	}
+	write("Artist/Album/01 Intro.flac")
+	write("Artist/Album/cover.jpg")
+	write("single.MP3")
+	write(".hidden/secret.mp3")
+
+	found, err := scanLibrary(root)
+
+	assert.NoError(t, err)
+	assert.Len(t, found, 2)
+	assert.Contains(t, found, "Artist/Album/01 Intro.flac")
+	assert.Contains(t, found, "single.MP3")
+	assert.Equal(t, int64(5), found["single.MP3"].Size)
+}
+
+func TestScanLibrary_MissingRoot(t *testing.T) {
+	_, err := scanLibrary(filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}
+
+func TestDiffIndex(t *testing.T) {
+	modified := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	found := map[string]fileStat{
+		"same.mp3":    {Size: 10, ModifiedAt: modified},
+		"resized.mp3": {Size: 20, ModifiedAt: modified},
+		"touched.mp3": {Size: 10, ModifiedAt: modified.Add(time.Second)},
+		"new.mp3":     {Size: 10, ModifiedAt: modified},
+	}
+	indexed := []Track{
+		{Path: "same.mp3", Size: 10, ModifiedAt: modified},
+		{Path: "resized.mp3", Size: 10, ModifiedAt: modified},
+		{Path: "touched.mp3", Size: 10, ModifiedAt: modified},
+		{Path: "deleted.mp3", Size: 10, ModifiedAt: modified},
+	}
+
+	toIndex, toRemove := diffIndex(found, indexed)
+
+	assert.Equal(t, []string{"new.mp3", "resized.mp3", "touched.mp3"}, toIndex)
+	assert.Equal(t, []string{"deleted.mp3"}, toRemove)
+}
+
+func TestFullPath(t *testing.T) {
+	lib := &Library{root: "/srv/music"}
+	assert.Equal(t, filepath.Join("/srv/music", "Artist", "song.mp3"), lib.FullPath(Track{Path: "Artist/song.mp3"}))
+	assert.True(t, lib.Enabled())
+	assert.False(t, (&Library{}).Enabled())
+}
+
+func TestEscapeLike(t *testing.T) {
+	assert.Equal(t, `50\% \_off`, escapeLike(`50% _off`))
+}
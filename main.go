@@ -1,13 +1,17 @@
 package main
 
 import (
+	"Twilight/audiocache"
 	"Twilight/commands"
 	"Twilight/config"
 	"Twilight/db_client"
 	"Twilight/handlers"
+	"Twilight/library"
+	"Twilight/playlist"
 	"Twilight/queue"
 	"Twilight/redis_client"
 	"Twilight/utils"
+	"Twilight/yt"
 	"flag"
 	"os"
 	"os/signal"
@@ -16,7 +20,6 @@ import (
 
 	"github.com/Strum355/log"
 	"github.com/bwmarrin/discordgo"
-	"github.com/redis/go-redis/v9"
 	"github.com/spf13/viper"
 )
 
@@ -58,7 +61,28 @@ func main() {
 
 	db_client.Init()
 
-	StartCacheCleaning()
+	// Indexes the audio cache left from the last run and evicts files once it grows too large
+	audiocache.Init(queue.QueuedFiles).StartEviction(time.Hour)
+
+	// Keeps linked playlists in sync with their YouTube source
+	if interval := viper.GetInt("playlist.sync.interval"); interval > 0 {
+		playlist.NewManager(s, redis_client.RDB, db_client.DB).StartSourceSync(time.Duration(interval) * time.Minute)
+	}
+
+	// Flags stored songs whose videos are no longer available
+	if interval := viper.GetInt("playlist.check.interval"); interval > 0 {
+		playlist.NewManager(s, redis_client.RDB, db_client.DB).StartAvailabilityChecks(time.Duration(interval)*time.Minute, viper.GetInt("playlist.check.batch"))
+	}
+
+	// Keeps the view counts and details of stored songs current
+	if interval := viper.GetInt("metadata.refresh.interval"); interval > 0 {
+		yt.StartMetadataRefresh(db_client.DB, time.Duration(interval)*time.Minute, viper.GetInt("metadata.refresh.batch"))
+	}
+
+	// Indexes the tags of the files within the local music library
+	if viper.GetString("library.root") != "" {
+		library.New(db_client.DB).StartIndexing(time.Duration(viper.GetInt("library.index.interval")) * time.Minute)
+	}
 
 	sc := make(chan os.Signal, 1)
 	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM)
@@ -87,47 +111,19 @@ func gracefulShutdown(s *discordgo.Session) {
 	log.Info("Cleanly exiting")
 }
 
-// cleanUpCache removes old cached audio files
+// cleanUpCache removes unfinished downloads, the cached audio is kept for the next start
 func cleanUpCache() {
 	cacheDir := "cache"
-	if _, err := os.Stat(cacheDir); os.IsNotExist(err) {
+	files, err := os.ReadDir(cacheDir)
+	if err != nil {
 		return
 	}
 
-	files, _ := os.ReadDir(cacheDir)
-
 	for _, file := range files {
-		_ = os.RemoveAll(cacheDir + "/" + file.Name())
-	}
-
-	log.Info("Cache cleanup completed")
-}
-
-// StartCacheCleaning starts the opus background cleanup
-func StartCacheCleaning() {
-	go func() {
-		ticker := time.NewTicker(1 * time.Hour)
-		defer ticker.Stop()
-		for range ticker.C {
-			routineCacheCleaning()
+		if utils.IsPartial(file.Name()) {
+			_ = os.RemoveAll(cacheDir + "/" + file.Name())
 		}
-	}()
-}
-
-// routineCacheCleaning cleans up opus files which have been unused
-func routineCacheCleaning() {
-	log.Info("Beginning cache cleanup!")
-	cacheDir := "cache"
-	if _, err := os.Stat(cacheDir); os.IsNotExist(err) {
-		return
 	}
 
-	files, _ := os.ReadDir(cacheDir)
-
-	for _, file := range files {
-		_, err := redis_client.RDB.Get(redis_client.Ctx, "ytvideo:"+utils.GetAudioID(file.Name())).Result()
-		if err == redis.Nil {
-			_ = os.Remove(cacheDir + "/" + file.Name())
-		}
-	}
+	log.Info("Cache cleanup completed")
 }
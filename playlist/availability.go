@@ -0,0 +1,263 @@
+package playlist
+
+import (
+	"Twilight/yt"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Strum355/log"
+	"github.com/bwmarrin/discordgo"
+	"github.com/spf13/viper"
+)
+
+// CleanupPrefix starts the custom ID of the button removing unavailable songs
+const CleanupPrefix = "cleanup:"
+
+// CheckResult counts the songs checked for availability
+type CheckResult struct {
+	Checked     int    // Songs which were checked
+	Unavailable []Song // Songs which can no longer be played
+	Failed      int    // Songs which couldn't be checked, left as they were
+}
+
+// checkSongs checks each song for availability using a worker pool and records the outcome
+func (pm *PlaylistManager) checkSongs(ctx context.Context, songs []Song, progress func(done int, title string)) CheckResult {
+	maxConcurrency := viper.GetInt("youtube.concurrency")
+	if maxConcurrency < 1 {
+		maxConcurrency = 1
+	}
+
+	jobs := make(chan Song)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	result := CheckResult{}
+
+	for range min(maxConcurrency, len(songs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for song := range jobs {
+				reason, err := yt.CheckAvailability(ctx, song.ID)
+
+				mu.Lock()
+				result.Checked++
+				done := result.Checked
+				mu.Unlock()
+				progress(done, song.Title)
+
+				if err != nil {
+					log.WithError(err).WithFields(log.Fields{"video_id": song.ID}).Error("Failed to check video availability")
+					mu.Lock()
+					result.Failed++
+					mu.Unlock()
+					continue
+				}
+
+				updated, err := pm.recordAvailability(song, reason)
+				if err != nil {
+					log.WithError(err).WithFields(log.Fields{"video_id": song.ID}).Error("Failed to record video availability")
+				}
+				if updated.Unavailable() {
+					mu.Lock()
+					result.Unavailable = append(result.Unavailable, updated)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for _, song := range songs {
+		jobs <- song
+	}
+	close(jobs)
+	wg.Wait()
+
+	return result
+}
+
+// recordAvailability stores the result of an availability check, keeping the time a song was first found unavailable
+func (pm *PlaylistManager) recordAvailability(song Song, reason string) (Song, error) {
+	now := time.Now()
+	song.CheckedAt = &now
+	song.UnavailableReason = reason
+	switch {
+	case reason == "":
+		song.UnavailableAt = nil
+	case song.UnavailableAt == nil:
+		song.UnavailableAt = &now
+	}
+
+	err := pm.db.Model(&Song{}).Where("id = ?", song.ID).Updates(map[string]any{
+		"unavailable_reason": song.UnavailableReason,
+		"unavailable_at":     song.UnavailableAt,
+		"checked_at":         song.CheckedAt,
+	}).Error
+	return song, err
+}
+
+// CheckPlaylist checks every song within a playlist for availability and offers to remove the unavailable ones
+func (pm *PlaylistManager) CheckPlaylist(ctx context.Context, i *discordgo.InteractionCreate, name string) {
+	t := pm.target(i, name, RoleViewer)
+	if t == nil {
+		return
+	}
+
+	entries, err := loadEntries(pm.db, t)
+	if err != nil || len(entries) == 0 {
+		content := "Looks like " + t.label() + " is empty. Add some songs to get started! 🎵"
+		if err != nil {
+			content = "Oops! Something went wrong while fetching " + t.label() + ". 😅"
+		}
+		pm.session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+			Content: content,
+		})
+		return
+	}
+
+	msg, err := pm.session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+		Content: fmt.Sprintf("Checking %d songs...", len(entries)),
+	})
+	if err != nil {
+		return
+	}
+
+	songs := make([]Song, 0, len(entries))
+	for _, e := range entries {
+		songs = append(songs, e.Song)
+	}
+
+	reporter := newThrottledProgress(progressInterval, func(done int, title string) {
+		content := fmt.Sprintf("Checking %d/%d: `%s`", done, len(songs), title)
+		pm.session.FollowupMessageEdit(i.Interaction, msg.ID, &discordgo.WebhookEdit{
+			Content: &content,
+		})
+	})
+	result := pm.checkSongs(ctx, songs, reporter.Update)
+	reporter.Stop()
+
+	content := fmt.Sprintf("✅ All `%d` songs in %s are available!", result.Checked-result.Failed, t.label())
+	var components []discordgo.MessageComponent
+	if len(result.Unavailable) > 0 {
+		labels := make([]string, 0, len(result.Unavailable))
+		for _, song := range result.Unavailable {
+			labels = append(labels, song.Title+" ("+song.UnavailableReason+")")
+		}
+		content = fmt.Sprintf("⚠️ Found `%d` unavailable song(s) in %s: %s", len(result.Unavailable), t.label(), summariseLabels(labels, 10))
+		components = []discordgo.MessageComponent{
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					discordgo.Button{
+						Label:    "Remove unavailable songs",
+						Emoji:    &discordgo.ComponentEmoji{Name: "🧹"},
+						Style:    discordgo.DangerButton,
+						CustomID: CleanupPrefix + i.Member.User.ID + ":" + name,
+					},
+				},
+			},
+		}
+	}
+	if result.Failed > 0 {
+		content += fmt.Sprintf("\n• `%d` couldn't be checked, try again later", result.Failed)
+	}
+
+	pm.session.FollowupMessageEdit(i.Interaction, msg.ID, &discordgo.WebhookEdit{
+		Content:    &content,
+		Components: &components,
+	})
+}
+
+// RemoveUnavailable removes every song marked unavailable from the playlist named in a cleanup button
+func (pm *PlaylistManager) RemoveUnavailable(i *discordgo.InteractionCreate) {
+	t := pm.componentTarget(i, CleanupPrefix, RoleEditor)
+	if t == nil {
+		return
+	}
+
+	var songIDs []string
+	if err := t.scope.where(pm.db).
+		Joins("JOIN songs ON songs.id = "+t.scope.table+".song_id").
+		Where("songs.unavailable_reason <> ''").
+		Pluck("song_id", &songIDs).Error; err != nil {
+		pm.session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+			Content: "Oops! Something went wrong while fetching " + t.label() + ". 😅",
+		})
+		return
+	}
+
+	err := pm.removeSongs(t, songIDs...)
+	if err != nil {
+		pm.session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+			Content: "Oops! Something went wrong while removing songs from " + t.label() + ". 😅",
+		})
+		return
+	}
+
+	// The deferred update edits the message holding the button, removing it so the cleanup can't be repeated
+	content := fmt.Sprintf("🧹 Removed `%d` unavailable song(s) from %s", len(songIDs), t.label())
+	components := []discordgo.MessageComponent{}
+	pm.session.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content:    &content,
+		Components: &components,
+	})
+}
+
+// StartAvailabilityChecks periodically checks the songs which have gone longest without a check
+func (pm *PlaylistManager) StartAvailabilityChecks(interval time.Duration, batchSize int) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			pm.routineAvailabilityCheck(batchSize)
+		}
+	}()
+}
+
+// routineAvailabilityCheck checks a batch of songs within playlists, oldest checks first
+func (pm *PlaylistManager) routineAvailabilityCheck(batchSize int) {
+	var songs []Song
+	// The songs table also caches the metadata of every video played, which only matters within a playlist
+	err := pm.db.
+		Where("id IN (SELECT song_id FROM playlists) OR id IN (SELECT song_id FROM guild_playlist_songs)").
+		Order("checked_at NULLS FIRST").
+		Limit(batchSize).
+		Find(&songs).Error
+	if err != nil {
+		log.WithError(err).Error("Failed to load songs for availability check")
+		return
+	}
+	if len(songs) == 0 {
+		return
+	}
+
+	result := pm.checkSongs(context.Background(), songs, func(int, string) {})
+	log.WithFields(log.Fields{
+		"checked":     result.Checked,
+		"unavailable": len(result.Unavailable),
+		"failed":      result.Failed,
+	}).Info("Checked song availability")
+}
+
+// availableSongs drops songs marked unavailable, returning how many were skipped
+func availableSongs(entries []Entry) ([]Entry, int) {
+	available := make([]Entry, 0, len(entries))
+	for _, e := range entries {
+		if !e.Song.Unavailable() {
+			available = append(available, e)
+		}
+	}
+	return available, len(entries) - len(available)
+}
+
+// queuedMessage describes the songs queued from a playlist, noting any skipped as unavailable
+func queuedMessage(songs []Song, skipped int, label string) string {
+	content := fmt.Sprintf("🎵 Queued `%d` songs from %s!", len(songs), label)
+	if len(songs) == 1 {
+		content = fmt.Sprintf("🎵 Queued `%s` from %s!", songs[0].Title, label)
+	}
+	if skipped > 0 {
+		content += fmt.Sprintf(" (skipped `%d` unavailable)", skipped)
+	}
+	return content
+}
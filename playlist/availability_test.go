@@ -0,0 +1,42 @@
+package playlist
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAvailableSongs(t *testing.T) {
+	entries := []Entry{
+		{Position: 1, Song: Song{ID: "a"}},
+		{Position: 2, Song: Song{ID: "b", UnavailableReason: "Private video"}},
+		{Position: 3, Song: Song{ID: "c"}},
+	}
+
+	available, skipped := availableSongs(entries)
+
+	assert.Equal(t, 1, skipped)
+	assert.Len(t, available, 2)
+	assert.Equal(t, "a", available[0].Song.ID)
+	assert.Equal(t, "c", available[1].Song.ID)
+}
+
+func TestQueuedMessage(t *testing.T) {
+	songs := []Song{{ID: "a", Title: "First"}, {ID: "b", Title: "Second"}}
+
+	assert.Equal(t, "🎵 Queued `2` songs from your playlist!", queuedMessage(songs, 0, "your playlist"))
+	assert.Equal(t, "🎵 Queued `First` from your playlist! (skipped `3` unavailable)", queuedMessage(songs[:1], 3, "your playlist"))
+}
+
+func TestCreatePlaylistEmbed_FlagsUnavailable(t *testing.T) {
+	entries := []Entry{
+		{Position: 1, Song: Song{ID: "a", Title: "Fine"}},
+		{Position: 2, Song: Song{ID: "b", Title: "Gone", UnavailableReason: "Video unavailable"}},
+	}
+
+	embed := CreatePlaylistEmbed("Your Playlist", entries, 0, SONGS_PER_PAGE)
+
+	assert.Contains(t, embed.Fields[0].Value, "⚠️ Unavailable: Video unavailable")
+	assert.Equal(t, 1, strings.Count(embed.Fields[0].Value, "⚠️"))
+}
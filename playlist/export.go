@@ -0,0 +1,201 @@
+package playlist
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+type Format string
+
+const (
+	FormatM3U  Format = "m3u"
+	FormatJSON Format = "json"
+	FormatCSV  Format = "csv"
+)
+
+var ErrUnknownFormat = errors.New("unknown playlist file format")
+
+// FileEntry is a single song written to or read from a playlist file
+type FileEntry struct {
+	Title    string `json:"title"`
+	Author   string `json:"author"`
+	Duration int64  `json:"duration"` // Seconds
+	URL      string `json:"url"`
+}
+
+// FormatFromFilename works out the playlist file format from a filename extension
+func FormatFromFilename(filename string) (Format, error) {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".m3u", ".m3u8":
+		return FormatM3U, nil
+	case ".json":
+		return FormatJSON, nil
+	case ".csv":
+		return FormatCSV, nil
+	}
+	return "", ErrUnknownFormat
+}
+
+// ContentType returns the MIME type used when uploading a playlist file
+func (f Format) ContentType() string {
+	switch f {
+	case FormatM3U:
+		return "audio/x-mpegurl"
+	case FormatJSON:
+		return "application/json"
+	default:
+		return "text/csv"
+	}
+}
+
+// EncodeFile writes playlist entries in the given file format
+func EncodeFile(format Format, entries []FileEntry) ([]byte, error) {
+	buf := &bytes.Buffer{}
+
+	switch format {
+	case FormatM3U:
+		buf.WriteString("#EXTM3U\n")
+		for _, e := range entries {
+			fmt.Fprintf(buf, "#EXTINF:%d,%s - %s\n%s\n", e.Duration, e.Author, e.Title, e.URL)
+		}
+	case FormatJSON:
+		enc := json.NewEncoder(buf)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(entries); err != nil {
+			return nil, err
+		}
+	case FormatCSV:
+		w := csv.NewWriter(buf)
+		w.Write([]string{"title", "author", "duration", "url"})
+		for _, e := range entries {
+			w.Write([]string{e.Title, e.Author, strconv.FormatInt(e.Duration, 10), e.URL})
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrUnknownFormat
+	}
+
+	return buf.Bytes(), nil
+}
+
+// DecodeFile reads playlist entries from a file in the given format
+func DecodeFile(format Format, data []byte) ([]FileEntry, error) {
+	switch format {
+	case FormatM3U:
+		return decodeM3U(data), nil
+	case FormatJSON:
+		return decodeJSON(data)
+	case FormatCSV:
+		return decodeCSV(data)
+	}
+	return nil, ErrUnknownFormat
+}
+
+// decodeM3U reads an extended or plain M3U file, using #EXTINF lines for titles and durations
+func decodeM3U(data []byte) []FileEntry {
+	var entries []FileEntry
+	var pending FileEntry
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "#EXTINF:"):
+			info := strings.TrimPrefix(line, "#EXTINF:")
+			duration, title, _ := strings.Cut(info, ",")
+			pending.Duration, _ = strconv.ParseInt(strings.TrimSpace(duration), 10, 64)
+			if pending.Duration < 0 {
+				pending.Duration = 0
+			}
+			if author, songTitle, ok := strings.Cut(title, " - "); ok {
+				pending.Author, pending.Title = strings.TrimSpace(author), strings.TrimSpace(songTitle)
+			} else {
+				pending.Title = strings.TrimSpace(title)
+			}
+		case strings.HasPrefix(line, "#"):
+			continue
+		default:
+			pending.URL = line
+			if pending.Title == "" && !strings.Contains(line, "://") {
+				pending.Title = strings.TrimSuffix(path.Base(line), path.Ext(line))
+			}
+			entries = append(entries, pending)
+			pending = FileEntry{}
+		}
+	}
+
+	return entries
+}
+
+// decodeJSON reads a list of entries, either top level or under a songs key
+func decodeJSON(data []byte) ([]FileEntry, error) {
+	var entries []FileEntry
+	if err := json.Unmarshal(data, &entries); err == nil {
+		return entries, nil
+	}
+
+	var wrapped struct {
+		Songs []FileEntry `json:"songs"`
+	}
+	if err := json.Unmarshal(data, &wrapped); err != nil {
+		return nil, err
+	}
+	return wrapped.Songs, nil
+}
+
+// decodeCSV reads entries from a CSV file with a header row naming the columns
+func decodeCSV(data []byte) ([]FileEntry, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	columns := map[string]int{}
+	for idx, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = idx
+	}
+
+	field := func(record []string, names ...string) string {
+		for _, name := range names {
+			if idx, ok := columns[name]; ok && idx < len(record) {
+				return strings.TrimSpace(record[idx])
+			}
+		}
+		return ""
+	}
+
+	var entries []FileEntry
+	for _, record := range records[1:] {
+		duration, _ := strconv.ParseInt(field(record, "duration"), 10, 64)
+		entry := FileEntry{
+			Title:    field(record, "title"),
+			Author:   field(record, "author", "artist"),
+			Duration: duration,
+			URL:      field(record, "url", "id", "video_id"),
+		}
+		if entry.Title == "" && entry.URL == "" {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
@@ -0,0 +1,67 @@
+package playlist
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testEntries = []FileEntry{
+	{Title: "Song One", Author: "Artist", Duration: 215, URL: "https://www.youtube.com/watch?v=abcdefghijk"},
+	{Title: "Song, Two", Author: "Other Artist", Duration: 61, URL: "https://www.youtube.com/watch?v=bcdefghijkl"},
+}
+
+func TestEncodeDecode_RoundTrip(t *testing.T) {
+	for _, format := range []Format{FormatM3U, FormatJSON, FormatCSV} {
+		data, err := EncodeFile(format, testEntries)
+		assert.NoError(t, err)
+
+		entries, err := DecodeFile(format, data)
+		assert.NoError(t, err)
+		assert.Equal(t, testEntries, entries, string(format))
+	}
+}
+
+func TestEncodeFile_UnknownFormat(t *testing.T) {
+	_, err := EncodeFile(Format("xml"), testEntries)
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+}
+
+func TestDecodeM3U_PlainPaths(t *testing.T) {
+	data := []byte("# a comment\nC:/Music/Artist - Track.mp3\n\nhttps://youtu.be/abcdefghijk\n")
+
+	entries, err := DecodeFile(FormatM3U, data)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []FileEntry{
+		{Title: "Artist - Track", URL: "C:/Music/Artist - Track.mp3"},
+		{URL: "https://youtu.be/abcdefghijk"},
+	}, entries)
+}
+
+func TestDecodeJSON_Wrapped(t *testing.T) {
+	data := []byte(`{"songs": [{"title": "Song One", "url": "abcdefghijk"}]}`)
+
+	entries, err := DecodeFile(FormatJSON, data)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []FileEntry{{Title: "Song One", URL: "abcdefghijk"}}, entries)
+}
+
+func TestDecodeCSV_ColumnAliases(t *testing.T) {
+	data := []byte("Artist,Title,ID\nArtist,Song One,abcdefghijk\n,,\n")
+
+	entries, err := DecodeFile(FormatCSV, data)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []FileEntry{{Title: "Song One", Author: "Artist", URL: "abcdefghijk"}}, entries)
+}
+
+func TestFormatFromFilename(t *testing.T) {
+	format, err := FormatFromFilename("Mix.M3U8")
+	assert.NoError(t, err)
+	assert.Equal(t, FormatM3U, format)
+
+	_, err = FormatFromFilename("playlist.txt")
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+}
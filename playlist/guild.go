@@ -0,0 +1,311 @@
+package playlist
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+type Role string
+
+const (
+	RoleNone   Role = "none"   // Cannot see the playlist
+	RoleViewer Role = "viewer" // Can view and play the playlist
+	RoleEditor Role = "editor" // Can add, remove and reorder songs
+	RoleOwner  Role = "owner"  // Can manage access and delete the playlist
+)
+
+// rank orders roles so that higher roles include the permissions of lower ones
+func (r Role) rank() int {
+	switch r {
+	case RoleViewer:
+		return 1
+	case RoleEditor:
+		return 2
+	case RoleOwner:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// Allows returns true if the role includes the permissions of the required role
+func (r Role) Allows(required Role) bool {
+	return r.rank() >= required.rank()
+}
+
+type GuildPlaylist struct {
+	ID          uint `gorm:"primaryKey"`
+	GuildID     int64
+	Name        string
+	OwnerID     int64
+	DefaultRole Role // Role granted to every member of the guild
+	CreatedAt   time.Time
+}
+
+type GuildPlaylistMember struct {
+	PlaylistID uint  `gorm:"primaryKey"`
+	UserID     int64 `gorm:"primaryKey"`
+	Role       Role
+}
+
+type GuildPlaylistSong struct {
+	PlaylistID uint   `gorm:"primaryKey"`
+	SongID     string `gorm:"primaryKey"`
+	Position   int
+	AddedBy    int64
+	AddedAt    time.Time
+	Song       Song `gorm:"foreignKey:SongID"`
+}
+
+const guildPlaylistTitle = "Guild Playlist: "
+
+// roleFor works out the role a user holds on a guild playlist
+func roleFor(gp *GuildPlaylist, userID int64, explicit Role, isAdmin bool) Role {
+	if gp.OwnerID == userID || isAdmin {
+		return RoleOwner
+	}
+	if explicit.rank() > gp.DefaultRole.rank() {
+		return explicit
+	}
+	return gp.DefaultRole
+}
+
+// target is the playlist a command operates on, either the callers personal playlist or a guild playlist
+type target struct {
+	userID int64
+	scope  entryScope
+	guild  *GuildPlaylist // nil for personal playlists
+	role   Role
+}
+
+// title returns the embed title used when displaying the target playlist
+func (t *target) title() string {
+	if t.guild == nil {
+		return "Your Playlist"
+	}
+	return guildPlaylistTitle + t.guild.Name
+}
+
+// label returns how the target playlist is referred to within messages
+func (t *target) label() string {
+	if t.guild == nil {
+		return "your playlist"
+	}
+	return "`" + t.guild.Name + "`"
+}
+
+// loadTarget finds the playlist named name within a guild, the users personal playlist if name is empty
+func loadTarget(db *gorm.DB, guildID string, userID int64, name string, isAdmin bool) (*target, error) {
+	if name == "" {
+		return &target{userID: userID, scope: personalScope(userID), role: RoleOwner}, nil
+	}
+
+	gid, _ := strconv.ParseInt(guildID, 10, 64)
+	var gp GuildPlaylist
+	if err := db.Where("guild_id = ? AND name = ?", gid, name).First(&gp).Error; err != nil {
+		return nil, err
+	}
+
+	var member GuildPlaylistMember
+	explicit := RoleNone
+	if err := db.Where("playlist_id = ? AND user_id = ?", gp.ID, userID).First(&member).Error; err == nil {
+		explicit = member.Role
+	}
+
+	return &target{
+		userID: userID,
+		scope:  guildScope(gp.ID),
+		guild:  &gp,
+		role:   roleFor(&gp, userID, explicit, isAdmin),
+	}, nil
+}
+
+// target resolves the playlist named by a command and checks the caller holds the required role, responding to the user on failure
+func (pm *PlaylistManager) target(i *discordgo.InteractionCreate, name string, required Role) *target {
+	userID, _ := strconv.ParseInt(i.Member.User.ID, 10, 64)
+	isAdmin := i.Member.Permissions&discordgo.PermissionManageServer != 0
+
+	t, err := loadTarget(pm.db, i.GuildID, userID, name, isAdmin)
+	content := ""
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		content = "There's no guild playlist called `" + name + "` 🤔"
+	case err != nil:
+		content = "Oops! Something went wrong while fetching that playlist. 😅"
+	case !t.role.Allows(RoleViewer):
+		content = "There's no guild playlist called `" + name + "` 🤔"
+	case !t.role.Allows(required):
+		content = fmt.Sprintf("You need `%s` access to do that on `%s` 🔒", required, name)
+	}
+
+	if content != "" {
+		pm.session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+			Content: content,
+		})
+		return nil
+	}
+	return t
+}
+
+// componentTarget resolves the playlist named in a component custom ID of the form <prefix><owner>:<name>, only the owner may use the component
+func (pm *PlaylistManager) componentTarget(i *discordgo.InteractionCreate, prefix string, required Role) *target {
+	owner, name, _ := strings.Cut(strings.TrimPrefix(i.MessageComponentData().CustomID, prefix), ":")
+	if owner != i.Member.User.ID {
+		pm.session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+			Content: "Only the person who ran the command can use this 🔒",
+			Flags:   discordgo.MessageFlagsEphemeral,
+		})
+		return nil
+	}
+	return pm.target(i, name, required)
+}
+
+// CreateGuildPlaylist creates a new playlist owned by the caller and shared with the guild
+func (pm *PlaylistManager) CreateGuildPlaylist(i *discordgo.InteractionCreate, name string, access Role) {
+	userID, _ := strconv.ParseInt(i.Member.User.ID, 10, 64)
+	guildID, _ := strconv.ParseInt(i.GuildID, 10, 64)
+	name = strings.TrimSpace(name)
+
+	if access == "" {
+		access = RoleViewer
+	}
+
+	gp := GuildPlaylist{
+		GuildID:     guildID,
+		Name:        name,
+		OwnerID:     userID,
+		DefaultRole: access,
+	}
+
+	content := fmt.Sprintf("Created guild playlist `%s`, everyone in the server has `%s` access ✨", name, access)
+	if access == RoleNone {
+		content = fmt.Sprintf("Created guild playlist `%s`, use `/playlist guild grant` to invite members ✨", name)
+	}
+	if err := pm.db.Create(&gp).Error; err != nil {
+		content = "Failed to create guild playlist `" + name + "`"
+		if strings.Contains(err.Error(), "duplicate") || strings.Contains(err.Error(), "UNIQUE") {
+			content = "A guild playlist called `" + name + "` already exists"
+		}
+	}
+
+	pm.session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+		Content: content,
+	})
+}
+
+// DeleteGuildPlaylist deletes a guild playlist along with its members and songs
+func (pm *PlaylistManager) DeleteGuildPlaylist(i *discordgo.InteractionCreate, name string) {
+	t := pm.target(i, name, RoleOwner)
+	if t == nil {
+		return
+	}
+
+	// Its songs cascade, the songs table rows are left for the metadata retention sweep
+	err := pm.db.Delete(t.guild).Error
+
+	content := "Deleted guild playlist `" + name + "` 🗑️"
+	if err != nil {
+		content = "Failed to delete guild playlist `" + name + "`"
+	}
+
+	pm.session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+		Content: content,
+	})
+}
+
+// GrantAccess gives a member a role on a guild playlist
+func (pm *PlaylistManager) GrantAccess(i *discordgo.InteractionCreate, name string, memberID string, role Role) {
+	t := pm.target(i, name, RoleOwner)
+	if t == nil {
+		return
+	}
+
+	userID, _ := strconv.ParseInt(memberID, 10, 64)
+	member := GuildPlaylistMember{PlaylistID: t.guild.ID, UserID: userID, Role: role}
+
+	content := fmt.Sprintf("<@%s> now has `%s` access to `%s`", memberID, role, name)
+	if err := pm.db.Save(&member).Error; err != nil {
+		content = "Failed to update access for `" + name + "`"
+	}
+
+	pm.session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+		Content:         content,
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+}
+
+// RevokeAccess removes a members explicit role on a guild playlist
+func (pm *PlaylistManager) RevokeAccess(i *discordgo.InteractionCreate, name string, memberID string) {
+	t := pm.target(i, name, RoleOwner)
+	if t == nil {
+		return
+	}
+
+	userID, _ := strconv.ParseInt(memberID, 10, 64)
+
+	content := fmt.Sprintf("<@%s> now has the default `%s` access to `%s`", memberID, t.guild.DefaultRole, name)
+	if err := pm.db.Where("playlist_id = ? AND user_id = ?", t.guild.ID, userID).Delete(&GuildPlaylistMember{}).Error; err != nil {
+		content = "Failed to update access for `" + name + "`"
+	}
+
+	pm.session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+		Content:         content,
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+}
+
+// ListGuildPlaylists shows the guild playlists the caller has access to
+func (pm *PlaylistManager) ListGuildPlaylists(i *discordgo.InteractionCreate) {
+	userID, _ := strconv.ParseInt(i.Member.User.ID, 10, 64)
+	guildID, _ := strconv.ParseInt(i.GuildID, 10, 64)
+	isAdmin := i.Member.Permissions&discordgo.PermissionManageServer != 0
+
+	var playlists []GuildPlaylist
+	if err := pm.db.Where("guild_id = ?", guildID).Order("name").Find(&playlists).Error; err != nil {
+		pm.session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+			Content: "Oops! Something went wrong while fetching the guild playlists. 😅",
+		})
+		return
+	}
+
+	text := ""
+	for _, gp := range playlists {
+		var member GuildPlaylistMember
+		explicit := RoleNone
+		if err := pm.db.Where("playlist_id = ? AND user_id = ?", gp.ID, userID).First(&member).Error; err == nil {
+			explicit = member.Role
+		}
+
+		role := roleFor(&gp, userID, explicit, isAdmin)
+		if !role.Allows(RoleViewer) {
+			continue
+		}
+
+		count, _ := guildScope(gp.ID).count(pm.db)
+		text += fmt.Sprintf("• `%s` - %d song(s), owned by <@%d> (`%s`)\n", gp.Name, count, gp.OwnerID, role)
+	}
+
+	if text == "" {
+		pm.session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+			Content: "There are no guild playlists yet. Create one with `/playlist guild create`! 🎵",
+		})
+		return
+	}
+
+	pm.session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+		Embeds: []*discordgo.MessageEmbed{
+			{
+				Title:       "Guild Playlists",
+				Description: text,
+				Color:       viper.GetInt("theme"),
+			},
+		},
+	})
+}
@@ -0,0 +1,42 @@
+package playlist
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRole_Allows(t *testing.T) {
+	assert.True(t, RoleOwner.Allows(RoleEditor))
+	assert.True(t, RoleEditor.Allows(RoleViewer))
+	assert.True(t, RoleViewer.Allows(RoleViewer))
+	assert.False(t, RoleViewer.Allows(RoleEditor))
+	assert.False(t, RoleNone.Allows(RoleViewer))
+	assert.False(t, Role("unknown").Allows(RoleViewer))
+}
+
+func TestRoleFor_Owner(t *testing.T) {
+	gp := &GuildPlaylist{OwnerID: 1, DefaultRole: RoleNone}
+
+	assert.Equal(t, RoleOwner, roleFor(gp, 1, RoleNone, false))
+}
+
+func TestRoleFor_Admin(t *testing.T) {
+	gp := &GuildPlaylist{OwnerID: 1, DefaultRole: RoleNone}
+
+	assert.Equal(t, RoleOwner, roleFor(gp, 2, RoleNone, true))
+}
+
+func TestRoleFor_ExplicitRole(t *testing.T) {
+	gp := &GuildPlaylist{OwnerID: 1, DefaultRole: RoleViewer}
+
+	assert.Equal(t, RoleEditor, roleFor(gp, 2, RoleEditor, false))
+}
+
+func TestRoleFor_DefaultRole(t *testing.T) {
+	gp := &GuildPlaylist{OwnerID: 1, DefaultRole: RoleEditor}
+
+	// A lower explicit role does not take away the default access
+	assert.Equal(t, RoleEditor, roleFor(gp, 2, RoleViewer, false))
+	assert.Equal(t, RoleEditor, roleFor(gp, 3, RoleNone, false))
+}
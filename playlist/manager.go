@@ -5,6 +5,8 @@ import (
 	"Twilight/queue"
 	"Twilight/redis_client"
 	"Twilight/yt"
+	"context"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -15,6 +17,7 @@ import (
 	"github.com/redis/go-redis/v9"
 	"github.com/spf13/viper"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type PlaylistManager struct {
@@ -37,25 +40,93 @@ type Song struct {
 	Duration    int64 // Seconds
 	PublishDate time.Time
 	URL         string
+
+	UnavailableReason string     // Why the video can no longer be played, empty when available
+	UnavailableAt     *time.Time // When the video was first found to be unavailable
+	CheckedAt         *time.Time // When availability was last checked
+}
+
+// Unavailable reports whether the song was found to be no longer playable
+func (s Song) Unavailable() bool {
+	return s.UnavailableReason != ""
 }
 
 type Playlist struct {
-	UserID int64  `gorm:"primaryKey"`
-	SongID string `gorm:"primaryKey"`
-	Song   Song   `gorm:"foreignKey:SongID"`
+	UserID   int64  `gorm:"primaryKey"`
+	SongID   string `gorm:"primaryKey"`
+	Position int    // 1-based position within the users playlist
+	Song     Song   `gorm:"foreignKey:SongID"`
+}
+
+// Entry is a single song within a personal or guild playlist
+type Entry struct {
+	Position int
+	Song     Song
+	AddedBy  int64 // User who added the song, 0 for personal playlists
 }
 
 const SONGS_PER_PAGE = 10
 
-// ShowPlaylist displays a users playlist
-func (pm *PlaylistManager) ShowPlaylist(i *discordgo.InteractionCreate) {
-	userID, _ := strconv.ParseInt(i.Member.User.ID, 10, 64)
+const (
+	insertBatchSize  = 100             // Rows per statement when inserting songs in bulk
+	progressInterval = 2 * time.Second // Minimum time between progress message edits
+)
+
+// loadEntries returns the songs within the target playlist ordered by position
+func loadEntries(db *gorm.DB, t *target) ([]Entry, error) {
+	var entries []Entry
+	if t.guild == nil {
+		var playlist []Playlist
+		if err := db.Where("user_id = ?", t.userID).Order("position").Preload("Song").Find(&playlist).Error; err != nil {
+			return nil, err
+		}
+		for _, p := range playlist {
+			entries = append(entries, Entry{Position: p.Position, Song: p.Song})
+		}
+		return entries, nil
+	}
+
+	var songs []GuildPlaylistSong
+	if err := db.Where("playlist_id = ?", t.guild.ID).Order("position").Preload("Song").Find(&songs).Error; err != nil {
+		return nil, err
+	}
+	for _, gs := range songs {
+		entries = append(entries, Entry{Position: gs.Position, Song: gs.Song, AddedBy: gs.AddedBy})
+	}
+	return entries, nil
+}
 
-	var playlist []Playlist
-	if err := pm.db.Where("user_id = ?", userID).Preload("Song").Find(&playlist).Error; err != nil || len(playlist) == 0 {
-		content := "Looks like your playlist is empty. Add some songs to get started! 🎵"
+// addEntry inserts a song into the target playlist at position, a position of 0 appends to the end
+func addEntry(tx *gorm.DB, t *target, songID string, position int) error {
+	position, err := makeRoom(tx, t.scope, position)
+	if err != nil {
+		return err
+	}
+
+	if t.guild == nil {
+		return tx.Create(&Playlist{UserID: t.userID, SongID: songID, Position: position}).Error
+	}
+	return tx.Create(&GuildPlaylistSong{
+		PlaylistID: t.guild.ID,
+		SongID:     songID,
+		Position:   position,
+		AddedBy:    t.userID,
+		AddedAt:    time.Now(),
+	}).Error
+}
+
+// ShowPlaylist displays a users playlist, or a guild playlist when name is given
+func (pm *PlaylistManager) ShowPlaylist(i *discordgo.InteractionCreate, name string) {
+	t := pm.target(i, name, RoleViewer)
+	if t == nil {
+		return
+	}
+
+	entries, err := loadEntries(pm.db, t)
+	if err != nil || len(entries) == 0 {
+		content := "Looks like " + t.label() + " is empty. Add some songs to get started! 🎵"
 		if err != nil {
-			content = "Oops! Something went wrong while fetching your playlist. 😅"
+			content = "Oops! Something went wrong while fetching " + t.label() + ". 😅"
 		}
 		pm.session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
 			Content: content,
@@ -63,20 +134,21 @@ func (pm *PlaylistManager) ShowPlaylist(i *discordgo.InteractionCreate) {
 		return
 	}
 
-	embed := CreatePlaylistEmbed(playlist, 0, SONGS_PER_PAGE)
+	embed := CreatePlaylistEmbed(t.title(), entries, 0, SONGS_PER_PAGE)
 	msg, _ := pm.session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
-		Embeds: []*discordgo.MessageEmbed{embed},
+		Embeds:          []*discordgo.MessageEmbed{embed},
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
 	})
 
-	if len(playlist) > SONGS_PER_PAGE {
+	if len(entries) > SONGS_PER_PAGE {
 		pm.session.MessageReactionAdd(i.ChannelID, msg.ID, "◀️")
 		pm.session.MessageReactionAdd(i.ChannelID, msg.ID, "▶️")
 	}
 }
 
 // CreatePlaylistEmbed creates the embedding to be shown for displaying playlist
-func CreatePlaylistEmbed(playlist []Playlist, page int, perPage int) *discordgo.MessageEmbed {
-	totalPages := (len(playlist) + perPage - 1) / perPage
+func CreatePlaylistEmbed(title string, entries []Entry, page int, perPage int) *discordgo.MessageEmbed {
+	totalPages := (len(entries) + perPage - 1) / perPage
 	if page < 0 {
 		page = 0
 	} else if page >= totalPages {
@@ -85,17 +157,27 @@ func CreatePlaylistEmbed(playlist []Playlist, page int, perPage int) *discordgo.
 
 	start := page * perPage
 	end := start + perPage
-	if end > len(playlist) {
-		end = len(playlist)
+	if end > len(entries) {
+		end = len(entries)
 	}
 
 	text := ""
-	for i, p := range playlist[start:end] {
-		text += fmt.Sprintf("%d. `%s`\n\u00A0\u00A0🔗 Video ID: `%s`\n", start+i+1, p.Song.Title, p.Song.ID)
+	for i, e := range entries[start:end] {
+		position := e.Position
+		if position == 0 {
+			position = start + i + 1
+		}
+		text += fmt.Sprintf("%d. `%s`\n\u00A0\u00A0🔗 Video ID: `%s`\n", position, e.Song.Title, e.Song.ID)
+		if e.Song.Unavailable() {
+			text += fmt.Sprintf("\u00A0\u00A0⚠️ Unavailable: %s\n", e.Song.UnavailableReason)
+		}
+		if e.AddedBy != 0 {
+			text += fmt.Sprintf("\u00A0\u00A0👤 Added by <@%d>\n", e.AddedBy)
+		}
 	}
 
 	return &discordgo.MessageEmbed{
-		Title: "Your Playlist",
+		Title: title,
 		Fields: []*discordgo.MessageEmbedField{
 			{Name: "\u200B", Value: text},
 		},
@@ -116,7 +198,7 @@ func HandlePlaylistReactions(s *discordgo.Session, r *discordgo.MessageReactionA
 		return
 	}
 	embed := msg.Embeds[0]
-	if embed.Title != "Your Playlist" || msg.Author.ID != s.State.User.ID {
+	if (embed.Title != "Your Playlist" && !strings.HasPrefix(embed.Title, guildPlaylistTitle)) || msg.Author.ID != s.State.User.ID {
 		return
 	}
 	footer := embed.Footer
@@ -127,11 +209,19 @@ func HandlePlaylistReactions(s *discordgo.Session, r *discordgo.MessageReactionA
 	fmt.Sscanf(footer.Text, "Page %d/", &currentPage)
 	currentPage--
 	userID, _ := strconv.ParseInt(r.UserID, 10, 64)
-	var playlist []Playlist
-	if err := db_client.DB.Where("user_id = ?", userID).Preload("Song").Find(&playlist).Error; err != nil {
+	name := ""
+	if strings.HasPrefix(embed.Title, guildPlaylistTitle) {
+		name = strings.TrimPrefix(embed.Title, guildPlaylistTitle)
+	}
+	t, err := loadTarget(db_client.DB, r.GuildID, userID, name, false)
+	if err != nil || !t.role.Allows(RoleViewer) {
+		return
+	}
+	entries, err := loadEntries(db_client.DB, t)
+	if err != nil || len(entries) == 0 {
 		return
 	}
-	totalPages := (len(playlist) + SONGS_PER_PAGE - 1) / SONGS_PER_PAGE
+	totalPages := (len(entries) + SONGS_PER_PAGE - 1) / SONGS_PER_PAGE
 	newPage := currentPage
 	if r.Emoji.Name == "▶️" {
 		newPage++
@@ -144,20 +234,27 @@ func HandlePlaylistReactions(s *discordgo.Session, r *discordgo.MessageReactionA
 			newPage = totalPages - 1
 		}
 	}
-	embed = CreatePlaylistEmbed(playlist, newPage, SONGS_PER_PAGE)
+	embed = CreatePlaylistEmbed(t.title(), entries, newPage, SONGS_PER_PAGE)
 	s.ChannelMessageEditEmbed(r.ChannelID, r.MessageID, embed)
 	s.MessageReactionRemove(r.ChannelID, r.MessageID, r.Emoji.Name, r.UserID)
 }
 
-// AddSong adds a given videoUrl to users playlist
-func (pm *PlaylistManager) AddSong(i *discordgo.InteractionCreate, videoURL string) {
-	userID, _ := strconv.ParseInt(i.Member.User.ID, 10, 64)
+// AddSong adds a given videoUrl to a playlist, inserting at position when it is greater than 0
+func (pm *PlaylistManager) AddSong(ctx context.Context, i *discordgo.InteractionCreate, videoURL string, position int, name string) {
+	t := pm.target(i, name, RoleEditor)
+	if t == nil {
+		return
+	}
 	ytManager := yt.NewYouTubeManager(redis_client.RDB)
 
-	data, err := ytManager.GetVideoMetadata(videoURL)
+	data, err := ytManager.GetVideoMetadata(ctx, videoURL)
 	if err != nil {
+		content := "Oops! Something went wrong while adding to " + t.label() + ". 😅"
+		if reason := yt.Describe(err); reason != "" {
+			content = "Couldn't add that song to " + t.label() + ", " + reason + ". 😅"
+		}
 		pm.session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
-			Content: "Oops! Something went wrong while adding to your playlist. 😅",
+			Content: content,
 		})
 		return
 	}
@@ -178,15 +275,15 @@ func (pm *PlaylistManager) AddSong(i *discordgo.InteractionCreate, videoURL stri
 			return err
 		}
 
-		return tx.Create(&Playlist{UserID: userID, SongID: data.ID}).Error
+		return addEntry(tx, t, data.ID, position)
 	})
 
-	content := "`" + data.Title + "` added to playlist"
+	content := "`" + data.Title + "` added to " + t.label()
 	if err != nil {
 		if strings.Contains(err.Error(), "duplicate") || strings.Contains(err.Error(), "UNIQUE") {
-			content = "`" + data.Title + "` is already in your playlist"
+			content = "`" + data.Title + "` is already in " + t.label()
 		} else {
-			content = "Failed to add `" + data.Title + "` to playlist"
+			content = "Failed to add `" + data.Title + "` to " + t.label()
 		}
 	}
 
@@ -195,12 +292,15 @@ func (pm *PlaylistManager) AddSong(i *discordgo.InteractionCreate, videoURL stri
 	})
 }
 
-// AddPlaylist adds an entire YouTube playlist to the users playlist
-func (pm *PlaylistManager) AddPlaylist(i *discordgo.InteractionCreate, videoURL string) {
-	userID, _ := strconv.ParseInt(i.Member.User.ID, 10, 64)
+// AddPlaylist adds an entire YouTube playlist to a playlist
+func (pm *PlaylistManager) AddPlaylist(ctx context.Context, i *discordgo.InteractionCreate, videoURL string, name string) {
+	t := pm.target(i, name, RoleEditor)
+	if t == nil {
+		return
+	}
 	ytManager := yt.NewYouTubeManager(redis_client.RDB)
 
-	videoIDs, err := ytManager.GetPlaylistVideoIDs(videoURL)
+	videoIDs, err := ytManager.GetPlaylistVideoIDs(ctx, videoURL)
 	if err != nil {
 		pm.session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
 			Content: "Oops! Something went wrong while fetching the playlist. 😅",
@@ -213,68 +313,116 @@ func (pm *PlaylistManager) AddPlaylist(i *discordgo.InteractionCreate, videoURL
 		Content: fmt.Sprintf("Processing %d songs...", len(videoIDs)),
 	})
 
-	addedCount := 0
-	skippedCount := 0
 	total := len(videoIDs)
-
-	for idx, videoID := range videoIDs {
-		videoFullURL := "https://www.youtube.com/watch?v=" + videoID
-		data, err := ytManager.GetVideoMetadata(videoID)
-		if err != nil {
-			continue
-		}
-
+	addedCount, skippedCount, _ := pm.addVideos(ctx, t, videoIDs, func(done int, title string) {
 		// Update progress with song title
-		content := fmt.Sprintf("Processing %d/%d: `%s`", idx+1, total, data.Title)
+		content := fmt.Sprintf("Processing %d/%d: `%s`", done, total, title)
 		pm.session.FollowupMessageEdit(i.Interaction, msg.ID, &discordgo.WebhookEdit{
 			Content: &content,
 		})
+	})
 
-		err = pm.db.Transaction(func(tx *gorm.DB) error {
-			song := Song{
-				ID:          data.ID,
-				Title:       data.Title,
-				Author:      data.Author,
-				Views:       data.Views,
-				Description: data.Description,
-				Duration:    int64(data.Duration.Seconds()),
-				PublishDate: data.PublishDate,
-				URL:         videoFullURL,
-			}
-
-			if err := tx.Save(&song).Error; err != nil {
-				return err
-			}
-
-			return tx.Create(&Playlist{UserID: userID, SongID: data.ID}).Error
-		})
-
-		if err != nil {
-			if strings.Contains(err.Error(), "duplicate") || strings.Contains(err.Error(), "UNIQUE") {
-				skippedCount++
-			}
-			continue
-		}
-
-		addedCount++
-	}
 	// Final message
 	var finalContent string
 	if skippedCount > 0 {
 		finalContent = fmt.Sprintf("Added `%d/%d` songs! (`%d` already in playlist)", addedCount, total, skippedCount)
 	} else {
-		finalContent = fmt.Sprintf("Added `%d/%d` songs to your playlist!", addedCount, total)
+		finalContent = fmt.Sprintf("Added `%d/%d` songs to %s!", addedCount, total, t.label())
 	}
 	pm.session.FollowupMessageEdit(i.Interaction, msg.ID, &discordgo.WebhookEdit{
 		Content: &finalContent,
 	})
 }
 
-// RemoveSong removes song from users playlist given YouTube videoID
-func (pm *PlaylistManager) RemoveSong(i *discordgo.InteractionCreate, songID string) {
-	userID, _ := strconv.ParseInt(i.Member.User.ID, 10, 64)
+// addVideos fetches metadata for each videoID concurrently and appends them to the target playlist in batches, reporting progress at a fixed interval
+func (pm *PlaylistManager) addVideos(ctx context.Context, t *target, videoIDs []string, progress func(done int, title string)) (added, skipped, failed int) {
+	ytManager := yt.NewYouTubeManager(redis_client.RDB)
+	concurrencyLimit := viper.GetInt("youtube.concurrency")
+
+	reporter := newThrottledProgress(progressInterval, progress)
+	videos, failed := FetchMetadataConcurrently(ctx, videoIDs, ytManager, concurrencyLimit, reporter.Update)
+	reporter.Stop()
+
+	var songs []Song
+	var songIDs []string
+	for _, data := range videos {
+		if data == nil {
+			continue
+		}
+		songs = append(songs, Song{
+			ID:          data.ID,
+			Title:       data.Title,
+			Author:      data.Author,
+			Views:       data.Views,
+			Description: data.Description,
+			Duration:    int64(data.Duration.Seconds()),
+			PublishDate: data.PublishDate,
+			URL:         "https://www.youtube.com/watch?v=" + data.ID,
+		})
+		songIDs = append(songIDs, data.ID)
+	}
+	if len(songs) == 0 {
+		return 0, 0, failed
+	}
+
+	err := pm.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(&songs, insertBatchSize).Error; err != nil {
+			return err
+		}
+
+		next, err := nextPosition(tx, t.scope)
+		if err != nil {
+			return err
+		}
+
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(newEntries(t, songIDs, next), insertBatchSize)
+		if res.Error != nil {
+			return res.Error
+		}
+		added = int(res.RowsAffected)
 
-	if err := pm.removeSong(userID, songID); err != nil {
+		// Songs already in the playlist leave gaps in the positions handed out above
+		return compactPositions(tx, t.scope)
+	})
+	if err != nil {
+		return 0, 0, failed + len(songIDs)
+	}
+
+	return added, len(songIDs) - added, failed
+}
+
+// newEntries builds the rows appending songIDs to the target playlist starting at position
+func newEntries(t *target, songIDs []string, position int) any {
+	if t.guild == nil {
+		rows := make([]Playlist, 0, len(songIDs))
+		for idx, songID := range songIDs {
+			rows = append(rows, Playlist{UserID: t.userID, SongID: songID, Position: position + idx})
+		}
+		return &rows
+	}
+
+	now := time.Now()
+	rows := make([]GuildPlaylistSong, 0, len(songIDs))
+	for idx, songID := range songIDs {
+		rows = append(rows, GuildPlaylistSong{
+			PlaylistID: t.guild.ID,
+			SongID:     songID,
+			Position:   position + idx,
+			AddedBy:    t.userID,
+			AddedAt:    now,
+		})
+	}
+	return &rows
+}
+
+// RemoveSong removes song from a playlist given YouTube videoID
+func (pm *PlaylistManager) RemoveSong(i *discordgo.InteractionCreate, songID string, name string) {
+	t := pm.target(i, name, RoleEditor)
+	if t == nil {
+		return
+	}
+
+	if err := pm.removeSongs(t, songID); err != nil {
 		pm.session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
 			Content: "Failed to remove song `" + songID + "`",
 		})
@@ -286,126 +434,146 @@ func (pm *PlaylistManager) RemoveSong(i *discordgo.InteractionCreate, songID str
 	})
 }
 
-// ClearPlaylist is responsible for cleaning the users playlist
-func (pm *PlaylistManager) ClearPlaylist(i *discordgo.InteractionCreate) {
-	userID, _ := strconv.ParseInt(i.Member.User.ID, 10, 64)
+// MoveSong moves the song at position from to position to within a playlist
+func (pm *PlaylistManager) MoveSong(i *discordgo.InteractionCreate, from, to int, name string) {
+	t := pm.target(i, name, RoleEditor)
+	if t == nil {
+		return
+	}
 
-	var playlist []Playlist
-	pm.db.Where("user_id = ?", userID).Find(&playlist)
+	var song Song
+	err := pm.db.Transaction(func(tx *gorm.DB) error {
+		songID, err := moveSong(tx, t.scope, from, to)
+		if err != nil {
+			return err
+		}
+		return tx.First(&song, "id = ?", songID).Error
+	})
 
-	for _, p := range playlist {
-		pm.removeSong(userID, p.SongID)
+	content := ""
+	switch {
+	case errors.Is(err, ErrPositionOutOfRange):
+		count, _ := t.scope.count(pm.db)
+		content = fmt.Sprintf("Position out of range, %s has positions `1` to `%d`", t.label(), count)
+	case err != nil:
+		content = "Oops! Something went wrong while moving that song. 😅"
+	default:
+		content = fmt.Sprintf("Moved `%s` to position `%d`", song.Title, to)
 	}
 
 	pm.session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
-		Content: "All done! Your playlist has been cleared. ✨",
+		Content: content,
 	})
 }
 
-// PlaySong plays a song given videoID from the users playlist, if omitted plays the entire users playlist
-func (pm *PlaylistManager) PlaySong(i *discordgo.InteractionCreate, songID string, voiceConnection *discordgo.VoiceConnection) {
-	userID, _ := strconv.ParseInt(i.Member.User.ID, 10, 64)
+// ClearPlaylist is responsible for cleaning a playlist
+func (pm *PlaylistManager) ClearPlaylist(i *discordgo.InteractionCreate, name string) {
+	t := pm.target(i, name, RoleEditor)
+	if t == nil {
+		return
+	}
+
+	var songIDs []string
+	t.scope.where(pm.db).Pluck("song_id", &songIDs)
+
+	pm.removeSongs(t, songIDs...)
+
+	pm.session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+		Content: "All done! " + strings.ToUpper(t.label()[:1]) + t.label()[1:] + " has been cleared. ✨",
+	})
+}
 
-	var videoIDs []string
-	var initialMsg *discordgo.Message
-	songID, err := youtube.ExtractVideoID(songID) // Works with URLs as well
+// PlaySong plays a song given videoID from a playlist, if omitted plays the playlist according to opts
+func (pm *PlaylistManager) PlaySong(i *discordgo.InteractionCreate, songID string, opts PlayOptions, name string, voiceConnection *discordgo.VoiceConnection) {
+	t := pm.target(i, name, RoleViewer)
+	if t == nil {
+		return
+	}
+
+	var songs []Song
+	skipped := 0
+	songID, _ = youtube.ExtractVideoID(songID) // Works with URLs as well
 	if songID == "" {
 		// Playing entire playlist
-		var playlist []Playlist
-		if err := pm.db.Where("user_id = ?", userID).Preload("Song").Find(&playlist).Error; err != nil {
+		entries, err := loadEntries(pm.db, t)
+		if err != nil {
 			pm.session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
-				Content: "Oops! Something went wrong while fetching your playlist. 😅",
+				Content: "Oops! Something went wrong while fetching " + t.label() + ". 😅",
 			})
 			return
 		}
 
-		if len(playlist) == 0 {
+		if len(entries) == 0 {
 			pm.session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
-				Content: "Looks like your playlist is empty. Add some songs to get started! 🎵",
+				Content: "Looks like " + t.label() + " is empty. Add some songs to get started! 🎵",
 			})
 			return
 		}
 
-		for _, p := range playlist {
-			videoIDs = append(videoIDs, p.Song.ID)
+		entries, skipped, err = selectSongs(entries, opts)
+		if errors.Is(err, ErrStartNotFound) {
+			pm.session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+				Content: "Couldn't find `" + opts.Start + "` in " + t.label() + " 🤔",
+			})
+			return
 		}
-
-		initialMsg, err = pm.session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
-			Content: fmt.Sprintf("Queuing `%d` song(s) from your playlist...", len(videoIDs)),
-		})
-		if err != nil {
-			fmt.Printf("Failed to create initial message: %v\n", err)
+		if len(entries) == 0 {
+			pm.session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+				Content: "Every song selected from " + t.label() + " is unavailable, run `/playlist check` to clean them up ⚠️",
+			})
 			return
 		}
+
+		for _, e := range entries {
+			songs = append(songs, e.Song)
+		}
 	} else {
 		// Playing selected song
-		var playlist Playlist
-		if err := pm.db.Where("user_id = ? AND song_id = ?", userID, songID).Preload("Song").First(&playlist).Error; err != nil {
+		var song Song
+		if err := t.scope.where(pm.db).
+			Joins("JOIN songs ON songs.id = "+t.scope.table+".song_id").
+			Where("song_id = ?", songID).
+			Select("songs.*").
+			Scan(&song).Error; err != nil || song.ID == "" {
 			pm.session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
-				Content: "Song not found in your playlist",
+				Content: "Song not found in " + t.label(),
 			})
 			return
 		}
 
-		videoIDs = []string{playlist.Song.ID}
-
-		initialMsg, err = pm.session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
-			Content: fmt.Sprintf("Queuing `%s`...", playlist.Song.Title),
-		})
-		if err != nil {
-			fmt.Printf("Failed to create initial message: %v\n", err)
+		if song.Unavailable() {
+			pm.session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+				Content: fmt.Sprintf("⚠️ `%s` is unavailable: %s", song.Title, song.UnavailableReason),
+			})
 			return
 		}
+
+		songs = []Song{song}
 	}
 
-	go pm.processPlaylistSongs(videoIDs, i, voiceConnection, initialMsg)
+	pm.queueSongs(i, voiceConnection, songs, opts.Replace)
+	pm.session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+		Content: queuedMessage(songs, skipped, t.label()),
+	})
 }
 
-// processPlaylistSongs handles downloading and queuing songs
-func (pm *PlaylistManager) processPlaylistSongs(videoIDs []string, i *discordgo.InteractionCreate, vc *discordgo.VoiceConnection, initialMsg *discordgo.Message) {
-	ytManager := yt.NewYouTubeManager(redis_client.RDB)
-	concurrencyLimit := viper.GetInt("youtube.concurrency")
-
-	filenames, successCount := DownloadVideosConcurrently(videoIDs, ytManager, concurrencyLimit)
-
-	if successCount == 0 {
-		failMsg := "Oops! Couldn't download any songs from your playlist. 😅"
-		pm.session.FollowupMessageEdit(i.Interaction, initialMsg.ID, &discordgo.WebhookEdit{
-			Content: &failMsg,
-		})
-		return
+// queueSongs adds songs to the guild queue straight away, replacing it when replace is set, their audio is downloaded just ahead of playback
+func (pm *PlaylistManager) queueSongs(i *discordgo.InteractionCreate, vc *discordgo.VoiceConnection, songs []Song, replace bool) {
+	queued := make([]*queue.QueueSong, 0, len(songs))
+	for _, song := range songs {
+		queued = append(queued, queue.NewQueueSong(song.ID, song.Title, i.Member.User.Username, i.ChannelID))
 	}
 
-	for _, filename := range filenames {
-		queue.Enqueue(i.GuildID, filename, i.Member.User.Username)
+	var gq *queue.GuildQueue
+	if replace {
+		gq = queue.ReplaceGuildQueue(i.GuildID, queued...)
+	} else {
+		gq = queue.EnqueueSongs(i.GuildID, queued...)
 	}
 
-	gq, _ := queue.GetGuildQueue(i.GuildID)
-
 	if gq.Session.VC == nil {
 		go queue.PlayNext(pm.session, i.GuildID, vc)
 	}
-
-	// Update user with final status by editing the initial message
-	var finalContent string
-	if successCount < len(videoIDs) {
-		failedCount := len(videoIDs) - successCount
-		if failedCount == 1 {
-			finalContent = fmt.Sprintf("✅ Added `%d` songs to queue! (`1` song couldn't be played)", successCount)
-		} else {
-			finalContent = fmt.Sprintf("✅ Added `%d` songs to queue! (`%d` songs couldn't be played)", successCount, failedCount)
-		}
-	} else {
-		if successCount == 1 {
-			finalContent = "🎵 Song added to queue!"
-		} else {
-			finalContent = fmt.Sprintf("🎵 All `%d` songs added to queue!", successCount)
-		}
-	}
-
-	pm.session.FollowupMessageEdit(i.Interaction, initialMsg.ID, &discordgo.WebhookEdit{
-		Content: &finalContent,
-	})
 }
 
 // Newmanager returns a new instance of PlayListManager
@@ -417,23 +585,16 @@ func NewManager(s *discordgo.Session, r *redis.Client, db *gorm.DB) *PlaylistMan
 	}
 }
 
-// removeSong removes a song from a users playlist, cleaning up any unused song entries
-func (pm *PlaylistManager) removeSong(userID int64, songID string) error {
-	if err := pm.db.Where("user_id = ? AND song_id = ?", userID, songID).Delete(&Playlist{}).Error; err != nil {
-		return err
-	}
-
-	if err := pm.db.Exec(`
-		DELETE FROM songs
-		WHERE id = ?
-		  AND NOT EXISTS (
-			SELECT 1 FROM playlists WHERE song_id = ?
-		  )
-	`, songID, songID).Error; err != nil {
-		return err
-	}
-
-	return nil
+// removeSongs removes songs from a playlist, leaving their rows within the songs table as cached metadata
+func (pm *PlaylistManager) removeSongs(t *target, songIDs ...string) error {
+	return pm.db.Transaction(func(tx *gorm.DB) error {
+		for _, songID := range songIDs {
+			if err := t.scope.remove(tx, songID); err != nil {
+				return err
+			}
+		}
+		return compactPositions(tx, t.scope)
+	})
 }
 
 // EnsureUserExists checks if user exists within the database, else it creates an entry for the user
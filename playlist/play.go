@@ -0,0 +1,67 @@
+package playlist
+
+import (
+	"errors"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+
+	"github.com/kkdai/youtube/v2"
+)
+
+var ErrStartNotFound = errors.New("start song not found in playlist")
+
+// PlayOptions controls which songs of a playlist are queued and how
+type PlayOptions struct {
+	Shuffle bool   // Shuffle the selected songs
+	Start   string // Position or video ID to start from, empty starts at the beginning
+	Count   int    // Maximum songs to queue, 0 queues every song
+	Replace bool   // Clear the guild queue before queuing
+}
+
+// selectSongs picks the songs to queue from a playlist ordered by position, returning how many unavailable songs were skipped
+func selectSongs(entries []Entry, opts PlayOptions) ([]Entry, int, error) {
+	start, err := startIndex(entries, opts.Start)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	selected, skipped := availableSongs(entries[start:])
+	if opts.Shuffle {
+		rand.Shuffle(len(selected), func(i, j int) {
+			selected[i], selected[j] = selected[j], selected[i]
+		})
+	}
+	if opts.Count > 0 && opts.Count < len(selected) {
+		selected = selected[:opts.Count]
+	}
+	return selected, skipped, nil
+}
+
+// startIndex finds the index of the entry given by a position or video ID
+func startIndex(entries []Entry, start string) (int, error) {
+	start = strings.TrimSpace(start)
+	if start == "" {
+		return 0, nil
+	}
+
+	if position, err := strconv.Atoi(start); err == nil {
+		for idx, e := range entries {
+			if e.Position == position {
+				return idx, nil
+			}
+		}
+		return 0, ErrStartNotFound
+	}
+
+	videoID, err := youtube.ExtractVideoID(start) // Works with URLs as well
+	if err != nil {
+		return 0, ErrStartNotFound
+	}
+	for idx, e := range entries {
+		if e.Song.ID == videoID {
+			return idx, nil
+		}
+	}
+	return 0, ErrStartNotFound
+}
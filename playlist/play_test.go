@@ -0,0 +1,79 @@
+package playlist
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func playEntries() []Entry {
+	return []Entry{
+		{Position: 1, Song: Song{ID: "aaaaaaaaaaa"}},
+		{Position: 2, Song: Song{ID: "bbbbbbbbbbb"}},
+		{Position: 3, Song: Song{ID: "ccccccccccc", UnavailableReason: "Private video"}},
+		{Position: 4, Song: Song{ID: "ddddddddddd"}},
+		{Position: 5, Song: Song{ID: "eeeeeeeeeee"}},
+	}
+}
+
+func songIDs(entries []Entry) []string {
+	ids := make([]string, 0, len(entries))
+	for _, e := range entries {
+		ids = append(ids, e.Song.ID)
+	}
+	return ids
+}
+
+func TestSelectSongs_Defaults(t *testing.T) {
+	selected, skipped, err := selectSongs(playEntries(), PlayOptions{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, skipped)
+	assert.Equal(t, []string{"aaaaaaaaaaa", "bbbbbbbbbbb", "ddddddddddd", "eeeeeeeeeee"}, songIDs(selected))
+}
+
+func TestSelectSongs_StartFromPosition(t *testing.T) {
+	selected, skipped, err := selectSongs(playEntries(), PlayOptions{Start: "4"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, skipped)
+	assert.Equal(t, []string{"ddddddddddd", "eeeeeeeeeee"}, songIDs(selected))
+}
+
+func TestSelectSongs_StartFromVideoID(t *testing.T) {
+	selected, _, err := selectSongs(playEntries(), PlayOptions{Start: "https://www.youtube.com/watch?v=bbbbbbbbbbb"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"bbbbbbbbbbb", "ddddddddddd", "eeeeeeeeeee"}, songIDs(selected))
+}
+
+func TestSelectSongs_StartNotFound(t *testing.T) {
+	_, _, err := selectSongs(playEntries(), PlayOptions{Start: "9"})
+	assert.ErrorIs(t, err, ErrStartNotFound)
+
+	_, _, err = selectSongs(playEntries(), PlayOptions{Start: "zzzzzzzzzzz"})
+	assert.ErrorIs(t, err, ErrStartNotFound)
+}
+
+func TestSelectSongs_CountSkipsUnavailable(t *testing.T) {
+	selected, skipped, err := selectSongs(playEntries(), PlayOptions{Start: "2", Count: 2})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, skipped)
+	assert.Equal(t, []string{"bbbbbbbbbbb", "ddddddddddd"}, songIDs(selected))
+}
+
+func TestSelectSongs_ShuffleKeepsSelection(t *testing.T) {
+	selected, _, err := selectSongs(playEntries(), PlayOptions{Shuffle: true, Start: "2"})
+
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"bbbbbbbbbbb", "ddddddddddd", "eeeeeeeeeee"}, songIDs(selected))
+}
+
+func TestSelectSongs_ShuffleWithCount(t *testing.T) {
+	selected, _, err := selectSongs(playEntries(), PlayOptions{Shuffle: true, Count: 2})
+
+	assert.NoError(t, err)
+	assert.Len(t, selected, 2)
+	assert.Subset(t, []string{"aaaaaaaaaaa", "bbbbbbbbbbb", "ddddddddddd", "eeeeeeeeeee"}, songIDs(selected))
+}
@@ -1,50 +1,118 @@
 package playlist
 
 import (
-	"Twilight/utils"
 	"Twilight/yt"
-	"fmt"
+	"context"
 	"sync"
+	"time"
 )
 
-// DownloadVideosConcurrently downloads a list of video IDs with limited concurrency
-func DownloadVideosConcurrently(videoIDs []string, ytManager *yt.YouTubeManager, maxConcurrency int) ([]string, int) {
-	successCount := 0
-	orderedFilenames := make([]string, len(videoIDs))
+// FetchMetadataConcurrently fetches metadata for a list of video IDs with limited concurrency, keeping the order of videoIDs
+func FetchMetadataConcurrently(ctx context.Context, videoIDs []string, ytManager *yt.YouTubeManager, maxConcurrency int, progress func(done int, title string)) ([]*yt.Video, int) {
+	if maxConcurrency < 1 {
+		maxConcurrency = 1
+	}
+
+	videos := make([]*yt.Video, len(videoIDs))
+	done, failed := 0, 0
 	var mu sync.Mutex
 	var wg sync.WaitGroup
-	concurrencySem := make(chan struct{}, maxConcurrency)
+	jobs := make(chan int)
 
-	// Loops over each videoID and runs go-routines to download concurrently
-	for idx, videoID := range videoIDs {
+	// Fixed pool of workers pulling indexes of videoIDs to fetch
+	for range maxConcurrency {
 		wg.Add(1)
-		go func(index int, vid string) {
+		go func() {
 			defer wg.Done()
+			for index := range jobs {
+				// Once ctx ends the remaining fetches fail straight away and are counted as failed
+				video, err := ytManager.GetVideoMetadata(ctx, videoIDs[index])
 
-			concurrencySem <- struct{}{}
-			defer func() { <-concurrencySem }()
-
-			if err := ytManager.DownloadAudio(vid); err != nil {
-				fmt.Printf("DEBUG: Download error for %s: %v\n", vid, err)
-				return
+				mu.Lock()
+				done++
+				if err != nil {
+					failed++
+				} else {
+					videos[index] = video
+					progress(done, video.Title)
+				}
+				mu.Unlock()
 			}
-
-			mu.Lock()
-			orderedFilenames[index] = utils.GetAudioFile(vid)
-			successCount++
-			mu.Unlock()
-		}(idx, videoID)
+		}()
 	}
 
+	for index := range videoIDs {
+		jobs <- index
+	}
+	close(jobs)
 	wg.Wait()
 
-	// Filter out failed downloads
-	var filenames []string
-	for _, fn := range orderedFilenames {
-		if fn != "" {
-			filenames = append(filenames, fn)
+	return videos, failed
+}
+
+// throttledProgress reports the latest progress at most once per interval
+type throttledProgress struct {
+	mu     sync.Mutex
+	done   int
+	title  string
+	dirty  bool
+	report func(done int, title string)
+	stop   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// newThrottledProgress starts reporting progress updates every interval until stopped
+func newThrottledProgress(interval time.Duration, report func(done int, title string)) *throttledProgress {
+	ticker := time.NewTicker(interval)
+	return startThrottledProgress(ticker.C, ticker.Stop, report)
+}
+
+// startThrottledProgress reports progress updates whenever ticks fires until stopped, calling release once stopped
+func startThrottledProgress(ticks <-chan time.Time, release func(), report func(done int, title string)) *throttledProgress {
+	p := &throttledProgress{report: report, stop: make(chan struct{})}
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer release()
+		for {
+			select {
+			case <-ticks:
+				p.flush()
+			case <-p.stop:
+				return
+			}
 		}
+	}()
+
+	return p
+}
+
+// Update records the latest progress to be reported on the next tick
+func (p *throttledProgress) Update(done int, title string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done = done
+	p.title = title
+	p.dirty = true
+}
+
+// Stop ends reporting, dropping any progress not yet reported
+func (p *throttledProgress) Stop() {
+	close(p.stop)
+	p.wg.Wait()
+}
+
+// flush reports the latest progress if it changed since the last report
+func (p *throttledProgress) flush() {
+	p.mu.Lock()
+	if !p.dirty {
+		p.mu.Unlock()
+		return
 	}
+	done, title := p.done, p.title
+	p.dirty = false
+	p.mu.Unlock()
 
-	return filenames, successCount
+	p.report(done, title)
 }
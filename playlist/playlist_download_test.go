@@ -0,0 +1,99 @@
+package playlist
+
+import (
+	"Twilight/yt"
+	"Twilight/yt/yttest"
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Strum355/log"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// useFakeYouTube serves the given videos through a fake backend and returns a manager caching in memory
+func useFakeYouTube(t *testing.T, videos ...*yt.Video) (*yt.YouTubeManager, *yttest.Backend) {
+	log.InitSimpleLogger(&log.Config{Output: io.Discard})
+	viper.Set("youtube.retries", 0)
+	t.Cleanup(func() { viper.Set("youtube.retries", 3) })
+
+	backend := yttest.NewBackend()
+	for _, video := range videos {
+		backend.AddVideo(video)
+	}
+	yttest.Use(t, backend)
+	return yt.NewYouTubeManagerWithStore(yttest.NewStore()), backend
+}
+
+func TestFetchMetadataConcurrently_KeepsOrder(t *testing.T) {
+	ym, _ := useFakeYouTube(t, &yt.Video{ID: "a", Title: "First"}, &yt.Video{ID: "c", Title: "Third"})
+
+	var mu sync.Mutex
+	titles := []string{}
+	videos, failed := FetchMetadataConcurrently(context.Background(), []string{"a", "b", "c"}, ym, 2, func(done int, title string) {
+		mu.Lock()
+		defer mu.Unlock()
+		titles = append(titles, title)
+	})
+
+	assert.Equal(t, 1, failed)
+	assert.Equal(t, "First", videos[0].Title)
+	assert.Nil(t, videos[1])
+	assert.Equal(t, "Third", videos[2].Title)
+	assert.ElementsMatch(t, []string{"First", "Third"}, titles)
+}
+
+func TestResolveEntry(t *testing.T) {
+	ym, backend := useFakeYouTube(t)
+	backend.AddSearch("Artist Song", yt.SearchResult{ID: "found"})
+
+	videoID, err := resolveEntry(context.Background(), ym, FileEntry{URL: "https://youtu.be/dQw4w9WgXcQ"})
+	assert.NoError(t, err)
+	assert.Equal(t, "dQw4w9WgXcQ", videoID)
+
+	videoID, err = resolveEntry(context.Background(), ym, FileEntry{Title: "Song", Author: "Artist"})
+	assert.NoError(t, err)
+	assert.Equal(t, "found", videoID)
+	assert.Equal(t, 1, backend.Calls("Search", "Artist Song"))
+
+	_, err = resolveEntry(context.Background(), ym, FileEntry{})
+	assert.Error(t, err)
+}
+
+func TestThrottledProgress_ReportsLatest(t *testing.T) {
+	ticks := make(chan time.Time)
+	var reports []int
+
+	p := startThrottledProgress(ticks, func() {}, func(done int, title string) {
+		reports = append(reports, done)
+	})
+
+	for done := 1; done <= 50; done++ {
+		p.Update(done, "song")
+	}
+	ticks <- time.Now()
+	ticks <- time.Now() // Nothing changed since the last report
+	p.Update(60, "song")
+	ticks <- time.Now()
+	p.Stop()
+
+	assert.Equal(t, []int{50, 60}, reports)
+}
+
+func TestThrottledProgress_NoUpdates(t *testing.T) {
+	ticks := make(chan time.Time)
+	called, released := false, false
+
+	p := startThrottledProgress(ticks, func() { released = true }, func(done int, title string) {
+		called = true
+	})
+	ticks <- time.Now()
+	ticks <- time.Now()
+	p.Stop()
+
+	assert.False(t, called)
+	assert.True(t, released)
+}
@@ -0,0 +1,133 @@
+package playlist
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+var ErrPositionOutOfRange = errors.New("position out of range")
+
+// entryScope identifies the table and owner key holding the entries of a playlist
+type entryScope struct {
+	table        string // Table storing the entries
+	column       string // Column identifying the owner of the entries
+	key          any    // Value of the owner column
+	parent       string // Table holding the owner, whose row is locked while positions change
+	parentColumn string // Key column of the parent table
+}
+
+// personalScope returns the scope of a users personal playlist
+func personalScope(userID int64) entryScope {
+	return entryScope{table: "playlists", column: "user_id", key: userID, parent: "users", parentColumn: "user_id"}
+}
+
+// guildScope returns the scope of a guild playlist
+func guildScope(playlistID uint) entryScope {
+	return entryScope{table: "guild_playlist_songs", column: "playlist_id", key: playlistID, parent: "guild_playlists", parentColumn: "id"}
+}
+
+// where returns a query restricted to the entries within the scope
+func (sc entryScope) where(tx *gorm.DB) *gorm.DB {
+	return tx.Table(sc.table).Where(sc.column+" = ?", sc.key)
+}
+
+// count returns the number of entries within the scope
+func (sc entryScope) count(tx *gorm.DB) (int64, error) {
+	var count int64
+	err := sc.where(tx).Count(&count).Error
+	return count, err
+}
+
+// remove deletes a song from the entries within the scope
+func (sc entryScope) remove(tx *gorm.DB, songID string) error {
+	return tx.Exec("DELETE FROM "+sc.table+" WHERE "+sc.column+" = ? AND song_id = ?", sc.key, songID).Error
+}
+
+// lock holds the owner row of the entries until tx ends, so concurrent changes to a playlist read positions one after
+// another rather than handing out the same position twice
+func (sc entryScope) lock(tx *gorm.DB) error {
+	return tx.Exec("SELECT 1 FROM "+sc.parent+" WHERE "+sc.parentColumn+" = ? FOR UPDATE", sc.key).Error
+}
+
+// nextPosition returns the position after the last song within a playlist, locking the playlist
+func nextPosition(tx *gorm.DB, sc entryScope) (int, error) {
+	if err := sc.lock(tx); err != nil {
+		return 0, err
+	}
+	var last int
+	err := sc.where(tx).Select("COALESCE(MAX(position), 0)").Scan(&last).Error
+	return last + 1, err
+}
+
+// makeRoom shifts songs down to free up position and returns the position to insert at, a position of 0 appends to the end
+func makeRoom(tx *gorm.DB, sc entryScope, position int) (int, error) {
+	next, err := nextPosition(tx, sc)
+	if err != nil {
+		return 0, err
+	}
+
+	if position <= 0 || position > next {
+		return next, nil
+	}
+
+	err = tx.Exec(
+		"UPDATE "+sc.table+" SET position = position + 1 WHERE "+sc.column+" = ? AND position >= ?",
+		sc.key, position,
+	).Error
+	return position, err
+}
+
+// moveSong moves the song at position from to position to, shifting the songs in between, and returns its song ID
+func moveSong(tx *gorm.DB, sc entryScope, from, to int) (string, error) {
+	if err := sc.lock(tx); err != nil {
+		return "", err
+	}
+	count, err := sc.count(tx)
+	if err != nil {
+		return "", err
+	}
+	if from < 1 || to < 1 || int64(from) > count || int64(to) > count {
+		return "", ErrPositionOutOfRange
+	}
+
+	var songID string
+	if err := sc.where(tx).Where("position = ?", from).Limit(1).Pluck("song_id", &songID).Error; err != nil {
+		return "", err
+	}
+	if songID == "" {
+		return "", gorm.ErrRecordNotFound
+	}
+	if from == to {
+		return songID, nil
+	}
+
+	shift := "UPDATE " + sc.table + " SET position = position + 1 WHERE " + sc.column + " = ? AND position >= ? AND position < ?"
+	lo, hi := to, from
+	if from < to {
+		shift = "UPDATE " + sc.table + " SET position = position - 1 WHERE " + sc.column + " = ? AND position > ? AND position <= ?"
+		lo, hi = from, to
+	}
+	if err := tx.Exec(shift, sc.key, lo, hi).Error; err != nil {
+		return "", err
+	}
+
+	return songID, sc.where(tx).Where("song_id = ?", songID).Update("position", to).Error
+}
+
+// compactPositions renumbers a playlist so positions stay dense after removals
+func compactPositions(tx *gorm.DB, sc entryScope) error {
+	if err := sc.lock(tx); err != nil {
+		return err
+	}
+	return tx.Exec(`
+		UPDATE `+sc.table+` p
+		SET position = o.rn
+		FROM (
+			SELECT song_id, ROW_NUMBER() OVER (ORDER BY position, song_id) AS rn
+			FROM `+sc.table+`
+			WHERE `+sc.column+` = ?
+		) o
+		WHERE p.`+sc.column+` = ? AND p.song_id = o.song_id AND p.position <> o.rn
+	`, sc.key, sc.key).Error
+}
@@ -0,0 +1,202 @@
+package playlist
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// scriptedDB is a database/sql connector answering each query with the next scripted row and recording every statement
+type scriptedDB struct {
+	mu         sync.Mutex
+	rows       []scriptedRow
+	statements []string
+	args       [][]driver.Value
+}
+
+// scriptedRow answers a single query, its columns only need names when it is scanned into a model
+type scriptedRow struct {
+	columns []string
+	values  []driver.Value
+}
+
+// value returns a row holding a single unnamed column
+func value(v driver.Value) scriptedRow {
+	return scriptedRow{values: []driver.Value{v}}
+}
+
+// newScriptedDB opens a Postgres flavoured gorm DB whose queries return rows in order
+func newScriptedDB(t *testing.T, rows ...scriptedRow) (*gorm.DB, *scriptedDB) {
+	script := &scriptedDB{rows: rows}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sql.OpenDB(script)}), &gorm.Config{
+		Logger:               logger.Default.LogMode(logger.Silent),
+		DisableAutomaticPing: true,
+	})
+	require.NoError(t, err)
+	return db, script
+}
+
+func (s *scriptedDB) Connect(context.Context) (driver.Conn, error) { return scriptedConn{s}, nil }
+func (s *scriptedDB) Driver() driver.Driver                        { return nil }
+
+// record keeps a statement, returning the row answering it when it is a query
+func (s *scriptedDB) record(query string, args []driver.Value, isQuery bool) *scriptedRow {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.statements = append(s.statements, query)
+	s.args = append(s.args, args)
+	if !isQuery || len(s.rows) == 0 {
+		return nil
+	}
+	row := s.rows[0]
+	s.rows = s.rows[1:]
+	return &row
+}
+
+// statement returns the index of the first statement containing text, -1 when none does
+func (s *scriptedDB) statement(text string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for idx, statement := range s.statements {
+		if strings.Contains(statement, text) {
+			return idx
+		}
+	}
+	return -1
+}
+
+type scriptedConn struct{ db *scriptedDB }
+
+func (c scriptedConn) Prepare(query string) (driver.Stmt, error) {
+	return scriptedStmt{c.db, query}, nil
+}
+
+func (c scriptedConn) Close() error              { return nil }
+func (c scriptedConn) Begin() (driver.Tx, error) { return scriptedTx{}, nil }
+
+type scriptedTx struct{}
+
+func (scriptedTx) Commit() error   { return nil }
+func (scriptedTx) Rollback() error { return nil }
+
+type scriptedStmt struct {
+	db    *scriptedDB
+	query string
+}
+
+func (s scriptedStmt) Close() error  { return nil }
+func (s scriptedStmt) NumInput() int { return -1 }
+
+func (s scriptedStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.db.record(s.query, args, false)
+	return driver.RowsAffected(1), nil
+}
+
+func (s scriptedStmt) Query(args []driver.Value) (driver.Rows, error) {
+	row := s.db.record(s.query, args, true)
+	return &scriptedRows{row: row}, nil
+}
+
+type scriptedRows struct {
+	row  *scriptedRow
+	done bool
+}
+
+func (r *scriptedRows) Columns() []string {
+	if r.row == nil {
+		return []string{"value"}
+	}
+	if r.row.columns != nil {
+		return r.row.columns
+	}
+	columns := make([]string, len(r.row.values))
+	for idx := range columns {
+		columns[idx] = "value"
+	}
+	return columns
+}
+
+func (r *scriptedRows) Close() error { return nil }
+
+func (r *scriptedRows) Next(dest []driver.Value) error {
+	if r.done || r.row == nil {
+		return io.EOF
+	}
+	r.done = true
+	copy(dest, r.row.values)
+	return nil
+}
+
+func TestMakeRoom_ShiftsSongsDown(t *testing.T) {
+	db, script := newScriptedDB(t, value(int64(3)))
+
+	position, err := makeRoom(db, personalScope(7), 2)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, position)
+	lock := script.statement("FROM users WHERE user_id = $1 FOR UPDATE")
+	shift := script.statement("SET position = position + 1")
+	if assert.GreaterOrEqual(t, lock, 0) && assert.Greater(t, shift, lock) {
+		assert.Equal(t, []driver.Value{int64(7), int64(2)}, script.args[shift])
+	}
+}
+
+func TestMakeRoom_AppendsPastTheEnd(t *testing.T) {
+	for _, requested := range []int{0, 9} {
+		db, script := newScriptedDB(t, value(int64(3)))
+
+		position, err := makeRoom(db, personalScope(7), requested)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 4, position, "requested %d", requested)
+		assert.Equal(t, -1, script.statement("SET position = position + 1"))
+	}
+}
+
+func TestMoveSong_ShiftsSongsBetween(t *testing.T) {
+	db, script := newScriptedDB(t, value(int64(3)), value("b"))
+
+	songID, err := moveSong(db, guildScope(5), 1, 3)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "b", songID)
+	lock := script.statement("FROM guild_playlists WHERE id = $1 FOR UPDATE")
+	shift := script.statement("SET position = position - 1")
+	if assert.Equal(t, 0, lock) && assert.Greater(t, shift, lock) {
+		assert.Equal(t, []driver.Value{int64(5), int64(1), int64(3)}, script.args[shift])
+	}
+	last := len(script.statements) - 1
+	assert.Contains(t, script.statements[last], `"position"=$1`)
+	assert.Equal(t, int64(3), script.args[last][0])
+}
+
+func TestMoveSong_Up(t *testing.T) {
+	db, script := newScriptedDB(t, value(int64(4)), value("d"))
+
+	songID, err := moveSong(db, personalScope(7), 4, 2)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "d", songID)
+	shift := script.statement("SET position = position + 1")
+	if assert.GreaterOrEqual(t, shift, 0) {
+		assert.Equal(t, []driver.Value{int64(7), int64(2), int64(4)}, script.args[shift])
+	}
+}
+
+func TestMoveSong_OutOfRange(t *testing.T) {
+	db, _ := newScriptedDB(t, value(int64(2)))
+
+	_, err := moveSong(db, personalScope(7), 1, 3)
+
+	assert.ErrorIs(t, err, ErrPositionOutOfRange)
+}
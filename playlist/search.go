@@ -0,0 +1,291 @@
+package playlist
+
+import (
+	"Twilight/utils"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// SearchPrefix starts the custom IDs of the select menus on search results
+	SearchPrefix       = "search:"
+	searchQueuePrefix  = SearchPrefix + "queue:"
+	searchRemovePrefix = SearchPrefix + "remove:"
+
+	maxSearchResults = 25 // Select menus hold at most 25 options
+	maxLabelLength   = 80
+)
+
+var ErrInvalidDuration = errors.New("invalid duration")
+
+// SearchFilter narrows down the songs matched by a playlist search
+type SearchFilter struct {
+	Query       string
+	Author      string
+	MinDuration int64 // Seconds, 0 for no lower bound
+	MaxDuration int64 // Seconds, 0 for no upper bound
+}
+
+// ParseDuration reads a duration given as seconds, mm:ss or hh:mm:ss, an empty string is 0
+func ParseDuration(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+
+	parts := strings.Split(s, ":")
+	if len(parts) > 3 {
+		return 0, ErrInvalidDuration
+	}
+
+	var total int64
+	for idx, part := range parts {
+		value, err := strconv.ParseInt(part, 10, 64)
+		if err != nil || value < 0 || (idx > 0 && value >= 60) {
+			return 0, ErrInvalidDuration
+		}
+		total = total*60 + value
+	}
+	return total, nil
+}
+
+// escapeLike escapes the wildcard characters of a LIKE pattern
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// searchEntries finds the songs within the target playlist matching the filter, best matches first
+func searchEntries(db *gorm.DB, t *target, f SearchFilter) ([]Entry, error) {
+	table := t.scope.table
+	q := t.scope.where(db).
+		Joins("JOIN songs ON songs.id = " + table + ".song_id").
+		Select("songs.*, " + table + ".position")
+
+	if f.Query != "" {
+		// Substring matches use the trigram indexes, word similarity catches typos
+		pattern := "%" + escapeLike(f.Query) + "%"
+		q = q.Where(
+			"(songs.title ILIKE ? OR songs.author ILIKE ? OR ? <% songs.title OR ? <% songs.author)",
+			pattern, pattern, f.Query, f.Query,
+		).Order(clause.Expr{
+			SQL:  "GREATEST(word_similarity(?, songs.title), word_similarity(?, songs.author)) DESC",
+			Vars: []any{f.Query, f.Query},
+		})
+	}
+	if f.Author != "" {
+		q = q.Where("songs.author ILIKE ?", "%"+escapeLike(f.Author)+"%")
+	}
+	if f.MinDuration > 0 {
+		q = q.Where("songs.duration >= ?", f.MinDuration)
+	}
+	if f.MaxDuration > 0 {
+		q = q.Where("songs.duration <= ?", f.MaxDuration)
+	}
+
+	var rows []struct {
+		Song     `gorm:"embedded"`
+		Position int
+	}
+	if err := q.Order(table + ".position").Limit(maxSearchResults).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0, len(rows))
+	for _, row := range rows {
+		entries = append(entries, Entry{Position: row.Position, Song: row.Song})
+	}
+	return entries, nil
+}
+
+// SearchPlaylist lists the songs within a playlist matching the filter, with menus to queue or remove them
+func (pm *PlaylistManager) SearchPlaylist(i *discordgo.InteractionCreate, f SearchFilter, name string) {
+	t := pm.target(i, name, RoleViewer)
+	if t == nil {
+		return
+	}
+
+	if f.MaxDuration > 0 && f.MinDuration > f.MaxDuration {
+		pm.session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+			Content: "The minimum duration can't be longer than the maximum 🤔",
+		})
+		return
+	}
+
+	entries, err := searchEntries(pm.db, t, f)
+	if err != nil {
+		pm.session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+			Content: "Oops! Something went wrong while searching " + t.label() + ". 😅",
+		})
+		return
+	}
+	if len(entries) == 0 {
+		pm.session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+			Content: "No songs in " + t.label() + " match `" + f.Query + "` 🔍",
+		})
+		return
+	}
+
+	pm.session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+		Embeds:     []*discordgo.MessageEmbed{createSearchEmbed(t.title(), f.Query, entries)},
+		Components: searchComponents(i.Member.User.ID, name, entries, t.role.Allows(RoleEditor)),
+	})
+}
+
+// createSearchEmbed lists search results along with their playlist positions
+func createSearchEmbed(title string, query string, entries []Entry) *discordgo.MessageEmbed {
+	text := ""
+	for _, e := range entries {
+		text += fmt.Sprintf("%d. `%s` by %s `[%s]`", e.Position, truncate(e.Song.Title, maxLabelLength), truncate(e.Song.Author, maxLabelLength),
+			utils.FormatYtDuration(time.Duration(e.Song.Duration)*time.Second))
+		if e.Song.Unavailable() {
+			text += " ⚠️"
+		}
+		text += "\n"
+	}
+
+	return &discordgo.MessageEmbed{
+		Title:       "🔍 " + title + ": " + truncate(query, maxLabelLength),
+		Description: text,
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: fmt.Sprintf("%d result(s)", len(entries)),
+		},
+		Color: viper.GetInt("theme"),
+	}
+}
+
+// searchComponents builds the select menus for queuing and removing search results
+func searchComponents(userID string, name string, entries []Entry, canEdit bool) []discordgo.MessageComponent {
+	options := make([]discordgo.SelectMenuOption, 0, len(entries))
+	for _, e := range entries {
+		options = append(options, discordgo.SelectMenuOption{
+			Label:       truncate(fmt.Sprintf("%d. %s", e.Position, e.Song.Title), maxLabelLength),
+			Value:       e.Song.ID,
+			Description: truncate(e.Song.Author, maxLabelLength),
+		})
+	}
+
+	one := 1
+	components := []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.SelectMenu{
+					CustomID:    searchQueuePrefix + userID + ":" + name,
+					Placeholder: "▶️ Queue songs",
+					MinValues:   &one,
+					MaxValues:   len(options),
+					Options:     options,
+				},
+			},
+		},
+	}
+	if canEdit {
+		components = append(components, discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.SelectMenu{
+					CustomID:    searchRemovePrefix + userID + ":" + name,
+					Placeholder: "🗑️ Remove songs",
+					MinValues:   &one,
+					MaxValues:   len(options),
+					Options:     options,
+				},
+			},
+		})
+	}
+	return components
+}
+
+// IsSearchQueue reports whether a component custom ID belongs to the queue menu of search results
+func IsSearchQueue(customID string) bool {
+	return strings.HasPrefix(customID, searchQueuePrefix)
+}
+
+// QueueSelected queues the songs picked from the search results menu, in playlist order
+func (pm *PlaylistManager) QueueSelected(i *discordgo.InteractionCreate, voiceConnection *discordgo.VoiceConnection) {
+	data := i.MessageComponentData()
+	t := pm.componentTarget(i, searchQueuePrefix, RoleViewer)
+	if t == nil {
+		return
+	}
+
+	var songs []Song
+	if err := t.scope.where(pm.db).
+		Joins("JOIN songs ON songs.id = "+t.scope.table+".song_id").
+		Where(t.scope.table+".song_id IN ?", data.Values).
+		Order(t.scope.table + ".position").
+		Select("songs.*").
+		Scan(&songs).Error; err != nil || len(songs) == 0 {
+		pm.session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+			Content: "Those songs are no longer in " + t.label() + " 🤔",
+		})
+		return
+	}
+
+	var available []Song
+	for _, song := range songs {
+		if !song.Unavailable() {
+			available = append(available, song)
+		}
+	}
+	if len(available) == 0 {
+		pm.session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+			Content: "Those songs are unavailable, run `/playlist check` to clean them up ⚠️",
+		})
+		return
+	}
+
+	pm.queueSongs(i, voiceConnection, available, false)
+	pm.session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+		Content: queuedMessage(available, len(songs)-len(available), t.label()),
+	})
+}
+
+// RemoveSelected removes the songs picked from the search results menu
+func (pm *PlaylistManager) RemoveSelected(i *discordgo.InteractionCreate) {
+	data := i.MessageComponentData()
+	t := pm.componentTarget(i, searchRemovePrefix, RoleEditor)
+	if t == nil {
+		return
+	}
+
+	var songIDs []string
+	if err := t.scope.where(pm.db).Where("song_id IN ?", data.Values).Pluck("song_id", &songIDs).Error; err != nil {
+		pm.session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+			Content: "Oops! Something went wrong while fetching " + t.label() + ". 😅",
+		})
+		return
+	}
+	if len(songIDs) == 0 {
+		pm.session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+			Content: "Those songs are no longer in " + t.label() + " 🤔",
+		})
+		return
+	}
+
+	if err := pm.removeSongs(t, songIDs...); err != nil {
+		pm.session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+			Content: "Oops! Something went wrong while removing songs from " + t.label() + ". 😅",
+		})
+		return
+	}
+
+	pm.session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+		Content: fmt.Sprintf("🗑️ Removed `%d` song(s) from %s", len(songIDs), t.label()),
+	})
+}
+
+// truncate shortens s to at most n runes, marking the cut with an ellipsis
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
@@ -0,0 +1,69 @@
+package playlist
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDuration(t *testing.T) {
+	cases := map[string]int64{
+		"":        0,
+		"45":      45,
+		"3:30":    210,
+		"1:02:03": 3723,
+		" 0:05 ":  5,
+	}
+	for input, expected := range cases {
+		got, err := ParseDuration(input)
+		assert.NoError(t, err, input)
+		assert.Equal(t, expected, got, input)
+	}
+}
+
+func TestParseDuration_Invalid(t *testing.T) {
+	for _, input := range []string{"abc", "1:60", "-5", "1:2:3:4", "3:"} {
+		_, err := ParseDuration(input)
+		assert.ErrorIs(t, err, ErrInvalidDuration, input)
+	}
+}
+
+func TestEscapeLike(t *testing.T) {
+	assert.Equal(t, `100\% pure\_hits \\o/`, escapeLike(`100% pure_hits \o/`))
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "short", truncate("short", 10))
+	assert.Equal(t, "abcd…", truncate("abcdefgh", 5))
+	assert.Equal(t, "ñañ…", truncate("ñañañaña", 4))
+}
+
+func TestSearchComponents(t *testing.T) {
+	entries := []Entry{
+		{Position: 3, Song: Song{ID: "a", Title: "First", Author: "Someone"}},
+		{Position: 7, Song: Song{ID: "b", Title: "Second", Author: "Someone Else"}},
+	}
+
+	components := searchComponents("42", "mix", entries, true)
+	assert.Len(t, components, 2)
+
+	queueMenu := components[0].(discordgo.ActionsRow).Components[0].(discordgo.SelectMenu)
+	assert.Equal(t, "search:queue:42:mix", queueMenu.CustomID)
+	assert.True(t, IsSearchQueue(queueMenu.CustomID))
+	assert.Equal(t, 2, queueMenu.MaxValues)
+	assert.Equal(t, "3. First", queueMenu.Options[0].Label)
+	assert.Equal(t, "b", queueMenu.Options[1].Value)
+
+	removeMenu := components[1].(discordgo.ActionsRow).Components[0].(discordgo.SelectMenu)
+	assert.Equal(t, "search:remove:42:mix", removeMenu.CustomID)
+	assert.False(t, IsSearchQueue(removeMenu.CustomID))
+}
+
+func TestSearchComponents_ViewerCannotRemove(t *testing.T) {
+	entries := []Entry{{Position: 1, Song: Song{ID: "a", Title: "Only"}}}
+
+	components := searchComponents("42", "", entries, false)
+
+	assert.Len(t, components, 1)
+}
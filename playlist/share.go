@@ -0,0 +1,147 @@
+package playlist
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"gorm.io/gorm"
+)
+
+type Share struct {
+	Code            string `gorm:"primaryKey"`
+	UserID          int64  // User who created the share code
+	GuildPlaylistID *uint  // Shared guild playlist, nil when sharing a personal playlist
+	CreatedAt       time.Time
+	ExpiresAt       *time.Time
+}
+
+func (Share) TableName() string {
+	return "playlist_shares"
+}
+
+var ErrShareExpired = errors.New("share code expired")
+
+// newShareCode generates an opaque random share code
+func newShareCode() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b), nil
+}
+
+// SharePlaylist creates a share code for a playlist which optionally expires after a number of hours
+func (pm *PlaylistManager) SharePlaylist(i *discordgo.InteractionCreate, expiresHours int, name string) {
+	t := pm.target(i, name, RoleViewer)
+	if t == nil {
+		return
+	}
+
+	code, err := newShareCode()
+	if err != nil {
+		pm.session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+			Content: "Oops! Something went wrong while sharing " + t.label() + ". 😅",
+		})
+		return
+	}
+
+	share := Share{Code: code, UserID: t.userID}
+	if t.guild != nil {
+		share.GuildPlaylistID = &t.guild.ID
+	}
+	if expiresHours > 0 {
+		expires := time.Now().Add(time.Duration(expiresHours) * time.Hour)
+		share.ExpiresAt = &expires
+	}
+
+	if err := pm.db.Create(&share).Error; err != nil {
+		pm.session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+			Content: "Oops! Something went wrong while sharing " + t.label() + ". 😅",
+		})
+		return
+	}
+
+	content := fmt.Sprintf("🔗 Share code for %s: `%s`\nFriends can copy it with `/playlist clone %s`", t.label(), code, code)
+	if share.ExpiresAt != nil {
+		content += fmt.Sprintf("\nExpires <t:%d:R>", share.ExpiresAt.Unix())
+	}
+
+	pm.session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+		Content: content,
+	})
+}
+
+// ClonePlaylist copies every song from a shared playlist into a playlist, reusing the stored songs
+func (pm *PlaylistManager) ClonePlaylist(i *discordgo.InteractionCreate, code string, name string) {
+	t := pm.target(i, name, RoleEditor)
+	if t == nil {
+		return
+	}
+
+	code = strings.ToUpper(strings.TrimSpace(code))
+	added, present := 0, 0
+	err := pm.db.Transaction(func(tx *gorm.DB) error {
+		var share Share
+		if err := tx.Where("code = ?", code).First(&share).Error; err != nil {
+			return err
+		}
+		if share.ExpiresAt != nil && share.ExpiresAt.Before(time.Now()) {
+			return ErrShareExpired
+		}
+
+		source := personalScope(share.UserID)
+		if share.GuildPlaylistID != nil {
+			source = guildScope(*share.GuildPlaylistID)
+		}
+
+		var songIDs []string
+		if err := source.where(tx).Order("position").Pluck("song_id", &songIDs).Error; err != nil {
+			return err
+		}
+
+		var existing []string
+		if err := t.scope.where(tx).Pluck("song_id", &existing).Error; err != nil {
+			return err
+		}
+		inPlaylist := make(map[string]bool, len(existing))
+		for _, songID := range existing {
+			inPlaylist[songID] = true
+		}
+
+		for _, songID := range songIDs {
+			if inPlaylist[songID] {
+				present++
+				continue
+			}
+			if err := addEntry(tx, t, songID, 0); err != nil {
+				return err
+			}
+			inPlaylist[songID] = true
+			added++
+		}
+		return nil
+	})
+
+	content := ""
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		content = "There's no playlist shared with the code `" + code + "` 🤔"
+	case errors.Is(err, ErrShareExpired):
+		content = "The share code `" + code + "` has expired ⌛"
+	case err != nil:
+		content = "Oops! Something went wrong while copying the shared playlist. 😅"
+	case present > 0:
+		content = fmt.Sprintf("📋 Copied `%d` song(s) into %s! (`%d` already present)", added, t.label(), present)
+	default:
+		content = fmt.Sprintf("📋 Copied `%d` song(s) into %s!", added, t.label())
+	}
+
+	pm.session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+		Content: content,
+	})
+}
@@ -0,0 +1,18 @@
+package playlist
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewShareCode(t *testing.T) {
+	first, err := newShareCode()
+	assert.NoError(t, err)
+	second, err := newShareCode()
+	assert.NoError(t, err)
+
+	assert.Len(t, first, 16)
+	assert.Regexp(t, "^[A-Z2-7]+$", first)
+	assert.NotEqual(t, first, second)
+}
@@ -0,0 +1,266 @@
+package playlist
+
+import (
+	"Twilight/redis_client"
+	"Twilight/yt"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Strum355/log"
+	"github.com/bwmarrin/discordgo"
+	"gorm.io/gorm"
+)
+
+type PlaylistSource struct {
+	UserID       int64 `gorm:"primaryKey"`
+	URL          string
+	Prune        bool // Remove songs which were removed from the source
+	LastSyncedAt *time.Time
+}
+
+type PlaylistSourceSong struct {
+	UserID int64  `gorm:"primaryKey"`
+	SongID string `gorm:"primaryKey"`
+}
+
+// SyncResult counts the changes made when syncing a playlist with its source
+type SyncResult struct {
+	Added   int // New videos added to the playlist
+	Skipped int // New videos which were already in the playlist
+	Failed  int // New videos which couldn't be fetched
+	Removed int // Videos removed from the playlist as they left the source
+	Missing int // Videos which left the source but were kept
+}
+
+// diffSource works out which videos to add and remove given the source listing, the videos seen at the last sync and the stored playlist
+func diffSource(source, previous, stored []string) (toAdd, toRemove []string) {
+	inSource := toSet(source)
+	inPrevious := toSet(previous)
+	inStored := toSet(stored)
+
+	for _, videoID := range source {
+		// Only add videos new to the source so songs removed by hand stay removed
+		if !inPrevious[videoID] && !inStored[videoID] {
+			toAdd = append(toAdd, videoID)
+			inStored[videoID] = true
+		}
+	}
+	for _, videoID := range previous {
+		if !inSource[videoID] && inStored[videoID] {
+			toRemove = append(toRemove, videoID)
+		}
+	}
+	return toAdd, toRemove
+}
+
+// seenSource returns the source videos seen before or stored now, leaving out failed adds so the next sync retries them
+func seenSource(source, previous, stored []string) []string {
+	inPrevious := toSet(previous)
+	inStored := toSet(stored)
+
+	var seen []string
+	for videoID := range toSet(source) {
+		if inPrevious[videoID] || inStored[videoID] {
+			seen = append(seen, videoID)
+		}
+	}
+	return seen
+}
+
+// toSet converts a list of IDs into a set
+func toSet(ids []string) map[string]bool {
+	set := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
+
+// syncSource brings a users personal playlist up to date with its linked source
+func (pm *PlaylistManager) syncSource(ctx context.Context, userID int64, progress func(done int, title string)) (*SyncResult, error) {
+	var source PlaylistSource
+	if err := pm.db.Where("user_id = ?", userID).First(&source).Error; err != nil {
+		return nil, err
+	}
+
+	ytManager := yt.NewYouTubeManager(redis_client.RDB)
+	videoIDs, err := ytManager.GetPlaylistVideoIDs(ctx, source.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &target{userID: userID, scope: personalScope(userID), role: RoleOwner}
+
+	var previous, stored []string
+	if err := pm.db.Model(&PlaylistSourceSong{}).Where("user_id = ?", userID).Pluck("song_id", &previous).Error; err != nil {
+		return nil, err
+	}
+	if err := t.scope.where(pm.db).Pluck("song_id", &stored).Error; err != nil {
+		return nil, err
+	}
+
+	toAdd, toRemove := diffSource(videoIDs, previous, stored)
+
+	result := &SyncResult{}
+	result.Added, result.Skipped, result.Failed = pm.addVideos(ctx, t, toAdd, progress)
+
+	for _, videoID := range toRemove {
+		if !source.Prune {
+			result.Missing++
+			continue
+		}
+		if err := pm.removeSongs(t, videoID); err == nil {
+			result.Removed++
+		}
+	}
+
+	var current []string
+	if err := t.scope.where(pm.db).Pluck("song_id", &current).Error; err != nil {
+		return nil, err
+	}
+
+	err = pm.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&PlaylistSourceSong{}).Error; err != nil {
+			return err
+		}
+		seenIDs := seenSource(videoIDs, previous, current)
+		seen := make([]PlaylistSourceSong, 0, len(seenIDs))
+		for _, videoID := range seenIDs {
+			seen = append(seen, PlaylistSourceSong{UserID: userID, SongID: videoID})
+		}
+		if len(seen) > 0 {
+			if err := tx.CreateInBatches(seen, 500).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&source).Update("last_synced_at", time.Now()).Error
+	})
+
+	return result, err
+}
+
+// String summarises the changes made by a sync
+func (r *SyncResult) String() string {
+	if r.Added == 0 && r.Removed == 0 && r.Failed == 0 && r.Missing == 0 {
+		return "🔄 Your playlist is already up to date!"
+	}
+
+	content := fmt.Sprintf("🔄 Synced your playlist! Added `%d`, removed `%d`", r.Added, r.Removed)
+	if r.Skipped > 0 {
+		content += fmt.Sprintf("\n• `%d` new video(s) were already in your playlist", r.Skipped)
+	}
+	if r.Failed > 0 {
+		content += fmt.Sprintf("\n• `%d` new video(s) couldn't be fetched", r.Failed)
+	}
+	if r.Missing > 0 {
+		content += fmt.Sprintf("\n• `%d` song(s) left the source but were kept, link with `prune` to remove them", r.Missing)
+	}
+	return content
+}
+
+// LinkSource links the users personal playlist to a YouTube playlist and syncs it
+func (pm *PlaylistManager) LinkSource(ctx context.Context, i *discordgo.InteractionCreate, playlistURL string, prune bool) {
+	userID, _ := strconv.ParseInt(i.Member.User.ID, 10, 64)
+
+	err := pm.db.Transaction(func(tx *gorm.DB) error {
+		var existing PlaylistSource
+		if err := tx.Where("user_id = ?", userID).First(&existing).Error; err == nil && existing.URL != playlistURL {
+			// Videos seen in the old source don't belong to the new one
+			if err := tx.Where("user_id = ?", userID).Delete(&PlaylistSourceSong{}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Save(&PlaylistSource{UserID: userID, URL: playlistURL, Prune: prune}).Error
+	})
+	if err != nil {
+		pm.session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+			Content: "Oops! Something went wrong while linking your playlist. 😅",
+		})
+		return
+	}
+
+	pm.SyncPlaylist(ctx, i)
+}
+
+// UnlinkSource removes the link between the users personal playlist and its source
+func (pm *PlaylistManager) UnlinkSource(i *discordgo.InteractionCreate) {
+	userID, _ := strconv.ParseInt(i.Member.User.ID, 10, 64)
+
+	content := "🔗 Your playlist is no longer linked, its songs have been kept"
+	if err := pm.db.Where("user_id = ?", userID).Delete(&PlaylistSource{}).Error; err != nil {
+		content = "Oops! Something went wrong while unlinking your playlist. 😅"
+	}
+
+	pm.session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+		Content: content,
+	})
+}
+
+// SyncPlaylist syncs the users personal playlist with its linked source and reports the changes
+func (pm *PlaylistManager) SyncPlaylist(ctx context.Context, i *discordgo.InteractionCreate) {
+	userID, _ := strconv.ParseInt(i.Member.User.ID, 10, 64)
+
+	msg, err := pm.session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+		Content: "🔄 Syncing your playlist...",
+	})
+	if err != nil {
+		return
+	}
+
+	result, err := pm.syncSource(ctx, userID, func(done int, title string) {
+		content := fmt.Sprintf("🔄 Adding %d: `%s`", done, title)
+		pm.session.FollowupMessageEdit(i.Interaction, msg.ID, &discordgo.WebhookEdit{
+			Content: &content,
+		})
+	})
+
+	content := ""
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		content = "Your playlist isn't linked to a YouTube playlist, use `/playlist link` first 🔗"
+	case err != nil:
+		content = "Oops! Something went wrong while syncing your playlist. 😅"
+	default:
+		content = result.String()
+	}
+
+	pm.session.FollowupMessageEdit(i.Interaction, msg.ID, &discordgo.WebhookEdit{
+		Content: &content,
+	})
+}
+
+// StartSourceSync starts the background sync of every linked playlist
+func (pm *PlaylistManager) StartSourceSync(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			pm.routineSourceSync()
+		}
+	}()
+}
+
+// routineSourceSync syncs every linked playlist with its source
+func (pm *PlaylistManager) routineSourceSync() {
+	var sources []PlaylistSource
+	if err := pm.db.Find(&sources).Error; err != nil {
+		log.WithError(err).Error("Failed to load linked playlists")
+		return
+	}
+
+	for _, source := range sources {
+		result, err := pm.syncSource(context.Background(), source.UserID, func(int, string) {})
+		if err != nil {
+			log.WithError(err).WithFields(log.Fields{"user_id": source.UserID}).Error("Failed to sync linked playlist")
+			continue
+		}
+		log.WithFields(log.Fields{
+			"user_id": source.UserID,
+			"added":   result.Added,
+			"removed": result.Removed,
+		}).Info("Synced linked playlist")
+	}
+}
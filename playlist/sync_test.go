@@ -0,0 +1,52 @@
+package playlist
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffSource_FirstSync(t *testing.T) {
+	toAdd, toRemove := diffSource([]string{"a", "b", "c"}, nil, []string{"b"})
+
+	assert.Equal(t, []string{"a", "c"}, toAdd)
+	assert.Empty(t, toRemove)
+}
+
+func TestDiffSource_NewAndRemovedVideos(t *testing.T) {
+	source := []string{"a", "c", "d"}
+	previous := []string{"a", "b", "c"}
+	stored := []string{"a", "b", "c", "x"}
+
+	toAdd, toRemove := diffSource(source, previous, stored)
+
+	assert.Equal(t, []string{"d"}, toAdd)
+	assert.Equal(t, []string{"b"}, toRemove)
+}
+
+func TestDiffSource_KeepsManualRemovals(t *testing.T) {
+	// "a" was removed from the playlist by hand and is still within the source
+	toAdd, toRemove := diffSource([]string{"a", "b"}, []string{"a", "b"}, []string{"b"})
+
+	assert.Empty(t, toAdd)
+	assert.Empty(t, toRemove)
+}
+
+func TestDiffSource_DuplicateSourceEntries(t *testing.T) {
+	toAdd, _ := diffSource([]string{"a", "a"}, nil, nil)
+
+	assert.Equal(t, []string{"a"}, toAdd)
+}
+
+func TestSeenSource_RetriesFailedVideos(t *testing.T) {
+	source := []string{"a", "b", "c", "d"}
+	previous := []string{"a", "b"}
+	// "a" was removed by hand, "c" was added by this sync and "d" failed to be fetched
+	stored := []string{"b", "c"}
+
+	seen := seenSource(source, previous, stored)
+	assert.ElementsMatch(t, []string{"a", "b", "c"}, seen)
+
+	toAdd, _ := diffSource(source, seen, stored)
+	assert.Equal(t, []string{"d"}, toAdd, "the failed video is added on the next sync")
+}
@@ -0,0 +1,237 @@
+package playlist
+
+import (
+	"Twilight/redis_client"
+	"Twilight/yt"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/kkdai/youtube/v2"
+)
+
+const maxImportSize = 1 << 20 // 1 MiB
+
+// ExportPlaylist uploads a playlist as a file attachment in the given format
+func (pm *PlaylistManager) ExportPlaylist(i *discordgo.InteractionCreate, format Format, name string) {
+	t := pm.target(i, name, RoleViewer)
+	if t == nil {
+		return
+	}
+
+	entries, err := loadEntries(pm.db, t)
+	if err != nil || len(entries) == 0 {
+		content := "Looks like " + t.label() + " is empty. Add some songs to get started! 🎵"
+		if err != nil {
+			content = "Oops! Something went wrong while fetching " + t.label() + ". 😅"
+		}
+		pm.session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+			Content: content,
+		})
+		return
+	}
+
+	fileEntries := make([]FileEntry, 0, len(entries))
+	for _, e := range entries {
+		fileEntries = append(fileEntries, FileEntry{
+			Title:    e.Song.Title,
+			Author:   e.Song.Author,
+			Duration: e.Song.Duration,
+			URL:      "https://www.youtube.com/watch?v=" + e.Song.ID,
+		})
+	}
+
+	data, err := EncodeFile(format, fileEntries)
+	if err != nil {
+		pm.session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+			Content: "Oops! Something went wrong while exporting " + t.label() + ". 😅",
+		})
+		return
+	}
+
+	filename := "playlist"
+	if t.guild != nil {
+		filename = strings.Map(func(r rune) rune {
+			if r == '/' || r == '\\' || r == ' ' {
+				return '_'
+			}
+			return r
+		}, t.guild.Name)
+	}
+
+	pm.session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+		Content: fmt.Sprintf("📤 Exported `%d` song(s) from %s", len(fileEntries), t.label()),
+		Files: []*discordgo.File{
+			{
+				Name:        filename + "." + string(format),
+				ContentType: format.ContentType(),
+				Reader:      bytes.NewReader(data),
+			},
+		},
+	})
+}
+
+// ImportPlaylist reads an attached M3U, JSON or CSV file and adds each entry to a playlist
+func (pm *PlaylistManager) ImportPlaylist(ctx context.Context, i *discordgo.InteractionCreate, attachment *discordgo.MessageAttachment, name string) {
+	t := pm.target(i, name, RoleEditor)
+	if t == nil {
+		return
+	}
+
+	if attachment == nil {
+		pm.session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+			Content: "Attach an M3U, JSON or CSV file to import 📎",
+		})
+		return
+	}
+
+	format, err := FormatFromFilename(attachment.Filename)
+	if err != nil {
+		pm.session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+			Content: "Unsupported file type, attach an `.m3u`, `.json` or `.csv` file 📎",
+		})
+		return
+	}
+
+	entries, err := downloadImportFile(attachment.URL, format)
+	if err != nil || len(entries) == 0 {
+		content := "Couldn't find any songs in `" + attachment.Filename + "` 🤔"
+		if err != nil {
+			content = "Oops! Couldn't read `" + attachment.Filename + "`. 😅"
+		}
+		pm.session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+			Content: content,
+		})
+		return
+	}
+
+	total := len(entries)
+	msg, err := pm.session.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+		Content: fmt.Sprintf("Resolving %d entries...", total),
+	})
+	if err != nil {
+		return
+	}
+
+	// Resolve every entry to a videoID, dropping duplicates within the file
+	ytManager := yt.NewYouTubeManager(redis_client.RDB)
+	seen := map[string]bool{}
+	var videoIDs []string
+	var unresolved []string
+	duplicates := 0
+	reporter := newThrottledProgress(progressInterval, func(done int, label string) {
+		content := fmt.Sprintf("Resolving %d/%d: `%s`", done, total, label)
+		pm.session.FollowupMessageEdit(i.Interaction, msg.ID, &discordgo.WebhookEdit{
+			Content: &content,
+		})
+	})
+	for idx, entry := range entries {
+		reporter.Update(idx+1, entryLabel(entry))
+
+		videoID, err := resolveEntry(ctx, ytManager, entry)
+		if err != nil {
+			unresolved = append(unresolved, entryLabel(entry))
+			continue
+		}
+		if seen[videoID] {
+			duplicates++
+			continue
+		}
+		seen[videoID] = true
+		videoIDs = append(videoIDs, videoID)
+	}
+	reporter.Stop()
+
+	added, skipped, failed := pm.addVideos(ctx, t, videoIDs, func(done int, title string) {
+		content := fmt.Sprintf("Importing %d/%d: `%s`", done, len(videoIDs), title)
+		pm.session.FollowupMessageEdit(i.Interaction, msg.ID, &discordgo.WebhookEdit{
+			Content: &content,
+		})
+	})
+
+	finalContent := fmt.Sprintf("📥 Imported `%d/%d` songs into %s!", added, total, t.label())
+	if duplicates > 0 {
+		finalContent += fmt.Sprintf("\n• `%d` duplicate(s) within the file", duplicates)
+	}
+	if skipped > 0 {
+		finalContent += fmt.Sprintf("\n• `%d` already in %s", skipped, t.label())
+	}
+	if failed > 0 {
+		finalContent += fmt.Sprintf("\n• `%d` couldn't be fetched from YouTube", failed)
+	}
+	if len(unresolved) > 0 {
+		finalContent += fmt.Sprintf("\n• `%d` couldn't be matched: %s", len(unresolved), summariseLabels(unresolved, 5))
+	}
+
+	pm.session.FollowupMessageEdit(i.Interaction, msg.ID, &discordgo.WebhookEdit{
+		Content: &finalContent,
+	})
+}
+
+// downloadImportFile fetches an attachment and decodes the playlist entries within it
+func downloadImportFile(url string, format Format) ([]FileEntry, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImportSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxImportSize {
+		return nil, errors.New("playlist file too large")
+	}
+
+	return DecodeFile(format, data)
+}
+
+// resolveEntry finds the videoID for a playlist file entry, searching YouTube when it has no YouTube URL
+func resolveEntry(ctx context.Context, ytManager *yt.YouTubeManager, entry FileEntry) (string, error) {
+	if entry.URL != "" {
+		if videoID, err := youtube.ExtractVideoID(entry.URL); err == nil {
+			return videoID, nil
+		}
+	}
+
+	query := strings.TrimSpace(entry.Author + " " + entry.Title)
+	if query == "" {
+		return "", errors.New("entry has no url or title")
+	}
+	return ytManager.SearchVideoID(ctx, query)
+}
+
+// entryLabel returns a short description of a playlist file entry for progress messages
+func entryLabel(entry FileEntry) string {
+	switch {
+	case entry.Author != "" && entry.Title != "":
+		return entry.Author + " - " + entry.Title
+	case entry.Title != "":
+		return entry.Title
+	default:
+		return entry.URL
+	}
+}
+
+// summariseLabels joins up to limit labels, noting how many were left out
+func summariseLabels(labels []string, limit int) string {
+	shown := labels
+	if len(shown) > limit {
+		shown = shown[:limit]
+	}
+	text := "`" + strings.Join(shown, "`, `") + "`"
+	if len(labels) > limit {
+		text += fmt.Sprintf(" and %d more", len(labels)-limit)
+	}
+	return text
+}
@@ -0,0 +1,145 @@
+package queue
+
+import (
+	"Twilight/source"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNotSeekable is returned when seeking a session which isn't playing a file, such as a live stream
+var ErrNotSeekable = errors.New("nothing seekable is playing")
+
+// section is a part of an audio file
+type section struct {
+	Start time.Duration
+	End   time.Duration // Zero for the end of the file
+}
+
+// NewChapterSongs returns a song for each chapter of a track, all sharing the audio file of the whole track
+func NewChapterSongs(t *source.Track, username, channelID string) []*QueueSong {
+	songs := make([]*QueueSong, 0, len(t.Chapters))
+	for i, chapter := range t.Chapters {
+		song := NewTrackSong(t, username, channelID)
+		song.Title = chapter.Title
+		if t.Title != "" {
+			song.Title = t.Title + " · " + chapter.Title
+		}
+		song.Start = chapter.Start
+		if i < len(t.Chapters)-1 {
+			song.End = chapter.End // The last chapter plays until the file ends however long the video turns out to be
+		}
+		songs = append(songs, song)
+	}
+	return songs
+}
+
+// Position returns how far into the audio file playback is
+func (s *AudioSession) Position() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.offset + time.Duration(s.played.Load())*frameDuration
+}
+
+// Seek restarts playback of the current file at position
+func (s *AudioSession) Seek(position time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.seekLocked(position)
+}
+
+// seekLocked records where playback restarts and kills ffmpeg so playAudioFile restarts it there, s.mu must be held
+func (s *AudioSession) seekLocked(position time.Duration) error {
+	if !s.seekable || s.stopped {
+		return ErrNotSeekable
+	}
+	position = max(position, 0)
+	s.seekTo = &position
+	if s.Cmd != nil && s.Cmd.Process != nil {
+		s.Cmd.Process.Kill() // streamPCM waits on the process once its output ends
+	}
+	return nil
+}
+
+// LoopSection plays the part of the file from start to end over and over, jumping to start unless playback is already
+// within it
+func (s *AudioSession) LoopSection(start, end time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.seekable || s.stopped {
+		return ErrNotSeekable
+	}
+
+	s.loop = &section{Start: start, End: end}
+	position := s.offset + time.Duration(s.played.Load())*frameDuration
+	if position < start || (end > 0 && position >= end) {
+		position = start
+	}
+	return s.seekLocked(position) // Restarts ffmpeg so it stops where the section ends
+}
+
+// StopLoop lets playback carry on past the end of the looped section
+func (s *AudioSession) StopLoop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loop = nil
+}
+
+// LoopedSection returns the part of the file being looped, false when not looping
+func (s *AudioSession) LoopedSection() (start, end time.Duration, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.loop == nil {
+		return 0, 0, false
+	}
+	return s.loop.Start, s.loop.End, true
+}
+
+// segment returns the section ffmpeg plays from position, stopping early at the end of a looped section it is within
+func (s *AudioSession) segment(position, songEnd time.Duration) section {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	end := songEnd
+	if loop := s.loop; loop != nil && position >= loop.Start && loop.End > 0 && position < loop.End && (end == 0 || loop.End < end) {
+		end = loop.End
+	}
+	return section{Start: position, End: end}
+}
+
+// restart resets the position as ffmpeg is started at offset
+func (s *AudioSession) restart(offset time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.offset = offset
+	s.played.Store(0)
+}
+
+// takeSeek returns and clears the position playback was seeked to, false when it wasn't seeked
+func (s *AudioSession) takeSeek() (time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.seekTo == nil {
+		return 0, false
+	}
+	position := *s.seekTo
+	s.seekTo = nil
+	return position, true
+}
+
+// sectionArgs returns the ffmpeg input arguments reading a file from start until end, until the file ends when end is
+// zero
+func sectionArgs(filename string, start, end time.Duration) []string {
+	var args []string
+	if start > 0 {
+		args = append(args, "-ss", ffmpegSeconds(start))
+	}
+	if end > start {
+		args = append(args, "-t", ffmpegSeconds(end-start))
+	}
+	return append(args, "-i", filename)
+}
+
+// ffmpegSeconds formats a duration the way ffmpeg reads seconds
+func ffmpegSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
@@ -0,0 +1,74 @@
+package queue
+
+import (
+	"Twilight/source"
+	"Twilight/yt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewChapterSongs(t *testing.T) {
+	track := &source.Track{
+		Source: source.YouTubeName,
+		ID:     "abc",
+		Title:  "Mix",
+		Chapters: []yt.Chapter{
+			{Title: "First", Start: 0, End: time.Minute},
+			{Title: "Second", Start: time.Minute, End: 2 * time.Minute},
+		},
+	}
+
+	songs := NewChapterSongs(track, "user", "channel")
+	assert.Len(t, songs, 2)
+	assert.Equal(t, "Mix · First", songs[0].Title)
+	assert.Equal(t, time.Duration(0), songs[0].Start)
+	assert.Equal(t, time.Minute, songs[0].End)
+	assert.Equal(t, time.Minute, songs[1].Start)
+	assert.Equal(t, time.Duration(0), songs[1].End, "the last chapter plays until the file ends")
+	assert.Equal(t, songs[0].Filename, songs[1].Filename, "chapters share the audio of the whole track")
+}
+
+func TestSectionArgs(t *testing.T) {
+	assert.Equal(t, []string{"-i", "song.opus"}, sectionArgs("song.opus", 0, 0))
+	assert.Equal(t, []string{"-ss", "61.500", "-i", "song.opus"}, sectionArgs("song.opus", 61500*time.Millisecond, 0))
+	assert.Equal(t, []string{"-ss", "60.000", "-t", "30.000", "-i", "song.opus"}, sectionArgs("song.opus", time.Minute, 90*time.Second))
+}
+
+func TestAudioSession_Seek(t *testing.T) {
+	session := &AudioSession{}
+	assert.ErrorIs(t, session.Seek(time.Minute), ErrNotSeekable, "nothing is playing")
+
+	session.seekable = true
+	session.restart(time.Minute)
+	session.played.Add(50)
+	assert.Equal(t, time.Minute+time.Second, session.Position())
+
+	assert.NoError(t, session.Seek(2*time.Minute))
+	position, ok := session.takeSeek()
+	assert.True(t, ok)
+	assert.Equal(t, 2*time.Minute, position)
+	_, ok = session.takeSeek()
+	assert.False(t, ok, "a seek is taken once")
+}
+
+func TestAudioSession_LoopSection(t *testing.T) {
+	session := &AudioSession{seekable: true}
+	session.restart(90 * time.Second)
+
+	assert.NoError(t, session.LoopSection(time.Minute, 2*time.Minute))
+	position, _ := session.takeSeek()
+	assert.Equal(t, 90*time.Second, position, "playback within the section carries on where it is")
+	assert.Equal(t, section{Start: 90 * time.Second, End: 2 * time.Minute}, session.segment(position, 0))
+	assert.Equal(t, section{Start: 3 * time.Minute, End: 0}, session.segment(3*time.Minute, 0), "playback outside the section isn't cut short")
+
+	assert.NoError(t, session.LoopSection(5*time.Minute, 6*time.Minute))
+	position, _ = session.takeSeek()
+	assert.Equal(t, 5*time.Minute, position, "playback outside the section jumps to it")
+
+	session.StopLoop()
+	_, _, looping := session.LoopedSection()
+	assert.False(t, looping)
+	assert.Equal(t, section{Start: 5 * time.Minute, End: 0}, session.segment(5*time.Minute, 0))
+}
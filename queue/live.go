@@ -0,0 +1,220 @@
+package queue
+
+import (
+	"Twilight/source"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Strum355/log"
+	"github.com/bwmarrin/discordgo"
+	"github.com/spf13/viper"
+)
+
+const (
+	liveStableAfter = time.Minute      // Streams which played this long get their reconnect attempts back
+	maxLiveBackoff  = 30 * time.Second // Longest wait between reconnect attempts
+)
+
+var errNotStreamable = errors.New("source can't stream live tracks")
+
+// NowPlaying returns the title a live stream last announced, empty for songs and streams without metadata
+func (q *QueueSong) NowPlaying() string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.nowPlaying
+}
+
+// setNowPlaying stores the title announced by a live stream
+func (q *QueueSong) setNowPlaying(title string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.nowPlaying = title
+}
+
+// playLiveStream streams a live song to Discord without caching it, reconnecting when the stream drops until skipped
+func playLiveStream(vc *discordgo.VoiceConnection, song *QueueSong, session *AudioSession) error {
+	if err := waitReady(vc); err != nil {
+		return err
+	}
+
+	vc.Speaking(true)
+	defer vc.Speaking(false)
+
+	stop := session.begin(vc)
+	defer session.Stop()
+
+	attempts := 0
+	for {
+		started := time.Now()
+		err := streamLive(vc, song, session, stop)
+
+		select {
+		case <-stop:
+			return nil
+		default:
+		}
+
+		if time.Since(started) > liveStableAfter {
+			attempts = 0
+		}
+		if attempts >= viper.GetInt("queue.live.retries") {
+			return err
+		}
+		attempts++
+
+		backoff := min(time.Second<<attempts, maxLiveBackoff)
+		log.WithError(err).WithFields(log.Fields{"url": song.URL, "attempt": attempts}).Warn("Live stream dropped, reconnecting")
+		select {
+		case <-stop:
+			return nil
+		case <-time.After(backoff):
+		}
+	}
+}
+
+// streamLive plays a live song from a freshly resolved stream URL until the stream ends or drops
+func streamLive(vc *discordgo.VoiceConnection, song *QueueSong, session *AudioSession, stop chan struct{}) error {
+	p, err := source.Get(song.Source)
+	if err != nil {
+		return err
+	}
+	streamer, ok := p.(source.Streamer)
+	if !ok {
+		return errNotStreamable
+	}
+
+	// Resolving and connecting to the stream give up as soon as the song is skipped
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	streamURL, err := streamer.StreamURL(ctx, song.Track())
+	if err != nil {
+		return err
+	}
+
+	// Icecast and Shoutcast are read here for their titles, HLS is left to ffmpeg like other live streams
+	if song.Source == source.RadioName {
+		resp, err := source.OpenRadio(ctx, streamURL)
+		if !errors.Is(err, source.ErrHLS) {
+			if err != nil {
+				return err
+			}
+			return streamRadio(vc, song, session, stop, resp)
+		}
+	}
+
+	// ffmpeg reconnects on its own to brief drops before giving up and letting the caller start over
+	cmd := exec.Command("ffmpeg", pcmArgs(
+		"-reconnect", "1",
+		"-reconnect_streamed", "1",
+		"-reconnect_delay_max", "5",
+		"-i", streamURL,
+	)...)
+	return streamPCM(vc, session, stop, cmd)
+}
+
+// streamRadio plays an Icecast or Shoutcast stream, reading it here so the ICY titles can be picked out of the audio
+func streamRadio(vc *discordgo.VoiceConnection, song *QueueSong, session *AudioSession, stop chan struct{}, resp *http.Response) error {
+	defer resp.Body.Close() // Also ends the copy into ffmpeg once playback stops
+
+	body := io.Reader(resp.Body)
+	if metaint, err := strconv.Atoi(resp.Header.Get("Icy-Metaint")); err == nil && metaint > 0 {
+		body = newICYReader(resp.Body, metaint, song.setNowPlaying)
+	}
+
+	cmd := exec.Command("ffmpeg", pcmArgs("-i", "pipe:0")...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	go func() {
+		io.Copy(stdin, body)
+		stdin.Close()
+	}()
+
+	return streamPCM(vc, session, stop, cmd)
+}
+
+// icyReader strips the metadata blocks Shoutcast and Icecast interleave with the audio, reporting each new title
+type icyReader struct {
+	r         io.Reader
+	metaint   int    // Bytes of audio between metadata blocks
+	remaining int    // Bytes of audio left before the next metadata block
+	title     string // Last title reported
+	onTitle   func(string)
+}
+
+// newICYReader wraps a stream which sends a metadata block after every metaint bytes of audio
+func newICYReader(r io.Reader, metaint int, onTitle func(string)) *icyReader {
+	return &icyReader{r: r, metaint: metaint, remaining: metaint, onTitle: onTitle}
+}
+
+// Read reads audio from the stream, consuming any metadata blocks on the way
+func (ir *icyReader) Read(p []byte) (int, error) {
+	if ir.remaining == 0 {
+		if err := ir.readMetadata(); err != nil {
+			return 0, err
+		}
+		ir.remaining = ir.metaint
+	}
+
+	if len(p) > ir.remaining {
+		p = p[:ir.remaining]
+	}
+	n, err := ir.r.Read(p)
+	ir.remaining -= n
+	return n, err
+}
+
+// readMetadata reads a metadata block, which is a length byte in units of 16 followed by the metadata
+func (ir *icyReader) readMetadata() error {
+	length := make([]byte, 1)
+	if _, err := io.ReadFull(ir.r, length); err != nil {
+		return err
+	}
+	if length[0] == 0 {
+		return nil // Titles are only sent when they change
+	}
+
+	meta := make([]byte, int(length[0])*16)
+	if _, err := io.ReadFull(ir.r, meta); err != nil {
+		return err
+	}
+	if title, ok := parseStreamTitle(string(meta)); ok && title != ir.title {
+		ir.title = title
+		ir.onTitle(title)
+	}
+	return nil
+}
+
+// parseStreamTitle extracts the StreamTitle from ICY metadata such as StreamTitle='Artist - Song';StreamUrl=”;
+func parseStreamTitle(meta string) (string, bool) {
+	meta = strings.TrimRight(meta, "\x00")
+	_, rest, ok := strings.Cut(meta, "StreamTitle='")
+	if !ok {
+		return "", false
+	}
+
+	// Titles may contain quotes themselves, so the value ends at the last quote before the next field or the end
+	end := strings.Index(rest, "';Stream")
+	if end < 0 {
+		end = strings.LastIndex(rest, "';")
+	}
+	if end < 0 {
+		end = len(strings.TrimSuffix(rest, "'"))
+	}
+	return strings.TrimSpace(rest[:end]), true
+}
@@ -0,0 +1,91 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// icyBlock builds an ICY metadata block padded to a multiple of 16 bytes
+func icyBlock(meta string) []byte {
+	length := (len(meta) + 15) / 16
+	block := make([]byte, 1+length*16)
+	block[0] = byte(length)
+	copy(block[1:], meta)
+	return block
+}
+
+func TestICYReader_StripsMetadata(t *testing.T) {
+	stream := &bytes.Buffer{}
+	stream.WriteString("aaaa")
+	stream.Write(icyBlock("StreamTitle='Artist - First';StreamUrl='';"))
+	stream.WriteString("bbbb")
+	stream.Write([]byte{0}) // No change in title
+	stream.WriteString("cccc")
+	stream.Write(icyBlock("StreamTitle='Artist - Second';"))
+	stream.WriteString("dd")
+
+	var titles []string
+	reader := newICYReader(stream, 4, func(title string) {
+		titles = append(titles, title)
+	})
+
+	audio, err := io.ReadAll(reader)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "aaaabbbbccccdd", string(audio))
+	assert.Equal(t, []string{"Artist - First", "Artist - Second"}, titles)
+}
+
+func TestICYReader_RepeatedTitleReportedOnce(t *testing.T) {
+	stream := &bytes.Buffer{}
+	stream.WriteString("aa")
+	stream.Write(icyBlock("StreamTitle='Same';"))
+	stream.WriteString("bb")
+	stream.Write(icyBlock("StreamTitle='Same';"))
+
+	calls := 0
+	reader := newICYReader(stream, 2, func(string) { calls++ })
+	_, err := io.ReadAll(reader)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestParseStreamTitle(t *testing.T) {
+	cases := map[string]string{
+		"StreamTitle='Artist - Song';StreamUrl='http://x';": "Artist - Song",
+		"StreamTitle='Don't Stop';\x00\x00\x00":             "Don't Stop",
+		"StreamTitle='';":                                   "",
+		"StreamTitle='No terminator'":                       "No terminator",
+	}
+	for meta, expected := range cases {
+		title, ok := parseStreamTitle(meta)
+		assert.True(t, ok, meta)
+		assert.Equal(t, expected, title, meta)
+	}
+
+	_, ok := parseStreamTitle("StreamUrl='http://x';")
+	assert.False(t, ok)
+}
+
+func TestResolve_LiveSongSkipsDownload(t *testing.T) {
+	mu, calls := stubDownload(t, nil)
+	song := &QueueSong{VideoID: "radio", Live: true}
+
+	assert.NoError(t, song.Resolve(context.Background()))
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Zero(t, calls["radio"])
+}
+
+func TestNowPlaying(t *testing.T) {
+	song := &QueueSong{Live: true}
+	assert.Empty(t, song.NowPlaying())
+
+	song.setNowPlaying("Artist - Song")
+	assert.Equal(t, "Artist - Song", song.NowPlaying())
+}
@@ -0,0 +1,132 @@
+package queue
+
+import (
+	"Twilight/segments"
+	"Twilight/source"
+	"Twilight/utils"
+	"Twilight/yt"
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/Strum355/log"
+	"github.com/bwmarrin/discordgo"
+	"github.com/spf13/viper"
+)
+
+// download fetches the audio of a song into the cache using its source provider, swapped out within tests
+var download = func(ctx context.Context, song *QueueSong) error {
+	p, err := source.Get(song.Source)
+	if err != nil {
+		return err
+	}
+	return p.Download(ctx, song.Track())
+}
+
+// Resolve makes sure the audio of the song is downloaded, remembering unavailable videos so they are only tried once
+func (q *QueueSong) Resolve(ctx context.Context) error {
+	if q.Live {
+		return nil // Live songs are streamed once they play
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.err != nil {
+		return q.err
+	}
+	if q.resolved {
+		// Looped songs may have been removed from the cache since they last played
+		if _, err := os.Stat(q.Filename); err == nil {
+			return nil
+		}
+	}
+
+	err := download(ctx, q)
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if yt.Unavailable(err) {
+		q.err = err // Rate limits and other temporary failures are tried again once the song comes round
+	}
+	q.resolved = err == nil
+	if q.resolved && (q.Source == "" || q.Source == source.YouTubeName) {
+		q.skips = segments.Lookup(ctx, q.VideoID)
+	}
+	return err
+}
+
+// Skips returns the parts of the song skipped while it plays, in order
+func (q *QueueSong) Skips() []segments.Segment {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.skips
+}
+
+// label returns the title of the song, falling back to its video ID
+func (q *QueueSong) label() string {
+	if q.Title != "" {
+		return q.Title
+	}
+	return q.VideoID
+}
+
+// prefetch starts downloading the next few songs in the queue so they are ready once their turn comes
+func prefetch(qd *QueueData) {
+	qd.mu.Lock()
+	count := max(min(viper.GetInt("queue.prefetch"), len(qd.Songs)), 0)
+	upcoming := append([]*QueueSong(nil), qd.Songs[:count]...)
+	ctx := qd.downloadContext()
+	qd.mu.Unlock()
+
+	for _, song := range upcoming {
+		go song.Resolve(ctx)
+	}
+}
+
+// Context returns a context of the guild's queue which is cancelled once the queue is cleared, replaced or stopped, for
+// work adding to the queue in the background
+func Context(guildID string) context.Context {
+	qd := guildManager.GetOrCreateQueue(guildID)
+	qd.mu.Lock()
+	defer qd.mu.Unlock()
+	return qd.downloadContext()
+}
+
+// downloadContext returns the context downloads for the guild run under, callers hold qd.mu
+func (qd *QueueData) downloadContext() context.Context {
+	if qd.ctx == nil {
+		qd.ctx, qd.cancel = context.WithCancel(context.Background())
+	}
+	return qd.ctx
+}
+
+// cancelDownloads cancels the downloads of every song queued so far, callers hold qd.mu
+func (qd *QueueData) cancelDownloads() {
+	if qd.cancel != nil {
+		qd.cancel()
+	}
+	qd.ctx, qd.cancel = nil, nil
+}
+
+// notifySegmentSkipped tells the text channel a song was queued from that part of it was skipped
+func notifySegmentSkipped(s *discordgo.Session, song *QueueSong, skip segments.Segment) {
+	log.WithFields(log.Fields{"video_id": song.VideoID, "category": skip.Category}).Info("Skipped segment")
+	if s == nil || song.ChannelID == "" {
+		return
+	}
+	s.ChannelMessageSend(song.ChannelID, fmt.Sprintf("⏩ Skipped the %s of `%s` (`%s`)", skip.Describe(), song.label(), utils.FormatYtDuration(skip.End-skip.Start)))
+}
+
+// notifySkipped tells the text channel a song was queued from that it was skipped
+func notifySkipped(s *discordgo.Session, song *QueueSong, err error) {
+	log.WithError(err).WithFields(log.Fields{"video_id": song.VideoID}).Error("Failed to download queued song")
+	if s == nil || song.ChannelID == "" {
+		return
+	}
+	reason := yt.Describe(err)
+	if reason == "" {
+		reason = "it couldn't be downloaded"
+	}
+	s.ChannelMessageSend(song.ChannelID, fmt.Sprintf("⚠️ Skipped `%s`, %s", song.label(), reason))
+}
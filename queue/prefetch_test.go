@@ -0,0 +1,167 @@
+package queue
+
+import (
+	"Twilight/segments"
+	"Twilight/segments/segmentstest"
+	"Twilight/source"
+	"Twilight/yt"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubDownload replaces the downloader for a test, recording each video downloaded failing those listed in fail
+func stubDownload(t *testing.T, fail map[string]error) (*sync.Mutex, map[string]int) {
+	var mu sync.Mutex
+	calls := map[string]int{}
+
+	original := download
+	download = func(ctx context.Context, song *QueueSong) error {
+		mu.Lock()
+		defer mu.Unlock()
+		calls[song.VideoID]++
+		if err := fail[song.VideoID]; err != nil {
+			return err
+		}
+		return nil
+	}
+	t.Cleanup(func() { download = original })
+
+	return &mu, calls
+}
+
+func TestNewQueueSong(t *testing.T) {
+	song := NewQueueSong("abc", "Title", "user", "channel")
+
+	assert.Equal(t, "abc", song.VideoID)
+	assert.Equal(t, "cache/abc.opus", song.Filename)
+	assert.Equal(t, "Title", song.label())
+	assert.Equal(t, "xyz", NewQueueSong("xyz", "", "user", "").label())
+}
+
+func TestQueueSong_ResolveRemembersFailure(t *testing.T) {
+	_, calls := stubDownload(t, map[string]error{"bad": fmt.Errorf("download failed: %w", yt.ErrRemoved)})
+
+	song := NewQueueSong("bad", "", "user", "")
+
+	assert.Error(t, song.Resolve(context.Background()))
+	assert.Error(t, song.Resolve(context.Background()))
+	assert.Equal(t, 1, calls["bad"])
+}
+
+func TestQueueSong_ResolveRetriesTemporaryFailure(t *testing.T) {
+	_, calls := stubDownload(t, map[string]error{"busy": fmt.Errorf("download failed: %w", yt.ErrRateLimited)})
+
+	song := NewQueueSong("busy", "", "user", "")
+
+	assert.Error(t, song.Resolve(context.Background()))
+	assert.Error(t, song.Resolve(context.Background()))
+	assert.Equal(t, 2, calls["busy"], "a looped song is tried again after a temporary failure")
+}
+
+func TestPrefetch_DownloadsNextSongs(t *testing.T) {
+	mu, calls := stubDownload(t, nil)
+	viper.Set("queue.prefetch", 2)
+	t.Cleanup(func() { viper.Set("queue.prefetch", 0) })
+
+	qd := &QueueData{Songs: []*QueueSong{
+		NewQueueSong("one", "", "user", ""),
+		NewQueueSong("two", "", "user", ""),
+		NewQueueSong("three", "", "user", ""),
+	}}
+
+	prefetch(qd)
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return calls["one"] == 1 && calls["two"] == 1
+	}, time.Second, 10*time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Zero(t, calls["three"])
+}
+
+func TestPrefetch_Disabled(t *testing.T) {
+	_, calls := stubDownload(t, nil)
+	viper.Set("queue.prefetch", 0)
+
+	qd := &QueueData{Songs: []*QueueSong{NewQueueSong("one", "", "user", "")}}
+
+	prefetch(qd)
+	time.Sleep(20 * time.Millisecond)
+
+	assert.Empty(t, calls)
+}
+
+func TestQueueSong_ResolveForgetsCancellation(t *testing.T) {
+	original := download
+	download = func(ctx context.Context, song *QueueSong) error {
+		<-ctx.Done()
+		return errors.New("yt-dlp was killed")
+	}
+	t.Cleanup(func() { download = original })
+
+	song := NewQueueSong("slow", "", "user", "")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.ErrorIs(t, song.Resolve(ctx), context.Canceled)
+	assert.NoError(t, song.err)
+}
+
+func TestClearGuildQueue_CancelsDownloads(t *testing.T) {
+	guildManager = &GuildManager{
+		songs:    make(map[string]*QueueData),
+		sessions: make(map[string]*SessionData),
+	}
+	viper.Set("queue.prefetch", 1)
+	t.Cleanup(func() { viper.Set("queue.prefetch", 0) })
+
+	cancelled := make(chan struct{})
+	original := download
+	download = func(ctx context.Context, song *QueueSong) error {
+		<-ctx.Done()
+		close(cancelled)
+		return ctx.Err()
+	}
+	t.Cleanup(func() { download = original })
+
+	EnqueueSongs("guild", NewQueueSong("slow", "", "user", ""))
+	ClearGuildQueue("guild")
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("download wasn't cancelled when the queue was cleared")
+	}
+}
+
+func TestQueueSong_ResolveLoadsSkips(t *testing.T) {
+	stubDownload(t, nil)
+	viper.Set("segments.enabled", true)
+	viper.Set("segments.categories", []string{"sponsor"})
+	t.Cleanup(func() {
+		viper.Set("segments.enabled", nil)
+		viper.Set("segments.categories", nil)
+	})
+	sponsor := segments.Segment{Category: "sponsor", Start: time.Minute, End: 2 * time.Minute}
+	provider := segmentstest.NewProvider().Add("abc", sponsor)
+	segmentstest.Use(t, provider)
+
+	song := NewQueueSong("abc", "", "user", "")
+	assert.NoError(t, song.Resolve(context.Background()))
+	assert.Equal(t, []segments.Segment{sponsor}, song.Skips())
+
+	local := NewTrackSong(&source.Track{Source: source.LocalName, ID: "abc", URL: "abc"}, "user", "")
+	assert.NoError(t, local.Resolve(context.Background()))
+	assert.Empty(t, local.Skips(), "only YouTube videos have segments")
+	assert.Equal(t, 1, provider.Calls("abc"))
+}
@@ -1,16 +1,17 @@
 package queue
 
 import (
-	"Twilight/redis_client"
-	"Twilight/utils"
-	"Twilight/yt"
+	"Twilight/audiocache"
+	"Twilight/segments"
+	"Twilight/source"
+	"context"
 	"encoding/binary"
 	"fmt"
 	"io"
 	"math/rand/v2"
-	"os"
 	"os/exec"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
@@ -28,6 +29,11 @@ type AudioSession struct {
 	stop        chan struct{}              // Channel to signal stopping the session
 	resume      chan struct{}              // Channel to signal resuming from pause
 	stopped     bool                       // True if session has been stopped already
+	seekable    bool                       // True while a file is playing, which unlike live streams can be seeked
+	seekTo      *time.Duration             // Position to restart playback at once ffmpeg is killed
+	offset      time.Duration              // Position within the file ffmpeg was started at
+	played      atomic.Int64               // Frames sent since ffmpeg was started
+	loop        *section                   // Part of the file played over and over, nil when not looping
 }
 
 // Pause sets the audio session to paused, stopping audio playback temporarily
@@ -84,16 +90,30 @@ func (s *AudioSession) Stop() {
 	s.Encoder = nil
 }
 
-// playAudioFile streams audio to Discord
-func playAudioFile(vc *discordgo.VoiceConnection, filename string, session *AudioSession) error {
-	const (
-		sampleRate       = 48000
-		channels         = 2
-		frameSize        = 960
-		maxOpusFrameSize = 4000
-		frameDuration    = 20 * time.Millisecond
-	)
+const (
+	sampleRate       = 48000
+	channels         = 2
+	frameSize        = 960
+	maxOpusFrameSize = 4000
+	frameDuration    = 20 * time.Millisecond
+)
+
+// begin marks the session as playing on a voice connection, returning the channel closed once it is stopped
+func (s *AudioSession) begin(vc *discordgo.VoiceConnection) chan struct{} {
+	stop := make(chan struct{})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.VC = vc
+	s.isPaused = false
+	s.stop = stop
+	s.stopped = false
+	s.seekable = false
+	return stop
+}
 
+// waitReady waits briefly for a voice connection to become ready
+func waitReady(vc *discordgo.VoiceConnection) error {
 	if !vc.Ready {
 		for range 20 {
 			time.Sleep(100 * time.Millisecond)
@@ -105,18 +125,83 @@ func playAudioFile(vc *discordgo.VoiceConnection, filename string, session *Audi
 			return fmt.Errorf("voice connection never became ready")
 		}
 	}
+	return nil
+}
+
+// playAudioFile streams the section of a song's audio file to Discord, restarting ffmpeg where it is seeked to,
+// wherever a looped chapter ends and past each skip segment, which is passed to skipped
+func playAudioFile(vc *discordgo.VoiceConnection, song *QueueSong, session *AudioSession, skipped func(segments.Segment)) error {
+	if err := waitReady(vc); err != nil {
+		return err
+	}
 
 	vc.Speaking(true)
 	defer vc.Speaking(false)
 
-	cmd := exec.Command("ffmpeg",
-		"-i", filename,
+	stop := session.begin(vc)
+	defer session.Stop()
+	session.mu.Lock()
+	session.seekable = true
+	session.mu.Unlock()
+
+	skips := song.Skips()
+	position := song.Start
+	for {
+		// A looped chapter plays whole, skipping within it could jump past its end
+		_, _, looping := session.LoopedSection()
+		if skip, ok := segments.At(skips, position); ok && !looping {
+			skipped(skip)
+			position = skip.End
+			continue
+		}
+
+		segment := session.segment(position, song.End)
+		if segment.End > 0 && position >= segment.End {
+			return nil
+		}
+		if next, ok := segments.Next(skips, position); ok && !looping && (segment.End == 0 || next.Start < segment.End) {
+			segment.End = next.Start
+		}
+
+		session.restart(position)
+		err := streamPCM(vc, session, stop, exec.Command("ffmpeg", pcmArgs(sectionArgs(song.Filename, position, segment.End)...)...))
+		select {
+		case <-stop:
+			return err
+		default:
+		}
+
+		if seekTo, ok := session.takeSeek(); ok {
+			position = seekTo
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if loopStart, loopEnd, looping := session.LoopedSection(); looping && loopEnd == segment.End {
+			position = loopStart
+			continue
+		}
+		if segment.End != song.End { // Stopped early for a skip segment or a loop stopped partway through its chapter
+			position = segment.End
+			continue
+		}
+		return nil
+	}
+}
+
+// pcmArgs appends the arguments making ffmpeg write raw PCM to stdout onto its input arguments
+func pcmArgs(input ...string) []string {
+	return append(input,
 		"-f", "s16le",
 		"-ar", fmt.Sprintf("%d", sampleRate),
 		"-ac", fmt.Sprintf("%d", channels),
 		"pipe:1",
 	)
+}
 
+// streamPCM encodes the output of an ffmpeg command and sends it to Discord until it ends or the session is stopped
+func streamPCM(vc *discordgo.VoiceConnection, session *AudioSession, stop chan struct{}, cmd *exec.Cmd) error {
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
 		return err
@@ -128,23 +213,33 @@ func playAudioFile(vc *discordgo.VoiceConnection, filename string, session *Audi
 	encoder, err := gopus.NewEncoder(sampleRate, channels, gopus.Audio)
 	if err != nil {
 		cmd.Process.Kill()
+		cmd.Wait()
 		return err
 	}
 
-	pcmBuffer := make([]int16, frameSize*channels)
-	stop := make(chan struct{})
-
 	session.mu.Lock()
-	session.VC = vc
+	if session.stopped || session.seekTo != nil { // Seeked before ffmpeg started, playAudioFile restarts it
+		session.mu.Unlock()
+		cmd.Process.Kill()
+		cmd.Wait()
+		return nil
+	}
 	session.Cmd = cmd
 	session.Encoder = encoder
-	session.isPaused = false
-	session.stop = stop
-	session.stopped = false
 	session.mu.Unlock()
 
-	defer session.Stop()
+	// Stop kills and waits on the command itself, otherwise it is cleaned up here
+	defer func() {
+		session.mu.Lock()
+		defer session.mu.Unlock()
+		if session.Cmd == cmd {
+			cmd.Process.Kill()
+			cmd.Wait()
+			session.Cmd = nil
+		}
+	}()
 
+	pcmBuffer := make([]int16, frameSize*channels)
 	ticker := time.NewTicker(frameDuration)
 	defer ticker.Stop()
 
@@ -157,7 +252,7 @@ func playAudioFile(vc *discordgo.VoiceConnection, filename string, session *Audi
 			select {
 			case <-resume:
 				ticker = time.NewTicker(frameDuration)
-			case <-session.stop:
+			case <-stop:
 				return nil
 			}
 			continue
@@ -167,7 +262,7 @@ func playAudioFile(vc *discordgo.VoiceConnection, filename string, session *Audi
 		err := binary.Read(stdout, binary.LittleEndian, pcmBuffer)
 		if err != nil {
 			if err == io.EOF {
-				break
+				return nil
 			}
 			return err
 		}
@@ -178,6 +273,7 @@ func playAudioFile(vc *discordgo.VoiceConnection, filename string, session *Audi
 		}
 
 		<-ticker.C
+		session.played.Add(1)
 
 		if len(opusFrame) > 0 {
 			select {
@@ -189,13 +285,58 @@ func playAudioFile(vc *discordgo.VoiceConnection, filename string, session *Audi
 			}
 		}
 	}
-
-	return cmd.Wait()
 }
 
 type QueueSong struct {
-	Filename    string // Path to the audio file
-	RequestedBy string // Username of who requested the song
+	VideoID     string        // Identifier of the track within its source, the YouTube video ID for YouTube songs
+	Source      string        // Name of the source provider, empty for YouTube
+	URL         string        // Page the track was resolved from
+	Title       string        // Title shown in notices, may be empty
+	Filename    string        // Path to the audio file once downloaded
+	RequestedBy string        // Username of who requested the song
+	ChannelID   string        // Text channel the song was queued from
+	Live        bool          // True for live streams, which are streamed rather than downloaded
+	Start       time.Duration // Position within the audio file playback starts at
+	End         time.Duration // Position within the audio file playback ends at, zero for the end of the file
+
+	mu         sync.Mutex         // Mutex to protect concurrent resolves
+	resolved   bool               // True once the audio has been downloaded
+	err        error              // Error from a failed download
+	nowPlaying string             // Title announced by a live stream
+	skips      []segments.Segment // Non-music parts of the song skipped while it plays, loaded alongside its audio
+}
+
+// NewQueueSong returns an unresolved song for a YouTube video, its audio is downloaded shortly before it plays
+func NewQueueSong(videoID, title, username, channelID string) *QueueSong {
+	return NewTrackSong(&source.Track{
+		Source: source.YouTubeName,
+		ID:     videoID,
+		URL:    "https://www.youtube.com/watch?v=" + videoID,
+		Title:  title,
+	}, username, channelID)
+}
+
+// NewTrackSong returns an unresolved song for a track from any source provider
+func NewTrackSong(t *source.Track, username, channelID string) *QueueSong {
+	return &QueueSong{
+		VideoID:     t.ID,
+		Source:      t.Source,
+		URL:         t.URL,
+		Title:       t.Title,
+		Filename:    t.Filename(),
+		RequestedBy: username,
+		ChannelID:   channelID,
+		Live:        t.Live,
+	}
+}
+
+// Track returns the source track the song was queued from
+func (q *QueueSong) Track() *source.Track {
+	name := q.Source
+	if name == "" {
+		name = source.YouTubeName
+	}
+	return &source.Track{Source: name, ID: q.VideoID, URL: q.URL, Title: q.Title, Live: q.Live}
 }
 
 type QueueData struct {
@@ -203,6 +344,9 @@ type QueueData struct {
 	CurrentSong *QueueSong   // Currently playing song
 	Loop        bool         // Queue Loop
 	mu          sync.Mutex   // Mutex to protect concurrent access
+
+	ctx    context.Context    // Context of the downloads for queued songs, cancelled when the queue is cleared
+	cancel context.CancelFunc // Cancels ctx
 }
 
 type SessionData struct {
@@ -279,9 +423,16 @@ func (gm *GuildManager) DeleteGuild(guildID string) {
 	if exists {
 		delete(gm.sessions, guildID)
 	}
+	qd, queued := gm.songs[guildID]
 	delete(gm.songs, guildID)
 	gm.mu.Unlock()
 
+	if queued {
+		qd.mu.Lock()
+		qd.cancelDownloads()
+		qd.mu.Unlock()
+	}
+
 	if exists {
 		sd.mu.Lock()
 		if sd.Session != nil && !sd.Session.stopped {
@@ -294,6 +445,11 @@ func (gm *GuildManager) DeleteGuild(guildID string) {
 // StopAll stops all sessions from GuildManager
 func (gm *GuildManager) StopAll() {
 	gm.mu.Lock()
+	for _, qd := range gm.songs {
+		qd.mu.Lock()
+		qd.cancelDownloads()
+		qd.mu.Unlock()
+	}
 	for _, sd := range gm.sessions {
 		sd.mu.Lock()
 		if sd.Session != nil && !sd.Session.stopped {
@@ -335,16 +491,22 @@ func LoopGuildQueue(guildID string) (bool, error) {
 	return qd.Loop, nil
 }
 
-// Enqueue queues a song into the queue for a given guild
+// Enqueue queues an audio file on the host into the queue for a given guild, the file is played where it is
 func Enqueue(guildID, filename, username string) *GuildQueue {
+	return EnqueueSongs(guildID, NewTrackSong(&source.Track{
+		Source: source.LocalName,
+		ID:     filename,
+		URL:    filename,
+	}, username, ""))
+}
+
+// EnqueueSongs queues songs into the queue for a given guild and starts fetching the first of them
+func EnqueueSongs(guildID string, songs ...*QueueSong) *GuildQueue {
 	qd := guildManager.GetOrCreateQueue(guildID)
 	sd := guildManager.GetOrCreateSession(guildID)
 
 	qd.mu.Lock()
-	qd.Songs = append(qd.Songs, &QueueSong{
-		Filename:    filename,
-		RequestedBy: username,
-	})
+	qd.Songs = append(qd.Songs, songs...)
 	songsCopy := qd.Songs
 	currentCopy := qd.CurrentSong
 	qd.mu.Unlock()
@@ -355,6 +517,8 @@ func Enqueue(guildID, filename, username string) *GuildQueue {
 	}
 	sd.mu.Unlock()
 
+	prefetch(qd)
+
 	return &GuildQueue{
 		Songs:       songsCopy,
 		CurrentSong: currentCopy,
@@ -370,8 +534,6 @@ func PlayNext(s *discordgo.Session, guildID string, vc *discordgo.VoiceConnectio
 	if !qExists || !sExists {
 		return
 	}
-	ytManager := yt.NewYouTubeManager(redis_client.RDB)
-
 	for {
 		qd.mu.Lock()
 		if len(qd.Songs) == 0 {
@@ -383,6 +545,7 @@ func PlayNext(s *discordgo.Session, guildID string, vc *discordgo.VoiceConnectio
 		item := qd.Songs[0]
 		qd.Songs = qd.Songs[1:]
 		qd.CurrentSong = item
+		ctx := qd.downloadContext()
 		qd.mu.Unlock()
 
 		sd.mu.Lock()
@@ -393,18 +556,34 @@ func PlayNext(s *discordgo.Session, guildID string, vc *discordgo.VoiceConnectio
 		session := sd.Session
 		sd.mu.Unlock()
 
-		videoID := utils.GetAudioID(item.Filename)
-		if _, err := os.Stat(item.Filename); os.IsNotExist(err) {
-			ytManager.DownloadAudio(videoID)
+		// Download the upcoming songs while this one plays
+		prefetch(qd)
+
+		if err := item.Resolve(ctx); err != nil {
+			if ctx.Err() == nil { // Cancelled downloads belong to songs which were cleared from the queue
+				notifySkipped(s, item, err)
+			}
+			qd.mu.Lock()
+			qd.CurrentSong = nil
+			qd.mu.Unlock()
+			continue
 		}
 
-		err := playAudioFile(vc, item.Filename, session)
+		var err error
+		if item.Live {
+			err = playLiveStream(vc, item, session)
+		} else {
+			audiocache.Touch(item.Filename)
+			err = playAudioFile(vc, item, session, func(skip segments.Segment) {
+				notifySegmentSkipped(s, item, skip)
+			})
+		}
 		if err != nil && err.Error() != "EOF" && err.Error() != "unexpected EOF" {
 			fmt.Printf("Playback error: %v\n", err)
 		}
 
 		qd.mu.Lock()
-		if qd.Loop {
+		if qd.Loop && qd.CurrentSong == item { // Cleared or replaced queues drop the current song
 			qd.Songs = append(qd.Songs, item)
 		} else {
 			qd.CurrentSong = nil
@@ -461,11 +640,43 @@ func ClearGuildQueue(guildID string) {
 
 	// Clear queue
 	qd.mu.Lock()
+	qd.cancelDownloads()
 	qd.Songs = []*QueueSong{}
 	qd.CurrentSong = nil
 	qd.mu.Unlock()
 }
 
+// ReplaceGuildQueue swaps the queued songs for a guild with new ones and stops the current song so playback moves straight on to them
+func ReplaceGuildQueue(guildID string, songs ...*QueueSong) *GuildQueue {
+	qd := guildManager.GetOrCreateQueue(guildID)
+	sd := guildManager.GetOrCreateSession(guildID)
+
+	qd.mu.Lock()
+	qd.cancelDownloads()
+	qd.Songs = songs
+	qd.CurrentSong = nil
+	songsCopy := qd.Songs
+	qd.mu.Unlock()
+
+	// A playing session is stopped so its playback loop moves on to the new songs, an idle one is replaced like Enqueue does
+	sd.mu.Lock()
+	if sd.Session == nil || sd.Session.stopped || sd.Session.VC == nil {
+		sd.Session = &AudioSession{}
+	} else {
+		sd.Session.Stop()
+	}
+	session := sd.Session
+	sd.mu.Unlock()
+
+	prefetch(qd)
+
+	return &GuildQueue{
+		Songs:   songsCopy,
+		Loop:    qd.Loop,
+		Session: session,
+	}
+}
+
 // ClearCurrentSong clears the currently playing item for a guild
 func ClearCurrentSong(guildID string) {
 	qd, exists := guildManager.GetQueue(guildID)
@@ -478,6 +689,31 @@ func ClearCurrentSong(guildID string) {
 	qd.mu.Unlock()
 }
 
+// QueuedFiles returns the audio files of every queued and playing song across guilds
+func QueuedFiles() []string {
+	guildManager.mu.RLock()
+	queues := make([]*QueueData, 0, len(guildManager.songs))
+	for _, qd := range guildManager.songs {
+		queues = append(queues, qd)
+	}
+	guildManager.mu.RUnlock()
+
+	var files []string
+	for _, qd := range queues {
+		qd.mu.Lock()
+		if qd.CurrentSong != nil && !qd.CurrentSong.Live {
+			files = append(files, qd.CurrentSong.Filename)
+		}
+		for _, song := range qd.Songs {
+			if !song.Live {
+				files = append(files, song.Filename)
+			}
+		}
+		qd.mu.Unlock()
+	}
+	return files
+}
+
 // StopAllSessions clears data for all guilds and closes all Sessions
 func StopAllSessions() {
 	guildManager.StopAll()
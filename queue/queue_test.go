@@ -3,6 +3,7 @@ package queue
 import (
 	"testing"
 
+	"github.com/bwmarrin/discordgo"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -345,3 +346,66 @@ func TestEnqueue_StoppedSession(t *testing.T) {
 	assert.False(t, sd.Session.stopped)
 	assert.Equal(t, 2, len(gq.Songs))
 }
+
+func TestReplaceGuildQueue(t *testing.T) {
+	guildManager = &GuildManager{
+		songs:    make(map[string]*QueueData),
+		sessions: make(map[string]*SessionData),
+	}
+
+	guildID := "test-guild-replace"
+	Enqueue(guildID, "cache/old1.opus", "user1")
+	Enqueue(guildID, "cache/old2.opus", "user1")
+
+	qd, _ := guildManager.GetQueue(guildID)
+	qd.mu.Lock()
+	qd.CurrentSong = &QueueSong{Filename: "cache/current.opus", RequestedBy: "user1"}
+	qd.mu.Unlock()
+
+	sd, _ := guildManager.GetSession(guildID)
+	playing := &AudioSession{VC: &discordgo.VoiceConnection{}, stop: make(chan struct{})}
+	sd.Session = playing
+
+	gq := ReplaceGuildQueue(guildID, NewQueueSong("new1", "", "user2", ""), NewQueueSong("new2", "", "user2", ""))
+
+	assert.Equal(t, 2, len(gq.Songs))
+	assert.Equal(t, "cache/new1.opus", gq.Songs[0].Filename)
+	assert.Equal(t, "user2", gq.Songs[1].RequestedBy)
+	assert.True(t, playing.stopped)
+	assert.Same(t, playing, gq.Session) // The running playback loop moves on with the same session
+
+	qd.mu.Lock()
+	defer qd.mu.Unlock()
+	assert.Nil(t, qd.CurrentSong)
+}
+
+func TestReplaceGuildQueue_Idle(t *testing.T) {
+	guildManager = &GuildManager{
+		songs:    make(map[string]*QueueData),
+		sessions: make(map[string]*SessionData),
+	}
+
+	gq := ReplaceGuildQueue("test-guild-replace-idle", NewQueueSong("new1", "", "user", ""))
+
+	assert.Equal(t, 1, len(gq.Songs))
+	assert.NotNil(t, gq.Session)
+	assert.Nil(t, gq.Session.VC)
+	assert.False(t, gq.Session.stopped)
+}
+
+func TestQueuedFiles(t *testing.T) {
+	guildManager = &GuildManager{
+		songs:    make(map[string]*QueueData),
+		sessions: make(map[string]*SessionData),
+	}
+
+	Enqueue("guild-1", "cache/song1.opus", "user1")
+	Enqueue("guild-2", "cache/song2.opus", "user2")
+	radio := NewQueueSong("radio", "", "user2", "")
+	radio.Live = true
+	EnqueueSongs("guild-2", radio)
+	qd, _ := guildManager.GetQueue("guild-1")
+	qd.CurrentSong = NewQueueSong("playing", "", "user1", "")
+
+	assert.ElementsMatch(t, []string{"cache/playing.opus", "cache/song1.opus", "cache/song2.opus"}, QueuedFiles())
+}
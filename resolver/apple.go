@@ -0,0 +1,124 @@
+package resolver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var ErrApplePlaylist = errors.New("apple music playlists can't be looked up")
+
+// AppleMusic lists tracks through the public iTunes lookup API, which needs no credentials
+type AppleMusic struct {
+	lookupURL string
+	client    *http.Client
+}
+
+// NewAppleMusic returns the Apple Music catalog
+func NewAppleMusic() *AppleMusic {
+	return &AppleMusic{
+		lookupURL: "https://itunes.apple.com/lookup",
+		client:    &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// Name returns the name of the service
+func (a *AppleMusic) Name() string {
+	return "Apple Music"
+}
+
+// Match reports whether the URL is an Apple Music song, album or playlist link
+func (a *AppleMusic) Match(u *url.URL) bool {
+	_, _, _, ok := parseAppleURL(u)
+	return ok
+}
+
+// Tracks returns the tracks behind an Apple Music song or album link
+func (a *AppleMusic) Tracks(ctx context.Context, rawURL string) ([]CatalogTrack, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	kind, id, country, ok := parseAppleURL(u)
+	if !ok {
+		return nil, ErrUnsupported
+	}
+	if kind == "playlist" {
+		return nil, ErrApplePlaylist
+	}
+
+	query := url.Values{"id": {id}, "country": {country}}
+	if kind == "album" {
+		query.Set("entity", "song")
+		query.Set("limit", "200")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.lookupURL+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("itunes lookup returned %s", resp.Status)
+	}
+
+	var body struct {
+		Results []struct {
+			WrapperType     string `json:"wrapperType"`
+			Kind            string `json:"kind"`
+			ArtistName      string `json:"artistName"`
+			TrackName       string `json:"trackName"`
+			TrackTimeMillis int64  `json:"trackTimeMillis"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+
+	// Album lookups list the collection itself first, followed by its songs
+	var tracks []CatalogTrack
+	for _, result := range body.Results {
+		if result.WrapperType != "track" || result.TrackName == "" {
+			continue
+		}
+		tracks = append(tracks, CatalogTrack{
+			Artist:   result.ArtistName,
+			Title:    result.TrackName,
+			Duration: time.Duration(result.TrackTimeMillis) * time.Millisecond,
+		})
+	}
+	return tracks, nil
+}
+
+// parseAppleURL reads links like music.apple.com/us/album/<name>/<id>?i=<song id>, a song within an album counts as a song
+func parseAppleURL(u *url.URL) (kind string, id string, country string, ok bool) {
+	if !strings.EqualFold(u.Hostname(), "music.apple.com") {
+		return "", "", "", false
+	}
+
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) < 3 {
+		return "", "", "", false
+	}
+	country, kind, id = parts[0], parts[1], parts[len(parts)-1]
+
+	switch kind {
+	case "album":
+		if song := u.Query().Get("i"); song != "" {
+			return "song", song, country, true
+		}
+		return kind, id, country, true
+	case "song", "playlist":
+		return kind, id, country, true
+	}
+	return "", "", "", false
+}
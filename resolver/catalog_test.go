@@ -0,0 +1,125 @@
+package resolver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSpotifyURL(t *testing.T) {
+	cases := map[string][2]string{
+		"https://open.spotify.com/track/4uLU6hMCjMI75M1A2tKUQC?si=abc":  {"track", "4uLU6hMCjMI75M1A2tKUQC"},
+		"https://open.spotify.com/intl-de/album/1DFixLWuPkv3KT3TnV35m3": {"album", "1DFixLWuPkv3KT3TnV35m3"},
+		"https://open.spotify.com/playlist/37i9dQZF1DXcBWIGoYBM5M":      {"playlist", "37i9dQZF1DXcBWIGoYBM5M"},
+	}
+	for rawURL, expected := range cases {
+		u, _ := url.Parse(rawURL)
+		kind, id, ok := parseSpotifyURL(u)
+		assert.True(t, ok, rawURL)
+		assert.Equal(t, expected, [2]string{kind, id}, rawURL)
+	}
+
+	for _, rawURL := range []string{"https://open.spotify.com/artist/1", "https://spotify.com/track/1", "https://open.spotify.com/track/"} {
+		u, _ := url.Parse(rawURL)
+		_, _, ok := parseSpotifyURL(u)
+		assert.False(t, ok, rawURL)
+	}
+}
+
+func TestParseAppleURL(t *testing.T) {
+	u, _ := url.Parse("https://music.apple.com/gb/album/some-album/1440857781?i=1440857795")
+	kind, id, country, ok := parseAppleURL(u)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"song", "1440857795", "gb"}, []string{kind, id, country})
+
+	u, _ = url.Parse("https://music.apple.com/us/album/some-album/1440857781")
+	kind, id, _, ok = parseAppleURL(u)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"album", "1440857781"}, []string{kind, id})
+
+	u, _ = url.Parse("https://music.apple.com/us/artist/someone/123")
+	_, _, _, ok = parseAppleURL(u)
+	assert.False(t, ok)
+}
+
+func TestSpotify_TracksPaged(t *testing.T) {
+	viper.Set("spotify.client.id", "id")
+	viper.Set("spotify.client.secret", "secret")
+	t.Cleanup(func() {
+		viper.Set("spotify.client.id", nil)
+		viper.Set("spotify.client.secret", nil)
+	})
+
+	tokenRequests := 0
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		user, pass, _ := r.BasicAuth()
+		assert.Equal(t, "id", user)
+		assert.Equal(t, "secret", pass)
+		tokenRequests++
+		w.Write([]byte(`{"access_token": "tok", "expires_in": 3600}`))
+	})
+	mux.HandleFunc("/playlists/pl/tracks", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer tok", r.Header.Get("Authorization"))
+		if r.URL.Query().Get("page") == "2" {
+			w.Write([]byte(`{"items": [{"track": {"name": "Three", "duration_ms": 3000, "artists": [{"name": "C"}]}}], "next": ""}`))
+			return
+		}
+		w.Write([]byte(`{"items": [
+			{"track": {"name": "One", "duration_ms": 1000, "artists": [{"name": "A"}, {"name": "B"}]}},
+			{"track": null}
+		], "next": "` + server.URL + `/playlists/pl/tracks?page=2"}`))
+	})
+	server = httptest.NewServer(mux)
+	defer server.Close()
+
+	spotify := NewSpotify()
+	spotify.apiURL = server.URL
+	spotify.tokenURL = server.URL + "/token"
+
+	tracks, err := spotify.Tracks(context.Background(), "https://open.spotify.com/playlist/pl")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []CatalogTrack{
+		{Artist: "A, B", Title: "One", Duration: time.Second},
+		{Artist: "C", Title: "Three", Duration: 3 * time.Second},
+	}, tracks)
+	assert.Equal(t, 1, tokenRequests)
+}
+
+func TestSpotify_NoCredentials(t *testing.T) {
+	_, err := NewSpotify().Tracks(context.Background(), "https://open.spotify.com/track/abc")
+	assert.ErrorIs(t, err, ErrNoCredentials)
+}
+
+func TestAppleMusic_Album(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "123", r.URL.Query().Get("id"))
+		assert.Equal(t, "song", r.URL.Query().Get("entity"))
+		w.Write([]byte(`{"results": [
+			{"wrapperType": "collection", "collectionName": "Album"},
+			{"wrapperType": "track", "artistName": "Artist", "trackName": "Song", "trackTimeMillis": 180000}
+		]}`))
+	}))
+	defer server.Close()
+
+	apple := NewAppleMusic()
+	apple.lookupURL = server.URL
+
+	tracks, err := apple.Tracks(context.Background(), "https://music.apple.com/us/album/album/123")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []CatalogTrack{{Artist: "Artist", Title: "Song", Duration: 3 * time.Minute}}, tracks)
+}
+
+func TestAppleMusic_Playlist(t *testing.T) {
+	_, err := NewAppleMusic().Tracks(context.Background(), "https://music.apple.com/us/playlist/mix/pl.abc")
+	assert.ErrorIs(t, err, ErrApplePlaylist)
+}
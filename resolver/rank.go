@@ -0,0 +1,82 @@
+package resolver
+
+import (
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/spf13/viper"
+)
+
+// Words marking a different version of a song, penalised unless the catalog track has them too
+var versionWords = []string{"live", "cover", "remix", "karaoke", "instrumental", "acoustic", "slowed", "sped", "nightcore", "reverb", "8d"}
+
+// rank picks the candidate closest to the track, earlier candidates win ties as search order is a signal too
+func rank(track CatalogTrack, candidates []Candidate) (Candidate, float64) {
+	best, bestScore := candidates[0], -1.0
+	for _, c := range candidates {
+		if s := score(track, c); s > bestScore {
+			best, bestScore = c, s
+		}
+	}
+	return best, bestScore
+}
+
+// score rates a candidate between 0 and 1, mostly on how close its duration is with the words of the title and artist breaking ties
+func score(track CatalogTrack, c Candidate) float64 {
+	haystack := words(c.Title + " " + c.Channel)
+	s := 0.5*durationScore(track.Duration, c.Duration) +
+		0.3*overlap(words(track.Title), haystack) +
+		0.2*overlap(words(track.Artist), haystack)
+
+	title := words(track.Title)
+	for _, word := range versionWords {
+		if haystack[word] && !title[word] {
+			s *= 0.5
+			break
+		}
+	}
+	return s
+}
+
+// durationScore falls linearly from 1 for equal durations to 0 at the configured tolerance, unknown durations score half
+func durationScore(want, got time.Duration) float64 {
+	if want <= 0 || got <= 0 {
+		return 0.5
+	}
+	tolerance := time.Duration(viper.GetInt("resolver.tolerance")) * time.Second
+	if tolerance <= 0 {
+		tolerance = 30 * time.Second
+	}
+
+	diff := want - got
+	if diff < 0 {
+		diff = -diff
+	}
+	return max(0, 1-float64(diff)/float64(tolerance))
+}
+
+// overlap returns the fraction of words found within the haystack, an empty set of words counts as found
+func overlap(want map[string]bool, haystack map[string]bool) float64 {
+	if len(want) == 0 {
+		return 1
+	}
+	found := 0
+	for word := range want {
+		if haystack[word] {
+			found++
+		}
+	}
+	return float64(found) / float64(len(want))
+}
+
+// words splits text into a set of lowercase words, ignoring punctuation
+func words(text string) map[string]bool {
+	set := map[string]bool{}
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}) {
+		set[word] = true
+	}
+	return set
+}
@@ -0,0 +1,195 @@
+package resolver
+
+import (
+	"Twilight/redis_client"
+	"Twilight/yt"
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+var ErrUnsupported = errors.New("unsupported catalog link")
+
+// CatalogTrack is a track listed by a streaming service which can't be played directly
+type CatalogTrack struct {
+	Artist   string
+	Title    string
+	Duration time.Duration
+}
+
+// Query returns the YouTube search query for the track
+func (t CatalogTrack) Query() string {
+	if t.Artist == "" {
+		return t.Title
+	}
+	return t.Artist + " - " + t.Title
+}
+
+// Candidate is a playable video which may match a catalog track
+type Candidate struct {
+	ID       string
+	Title    string
+	Channel  string
+	Duration time.Duration
+}
+
+// Match is a catalog track along with the video picked to play it
+type Match struct {
+	Track      CatalogTrack
+	Candidate  Candidate
+	Confidence float64 // Between 0 and 1, how closely the video matches the track
+}
+
+// Catalog lists the tracks behind a streaming service link
+type Catalog interface {
+	Name() string                                                      // Name of the service shown to users
+	Match(u *url.URL) bool                                             // Reports whether the catalog handles the URL
+	Tracks(ctx context.Context, rawURL string) ([]CatalogTrack, error) // Returns the tracks of a track, album or playlist link
+}
+
+// Searcher finds videos which may match a catalog track
+type Searcher interface {
+	Search(ctx context.Context, query string, limit int) ([]Candidate, error)
+}
+
+// Resolver turns streaming service links into playable videos
+type Resolver struct {
+	catalogs []Catalog
+	searcher Searcher
+}
+
+// Result is the outcome of resolving a link
+type Result struct {
+	Catalog   string  // Name of the service the link belongs to
+	Matches   []Match // Matched tracks in the order the service lists them
+	Unmatched []CatalogTrack
+}
+
+// New returns a resolver using the given search backend and catalogs
+func New(searcher Searcher, catalogs ...Catalog) *Resolver {
+	return &Resolver{catalogs: catalogs, searcher: searcher}
+}
+
+// Default returns a resolver matching Spotify and Apple Music links against YouTube
+func Default() *Resolver {
+	return New(&YouTubeSearcher{}, NewSpotify(), NewAppleMusic())
+}
+
+// Handles reports whether the link belongs to one of the catalogs
+func (r *Resolver) Handles(rawURL string) bool {
+	return r.catalog(rawURL) != nil
+}
+
+// catalog returns the catalog handling a link, or nil
+func (r *Resolver) catalog(rawURL string) Catalog {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || u.Host == "" {
+		return nil
+	}
+	for _, c := range r.catalogs {
+		if c.Match(u) {
+			return c
+		}
+	}
+	return nil
+}
+
+// Resolve lists the tracks behind a link and matches each of them to a video, searching concurrently and handing
+// matched each run of matches in order as soon as every track before them is resolved, matched may be nil
+func (r *Resolver) Resolve(ctx context.Context, rawURL string, matched func([]Match)) (*Result, error) {
+	c := r.catalog(rawURL)
+	if c == nil {
+		return nil, ErrUnsupported
+	}
+
+	tracks, err := c.Tracks(ctx, rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if maxTracks := viper.GetInt("resolver.max_tracks"); maxTracks > 0 && len(tracks) > maxTracks {
+		tracks = tracks[:maxTracks]
+	}
+
+	maxConcurrency := max(viper.GetInt("youtube.concurrency"), 1)
+	matches := make([]*Match, len(tracks))
+	done := make([]bool, len(tracks))
+	var mu sync.Mutex
+	next := 0 // First track whose match hasn't been handed to matched yet
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(maxConcurrency, len(tracks)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				m := r.match(ctx, tracks[idx])
+
+				mu.Lock()
+				matches[idx], done[idx] = m, true
+				var ready []Match
+				for ; next < len(tracks) && done[next]; next++ {
+					if matches[next] != nil {
+						ready = append(ready, *matches[next])
+					}
+				}
+				// Called while locked so runs are handed over in order
+				if matched != nil && len(ready) > 0 {
+					matched(ready)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for idx := range tracks {
+		jobs <- idx
+	}
+	close(jobs)
+	wg.Wait()
+
+	result := &Result{Catalog: c.Name()}
+	for idx, m := range matches {
+		if m == nil {
+			result.Unmatched = append(result.Unmatched, tracks[idx])
+			continue
+		}
+		result.Matches = append(result.Matches, *m)
+	}
+	return result, nil
+}
+
+// match searches for a track and picks the best candidate, returning nil when nothing was found
+func (r *Resolver) match(ctx context.Context, track CatalogTrack) *Match {
+	candidates, err := r.searcher.Search(ctx, track.Query(), viper.GetInt("resolver.candidates"))
+	if err != nil || len(candidates) == 0 {
+		return nil
+	}
+	best, confidence := rank(track, candidates)
+	return &Match{Track: track, Candidate: best, Confidence: confidence}
+}
+
+// YouTubeSearcher searches YouTube through yt-dlp
+type YouTubeSearcher struct{}
+
+// Search returns up to limit videos for a query
+func (y *YouTubeSearcher) Search(ctx context.Context, query string, limit int) ([]Candidate, error) {
+	results, err := yt.NewYouTubeManager(redis_client.RDB).SearchVideos(ctx, query, max(limit, 1))
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]Candidate, 0, len(results))
+	for _, result := range results {
+		candidates = append(candidates, Candidate{
+			ID:       result.ID,
+			Title:    result.Title,
+			Channel:  result.Channel,
+			Duration: result.Duration,
+		})
+	}
+	return candidates, nil
+}
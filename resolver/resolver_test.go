@@ -0,0 +1,174 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeCatalog serves a fixed set of tracks for links on its host
+type fakeCatalog struct {
+	host   string
+	tracks []CatalogTrack
+	err    error
+}
+
+func (f *fakeCatalog) Name() string { return "Fake" }
+
+func (f *fakeCatalog) Match(u *url.URL) bool { return u.Hostname() == f.host }
+
+func (f *fakeCatalog) Tracks(ctx context.Context, rawURL string) ([]CatalogTrack, error) {
+	return f.tracks, f.err
+}
+
+// fakeSearcher returns canned candidates per query and records the queries it was given
+type fakeSearcher struct {
+	mu      sync.Mutex
+	results map[string][]Candidate
+	queries []string
+}
+
+func (f *fakeSearcher) Search(ctx context.Context, query string, limit int) ([]Candidate, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.queries = append(f.queries, query)
+	results, ok := f.results[query]
+	if !ok {
+		return nil, errors.New("no results")
+	}
+	return results, nil
+}
+
+func TestResolve_RanksByDurationAndKeepsOrder(t *testing.T) {
+	catalog := &fakeCatalog{host: "catalog.test", tracks: []CatalogTrack{
+		{Artist: "Artist", Title: "First", Duration: 200 * time.Second},
+		{Artist: "Artist", Title: "Missing", Duration: 180 * time.Second},
+		{Artist: "Artist", Title: "Second", Duration: 240 * time.Second},
+	}}
+	searcher := &fakeSearcher{results: map[string][]Candidate{
+		"Artist - First": {
+			{ID: "extended", Title: "Artist - First (Extended)", Duration: 400 * time.Second},
+			{ID: "first", Title: "Artist - First", Duration: 201 * time.Second},
+		},
+		"Artist - Second": {
+			{ID: "second", Title: "Second", Channel: "Artist", Duration: 240 * time.Second},
+		},
+	}}
+
+	result, err := New(searcher, catalog).Resolve(context.Background(), "https://catalog.test/album/1", nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Fake", result.Catalog)
+	assert.Len(t, result.Matches, 2)
+	assert.Equal(t, "first", result.Matches[0].Candidate.ID)
+	assert.Equal(t, "second", result.Matches[1].Candidate.ID)
+	assert.Greater(t, result.Matches[1].Confidence, 0.9)
+	assert.Equal(t, []CatalogTrack{catalog.tracks[1]}, result.Unmatched)
+	assert.Len(t, searcher.queries, 3)
+}
+
+func TestResolve_HandsOverMatchesInOrder(t *testing.T) {
+	viper.Set("youtube.concurrency", 3)
+	t.Cleanup(func() { viper.Set("youtube.concurrency", nil) })
+	catalog := &fakeCatalog{host: "catalog.test"}
+	searcher := &fakeSearcher{results: map[string][]Candidate{}}
+	var want []string
+	for idx := range 20 {
+		title := fmt.Sprintf("Song %d", idx)
+		catalog.tracks = append(catalog.tracks, CatalogTrack{Title: title})
+		if idx%4 != 1 {
+			searcher.results[title] = []Candidate{{ID: title, Title: title}}
+			want = append(want, title)
+		}
+	}
+
+	var got []string
+	_, err := New(searcher, catalog).Resolve(context.Background(), "https://catalog.test/album/1", func(matches []Match) {
+		for _, m := range matches {
+			got = append(got, m.Candidate.ID)
+		}
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestResolve_CapsTracks(t *testing.T) {
+	viper.Set("resolver.max_tracks", 2)
+	t.Cleanup(func() { viper.Set("resolver.max_tracks", nil) })
+	catalog := &fakeCatalog{host: "catalog.test", tracks: []CatalogTrack{{Title: "A"}, {Title: "B"}, {Title: "C"}}}
+	searcher := &fakeSearcher{results: map[string][]Candidate{}}
+
+	result, err := New(searcher, catalog).Resolve(context.Background(), "https://catalog.test/album/1", nil)
+
+	assert.NoError(t, err)
+	assert.Len(t, result.Unmatched, 2)
+	assert.Len(t, searcher.queries, 2)
+}
+
+func TestResolve_Unsupported(t *testing.T) {
+	r := New(&fakeSearcher{}, &fakeCatalog{host: "catalog.test"})
+
+	assert.False(t, r.Handles("https://example.com/track/1"))
+	assert.True(t, r.Handles("https://catalog.test/track/1"))
+	_, err := r.Resolve(context.Background(), "https://example.com/track/1", nil)
+	assert.ErrorIs(t, err, ErrUnsupported)
+}
+
+func TestResolve_CatalogError(t *testing.T) {
+	r := New(&fakeSearcher{}, &fakeCatalog{host: "catalog.test", err: ErrNoCredentials})
+
+	_, err := r.Resolve(context.Background(), "https://catalog.test/track/1", nil)
+
+	assert.ErrorIs(t, err, ErrNoCredentials)
+}
+
+func TestRank_PenalisesOtherVersions(t *testing.T) {
+	track := CatalogTrack{Artist: "Band", Title: "Song", Duration: 180 * time.Second}
+	candidates := []Candidate{
+		{ID: "live", Title: "Band - Song (Live)", Duration: 180 * time.Second},
+		{ID: "studio", Title: "Band - Song", Duration: 185 * time.Second},
+	}
+
+	best, confidence := rank(track, candidates)
+
+	assert.Equal(t, "studio", best.ID)
+	assert.InDelta(t, 0.5*(1-5.0/30)+0.3+0.2, confidence, 0.001)
+}
+
+func TestRank_KeepsVersionAskedFor(t *testing.T) {
+	track := CatalogTrack{Artist: "Band", Title: "Song - Live", Duration: 180 * time.Second}
+	candidates := []Candidate{
+		{ID: "studio", Title: "Band - Song", Duration: 170 * time.Second},
+		{ID: "live", Title: "Band - Song (Live)", Duration: 180 * time.Second},
+	}
+
+	best, _ := rank(track, candidates)
+
+	assert.Equal(t, "live", best.ID)
+}
+
+func TestDurationScore(t *testing.T) {
+	assert.Equal(t, 1.0, durationScore(time.Minute, time.Minute))
+	assert.InDelta(t, 0.5, durationScore(time.Minute, 75*time.Second), 0.001)
+	assert.Equal(t, 0.0, durationScore(time.Minute, 2*time.Minute))
+	assert.Equal(t, 0.5, durationScore(0, time.Minute))
+}
+
+func TestCatalogTrack_Query(t *testing.T) {
+	assert.Equal(t, "Artist - Title", CatalogTrack{Artist: "Artist", Title: "Title"}.Query())
+	assert.Equal(t, "Title", CatalogTrack{Title: "Title"}.Query())
+}
+
+func TestWords(t *testing.T) {
+	assert.Equal(t, map[string]bool{"don": true, "t": true, "stop": true, "me": true, "now": true}, words("Don't Stop Me Now!"))
+	assert.True(t, words(strings.ToUpper("Beyoncé"))["beyoncé"])
+}
@@ -0,0 +1,217 @@
+package resolver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+var ErrNoCredentials = errors.New("spotify client credentials are not configured")
+
+// Spotify lists tracks through the Spotify Web API using client credentials
+type Spotify struct {
+	apiURL   string
+	tokenURL string
+	client   *http.Client
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// spotifyTrack is the subset of a Spotify track object used to build catalog tracks
+type spotifyTrack struct {
+	Name       string `json:"name"`
+	DurationMS int64  `json:"duration_ms"`
+	Artists    []struct {
+		Name string `json:"name"`
+	} `json:"artists"`
+}
+
+// NewSpotify returns the Spotify catalog
+func NewSpotify() *Spotify {
+	return &Spotify{
+		apiURL:   "https://api.spotify.com/v1",
+		tokenURL: "https://accounts.spotify.com/api/token",
+		client:   &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// Name returns the name of the service
+func (s *Spotify) Name() string {
+	return "Spotify"
+}
+
+// Match reports whether the URL is a Spotify track, album or playlist link
+func (s *Spotify) Match(u *url.URL) bool {
+	_, _, ok := parseSpotifyURL(u)
+	return ok
+}
+
+// Tracks returns the tracks behind a Spotify link
+func (s *Spotify) Tracks(ctx context.Context, rawURL string) ([]CatalogTrack, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	kind, id, ok := parseSpotifyURL(u)
+	if !ok {
+		return nil, ErrUnsupported
+	}
+
+	if kind == "track" {
+		var track spotifyTrack
+		if err := s.get(ctx, s.apiURL+"/tracks/"+id, &track); err != nil {
+			return nil, err
+		}
+		return []CatalogTrack{track.catalogTrack()}, nil
+	}
+
+	// Albums list tracks directly while playlists wrap them, both are paged through next
+	next := s.apiURL + "/albums/" + id + "/tracks?limit=50"
+	if kind == "playlist" {
+		next = s.apiURL + "/playlists/" + id + "/tracks?limit=100&fields=items(track(name,duration_ms,artists(name))),next"
+	}
+
+	maxTracks := viper.GetInt("resolver.max_tracks")
+	var tracks []CatalogTrack
+	for next != "" && (maxTracks <= 0 || len(tracks) < maxTracks) {
+		var page struct {
+			Items []json.RawMessage `json:"items"`
+			Next  string            `json:"next"`
+		}
+		if err := s.get(ctx, next, &page); err != nil {
+			return nil, err
+		}
+
+		for _, item := range page.Items {
+			var track spotifyTrack
+			if kind == "playlist" {
+				var wrapped struct {
+					Track *spotifyTrack `json:"track"`
+				}
+				if json.Unmarshal(item, &wrapped) != nil || wrapped.Track == nil {
+					continue // Removed tracks come back as null
+				}
+				track = *wrapped.Track
+			} else if json.Unmarshal(item, &track) != nil {
+				continue
+			}
+			if track.Name != "" {
+				tracks = append(tracks, track.catalogTrack())
+			}
+		}
+		next = page.Next
+	}
+
+	if maxTracks > 0 && len(tracks) > maxTracks {
+		tracks = tracks[:maxTracks]
+	}
+	return tracks, nil
+}
+
+// get fetches a Web API endpoint into v, authenticating with an access token
+func (s *Spotify) get(ctx context.Context, endpoint string, v any) error {
+	token, err := s.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("spotify returned %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// accessToken returns a client credentials token, requesting a new one shortly before the last expires
+func (s *Spotify) accessToken(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != "" && time.Now().Before(s.expires) {
+		return s.token, nil
+	}
+
+	clientID, clientSecret := viper.GetString("spotify.client.id"), viper.GetString("spotify.client.secret")
+	if clientID == "" || clientSecret == "" {
+		return "", ErrNoCredentials
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.tokenURL, strings.NewReader(url.Values{"grant_type": {"client_credentials"}}.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.SetBasicAuth(clientID, clientSecret)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("spotify token request returned %s", resp.Status)
+	}
+
+	var body struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	s.token = body.AccessToken
+	s.expires = time.Now().Add(time.Duration(body.ExpiresIn)*time.Second - time.Minute)
+	return s.token, nil
+}
+
+// catalogTrack converts a Spotify track, joining multiple artists
+func (t spotifyTrack) catalogTrack() CatalogTrack {
+	artists := make([]string, 0, len(t.Artists))
+	for _, artist := range t.Artists {
+		artists = append(artists, artist.Name)
+	}
+	return CatalogTrack{
+		Artist:   strings.Join(artists, ", "),
+		Title:    t.Name,
+		Duration: time.Duration(t.DurationMS) * time.Millisecond,
+	}
+}
+
+// parseSpotifyURL reads the kind and ID from links like open.spotify.com/intl-de/album/<id>
+func parseSpotifyURL(u *url.URL) (string, string, bool) {
+	if !strings.EqualFold(u.Hostname(), "open.spotify.com") {
+		return "", "", false
+	}
+
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) > 0 && strings.HasPrefix(parts[0], "intl-") {
+		parts = parts[1:]
+	}
+	if len(parts) < 2 || parts[1] == "" {
+		return "", "", false
+	}
+	switch parts[0] {
+	case "track", "album", "playlist":
+		return parts[0], parts[1], true
+	}
+	return "", "", false
+}
@@ -0,0 +1,192 @@
+package segments
+
+import (
+	"Twilight/redis_client"
+	"Twilight/yt"
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Strum355/log"
+	"github.com/spf13/viper"
+)
+
+// Segment is a part of a video which isn't music, such as a sponsor read or a talking intro
+type Segment struct {
+	Category string // SponsorBlock category such as sponsor, intro or music_offtopic
+	Start    time.Duration
+	End      time.Duration
+}
+
+// Provider loads the skip segments of videos
+type Provider interface {
+	Segments(ctx context.Context, videoID string, categories []string) ([]Segment, error) // Videos without segments return none without failing
+}
+
+var (
+	providerMu sync.RWMutex
+	provider   Provider
+)
+
+// SetProvider replaces the provider segments are loaded from, returning the previous one so tests can restore it
+func SetProvider(p Provider) Provider {
+	providerMu.Lock()
+	defer providerMu.Unlock()
+	previous := provider
+	provider = p
+	return previous
+}
+
+// currentProvider returns the provider segments are loaded from, SponsorBlock cached within Redis unless replaced
+func currentProvider() Provider {
+	providerMu.RLock()
+	p := provider
+	providerMu.RUnlock()
+	if p != nil {
+		return p
+	}
+
+	providerMu.Lock()
+	defer providerMu.Unlock()
+	if provider == nil {
+		ttl := time.Duration(viper.GetInt("cache.segments")) * time.Second
+		provider = Cached(NewSponsorBlock(viper.GetString("segments.api")), yt.NewRedisStore(redis_client.RDB), ttl)
+	}
+	return provider
+}
+
+// cached keeps the segments a provider loaded within a Store
+type cached struct {
+	provider Provider
+	store    yt.Store
+	ttl      time.Duration
+}
+
+// Cached wraps a provider so the segments of each video are only loaded once per ttl, videos without any included
+func Cached(p Provider, store yt.Store, ttl time.Duration) Provider {
+	return cached{provider: p, store: store, ttl: ttl}
+}
+
+// Segments returns the cached segments of a video, loading them on a miss
+func (c cached) Segments(ctx context.Context, videoID string, categories []string) ([]Segment, error) {
+	key := "segments:" + videoID + ":" + strings.Join(categories, ",")
+	value, err := c.store.Get(ctx, key)
+	if err == nil {
+		var segments []Segment
+		if err := json.Unmarshal([]byte(value), &segments); err == nil {
+			return segments, nil
+		}
+	} else if !errors.Is(err, yt.ErrNotCached) {
+		log.WithError(err).WithFields(log.Fields{"video_id": videoID}).Warn("Failed to read cached skip segments")
+	}
+
+	segments, err := c.provider.Segments(ctx, videoID, categories)
+	if err != nil {
+		return nil, err
+	}
+	if segments == nil {
+		segments = []Segment{} // Cached as an empty list so videos without segments aren't asked for again
+	}
+	data, err := json.Marshal(segments)
+	if err == nil {
+		err = c.store.Set(ctx, key, data, c.ttl)
+	}
+	if err != nil {
+		log.WithError(err).WithFields(log.Fields{"video_id": videoID}).Warn("Failed to cache skip segments")
+	}
+	return segments, nil
+}
+
+// Lookup returns the segments of a YouTube video to skip in order, none when skipping is disabled or they can't be loaded
+func Lookup(ctx context.Context, videoID string) []Segment {
+	if !viper.GetBool("segments.enabled") {
+		return nil
+	}
+	categories := viper.GetStringSlice("segments.categories")
+	if len(categories) == 0 {
+		return nil
+	}
+
+	segments, err := currentProvider().Segments(ctx, videoID, categories)
+	if err != nil {
+		log.WithError(err).WithFields(log.Fields{"video_id": videoID}).Warn("Failed to load skip segments")
+		return nil
+	}
+	// Overlapping segments are merged and those shorter than segments.min_length aren't worth skipping
+	return normalise(segments, time.Duration(viper.GetInt("segments.min_length"))*time.Second)
+}
+
+// normalise sorts segments, merges those which overlap and drops those shorter than minLength
+func normalise(segments []Segment, minLength time.Duration) []Segment {
+	sorted := slices.Clone(segments)
+	slices.SortFunc(sorted, func(a, b Segment) int {
+		return int(a.Start - b.Start)
+	})
+
+	var merged []Segment
+	for _, segment := range sorted {
+		if segment.End <= segment.Start {
+			continue
+		}
+		if last := len(merged) - 1; last >= 0 && segment.Start <= merged[last].End {
+			merged[last].End = max(merged[last].End, segment.End)
+			continue
+		}
+		merged = append(merged, segment)
+	}
+
+	var kept []Segment
+	for _, segment := range merged {
+		if segment.End-segment.Start >= minLength {
+			kept = append(kept, segment)
+		}
+	}
+	return kept
+}
+
+// At returns the segment position falls within, false when it is within none
+func At(segments []Segment, position time.Duration) (Segment, bool) {
+	for _, segment := range segments {
+		if position >= segment.Start && position < segment.End {
+			return segment, true
+		}
+	}
+	return Segment{}, false
+}
+
+// Next returns the first segment starting after position, false when none is left
+func Next(segments []Segment, position time.Duration) (Segment, bool) {
+	for _, segment := range segments {
+		if segment.Start > position {
+			return segment, true
+		}
+	}
+	return Segment{}, false
+}
+
+// Describe names the category of a segment for notices
+func (s Segment) Describe() string {
+	switch s.Category {
+	case "sponsor":
+		return "sponsor"
+	case "selfpromo":
+		return "self promotion"
+	case "interaction":
+		return "subscribe reminder"
+	case "intro":
+		return "intro"
+	case "outro":
+		return "outro"
+	case "preview":
+		return "preview"
+	case "filler":
+		return "filler"
+	case "music_offtopic":
+		return "non-music section"
+	}
+	return strings.ReplaceAll(s.Category, "_", " ")
+}
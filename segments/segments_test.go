@@ -0,0 +1,147 @@
+package segments_test
+
+import (
+	"Twilight/segments"
+	"Twilight/segments/segmentstest"
+	"Twilight/yt/yttest"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Strum355/log"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	log.InitSimpleLogger(&log.Config{Output: io.Discard})
+}
+
+// enable turns segment skipping on for the given categories until the test ends
+func enable(t *testing.T, categories ...string) {
+	viper.Set("segments.enabled", true)
+	viper.Set("segments.categories", categories)
+	viper.Set("segments.min_length", 2)
+	t.Cleanup(func() {
+		viper.Set("segments.enabled", nil)
+		viper.Set("segments.categories", nil)
+		viper.Set("segments.min_length", nil)
+	})
+}
+
+func TestLookup_Disabled(t *testing.T) {
+	provider := segmentstest.NewProvider().Add("abc", segments.Segment{Category: "sponsor", Start: 0, End: time.Minute})
+	segmentstest.Use(t, provider)
+
+	assert.Nil(t, segments.Lookup(context.Background(), "abc"))
+	assert.Equal(t, 0, provider.Calls("abc"), "nothing is loaded while skipping is disabled")
+}
+
+func TestLookup_MergesAndFilters(t *testing.T) {
+	enable(t, "sponsor", "intro")
+	segmentstest.Use(t, segmentstest.NewProvider().Add("abc",
+		segments.Segment{Category: "sponsor", Start: 3 * time.Minute, End: 4 * time.Minute},
+		segments.Segment{Category: "intro", Start: 0, End: 20 * time.Second},
+		segments.Segment{Category: "sponsor", Start: 10 * time.Second, End: 30 * time.Second},
+		segments.Segment{Category: "sponsor", Start: time.Minute, End: time.Minute + time.Second},
+		segments.Segment{Category: "outro", Start: 5 * time.Minute, End: 6 * time.Minute},
+	))
+
+	assert.Equal(t, []segments.Segment{
+		{Category: "intro", Start: 0, End: 30 * time.Second},
+		{Category: "sponsor", Start: 3 * time.Minute, End: 4 * time.Minute},
+	}, segments.Lookup(context.Background(), "abc"), "overlaps merge, short segments and other categories are dropped")
+}
+
+func TestLookup_FailureSkipsNothing(t *testing.T) {
+	enable(t, "sponsor")
+	segmentstest.Use(t, segmentstest.NewProvider().Fail("abc", errors.New("unreachable")))
+
+	assert.Nil(t, segments.Lookup(context.Background(), "abc"))
+}
+
+func TestCached(t *testing.T) {
+	provider := segmentstest.NewProvider().Add("abc", segments.Segment{Category: "sponsor", Start: 0, End: time.Minute})
+	store := yttest.NewStore()
+	cached := segments.Cached(provider, store, time.Hour)
+	ctx := context.Background()
+
+	for range 2 {
+		found, err := cached.Segments(ctx, "abc", []string{"sponsor"})
+		assert.NoError(t, err)
+		assert.Len(t, found, 1)
+
+		none, err := cached.Segments(ctx, "empty", []string{"sponsor"})
+		assert.NoError(t, err)
+		assert.Empty(t, none)
+	}
+	assert.Equal(t, 1, provider.Calls("abc"))
+	assert.Equal(t, 1, provider.Calls("empty"), "videos without segments are cached too")
+
+	store.Advance(2 * time.Hour)
+	_, err := cached.Segments(ctx, "abc", []string{"sponsor"})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, provider.Calls("abc"), "segments are loaded again once they expire")
+}
+
+func TestSponsorBlock_Segments(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/skipSegments", r.URL.Path)
+		assert.Equal(t, `["sponsor","intro"]`, r.URL.Query().Get("categories"))
+		switch r.URL.Query().Get("videoID") {
+		case "abc":
+			w.Write([]byte(`[
+				{"category":"sponsor","actionType":"skip","segment":[10.5,40]},
+				{"category":"intro","actionType":"mute","segment":[0,5]}
+			]`))
+		case "broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	sb := segments.NewSponsorBlock(server.URL + "/")
+	ctx := context.Background()
+	categories := []string{"sponsor", "intro"}
+
+	found, err := sb.Segments(ctx, "abc", categories)
+	assert.NoError(t, err)
+	assert.Equal(t, []segments.Segment{{Category: "sponsor", Start: 10500 * time.Millisecond, End: 40 * time.Second}}, found)
+
+	found, err = sb.Segments(ctx, "none", categories)
+	assert.NoError(t, err)
+	assert.Empty(t, found, "videos without submissions have no segments")
+
+	_, err = sb.Segments(ctx, "broken", categories)
+	assert.Error(t, err)
+}
+
+func TestAtAndNext(t *testing.T) {
+	skips := []segments.Segment{
+		{Category: "intro", Start: 0, End: 30 * time.Second},
+		{Category: "sponsor", Start: 3 * time.Minute, End: 4 * time.Minute},
+	}
+
+	skip, ok := segments.At(skips, 10*time.Second)
+	assert.True(t, ok)
+	assert.Equal(t, "intro", skip.Category)
+	_, ok = segments.At(skips, 30*time.Second)
+	assert.False(t, ok, "segments end where the music starts")
+
+	skip, ok = segments.Next(skips, time.Minute)
+	assert.True(t, ok)
+	assert.Equal(t, 3*time.Minute, skip.Start)
+	_, ok = segments.Next(skips, 3*time.Minute)
+	assert.False(t, ok)
+}
+
+func TestSegment_Describe(t *testing.T) {
+	assert.Equal(t, "non-music section", segments.Segment{Category: "music_offtopic"}.Describe())
+	assert.Equal(t, "exclusive access", segments.Segment{Category: "exclusive_access"}.Describe())
+}
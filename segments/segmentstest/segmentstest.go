@@ -0,0 +1,74 @@
+// Package segmentstest provides a fake segments provider so code skipping segments can be tested offline
+package segmentstest
+
+import (
+	"Twilight/segments"
+	"context"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// Provider is a fake segments.Provider serving fixture segments
+type Provider struct {
+	mu       sync.Mutex
+	segments map[string][]segments.Segment
+	errors   map[string]error
+	calls    map[string]int
+}
+
+// NewProvider returns a fake provider without any segments
+func NewProvider() *Provider {
+	return &Provider{
+		segments: map[string][]segments.Segment{},
+		errors:   map[string]error{},
+		calls:    map[string]int{},
+	}
+}
+
+// Use makes p the provider of the segments package until the test ends
+func Use(t testing.TB, p segments.Provider) {
+	previous := segments.SetProvider(p)
+	t.Cleanup(func() { segments.SetProvider(previous) })
+}
+
+// Add serves segments for a video
+func (p *Provider) Add(videoID string, segs ...segments.Segment) *Provider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.segments[videoID] = append(p.segments[videoID], segs...)
+	return p
+}
+
+// Fail makes loading the segments of a video fail with err
+func (p *Provider) Fail(videoID string, err error) *Provider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.errors[videoID] = err
+	return p
+}
+
+// Calls returns how many times the segments of a video were loaded
+func (p *Provider) Calls(videoID string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.calls[videoID]
+}
+
+// Segments returns the segments of a video within the given categories
+func (p *Provider) Segments(ctx context.Context, videoID string, categories []string) ([]segments.Segment, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls[videoID]++
+	if err := p.errors[videoID]; err != nil {
+		return nil, err
+	}
+
+	var matched []segments.Segment
+	for _, segment := range p.segments[videoID] {
+		if slices.Contains(categories, segment.Category) {
+			matched = append(matched, segment)
+		}
+	}
+	return matched, nil
+}
@@ -0,0 +1,80 @@
+package segments
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// SponsorBlock loads segments from a SponsorBlock compatible API
+type SponsorBlock struct {
+	apiURL string
+	client *http.Client
+}
+
+// sponsorBlockSegment is the subset of a SponsorBlock segment used to build segments
+type sponsorBlockSegment struct {
+	Category   string     `json:"category"`
+	ActionType string     `json:"actionType"`
+	Segment    [2]float64 `json:"segment"` // Start and end in seconds
+}
+
+// NewSponsorBlock returns the provider for the SponsorBlock API at apiURL
+func NewSponsorBlock(apiURL string) *SponsorBlock {
+	return &SponsorBlock{
+		apiURL: strings.TrimSuffix(apiURL, "/"),
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Segments returns the segments of a video within the given categories which are meant to be skipped
+func (sb *SponsorBlock) Segments(ctx context.Context, videoID string, categories []string) ([]Segment, error) {
+	encoded, err := json.Marshal(categories)
+	if err != nil {
+		return nil, err
+	}
+	query := url.Values{
+		"videoID":     {videoID},
+		"categories":  {string(encoded)},
+		"actionTypes": {`["skip"]`},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, sb.apiURL+"/api/skipSegments?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := sb.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil // Videos nobody submitted segments for
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("sponsorblock returned %s", resp.Status)
+	}
+
+	var raw []sponsorBlockSegment
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	var segments []Segment
+	for _, s := range raw {
+		if s.ActionType != "" && s.ActionType != "skip" {
+			continue
+		}
+		segments = append(segments, Segment{
+			Category: s.Category,
+			Start:    time.Duration(s.Segment[0] * float64(time.Second)),
+			End:      time.Duration(s.Segment[1] * float64(time.Second)),
+		})
+	}
+	return segments, nil
+}
@@ -0,0 +1,272 @@
+package source
+
+import (
+	"Twilight/redis_client"
+	"Twilight/yt"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const DirectName = "direct"
+
+var (
+	ErrNotAudio = errors.New("file has no audio stream")
+	ErrTooLarge = errors.New("file is too large")
+)
+
+// File extensions of media played straight from their URL
+var mediaExtensions = []string{".mp3", ".flac", ".wav", ".ogg", ".oga", ".opus", ".m4a", ".aac", ".wma", ".webm", ".mp4", ".mkv", ".mov"}
+
+var httpClient = newPublicClient(5 * time.Minute)
+
+// Direct plays media files linked directly or uploaded as Discord attachments
+type Direct struct{}
+
+// NewDirect returns the direct media provider
+func NewDirect() *Direct {
+	return &Direct{}
+}
+
+// ffprobeOutput is the subset of the ffprobe JSON output used to build tracks
+type ffprobeOutput struct {
+	Streams []struct {
+		CodecType string            `json:"codec_type"`
+		Tags      map[string]string `json:"tags"`
+	} `json:"streams"`
+	Format struct {
+		Duration string            `json:"duration"`
+		Tags     map[string]string `json:"tags"`
+	} `json:"format"`
+}
+
+// Name returns the name of the provider
+func (p *Direct) Name() string {
+	return DirectName
+}
+
+// Match reports whether the URL is a http(s) link to a media file
+func (p *Direct) Match(u *url.URL) bool {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+	return IsMediaFile(u.Path)
+}
+
+// Resolve downloads the file at a URL, probes it for its metadata and caches it under a hash of its content
+func (p *Direct) Resolve(ctx context.Context, rawURL string) (*Track, error) {
+	cached, err := redis_client.RDB.Get(redis_client.Ctx, "trackmeta:"+rawURL).Bytes()
+	if err == nil && len(cached) > 0 {
+		var track Track
+		if json.Unmarshal(cached, &track) == nil {
+			return &track, nil
+		}
+	}
+
+	tmp, hash, err := fetchMedia(ctx, rawURL)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp)
+
+	track, err := Probe(tmp)
+	if err != nil {
+		return nil, err
+	}
+	track.Source = DirectName
+	track.ID = hash
+	track.URL = rawURL
+	if track.Title == "" {
+		track.Title = fileTitle(rawURL)
+	}
+
+	if err := p.transcode(ctx, tmp, track); err != nil {
+		return nil, err
+	}
+
+	data, _ := json.Marshal(track)
+	redis_client.RDB.Set(redis_client.Ctx, "trackmeta:"+rawURL, data, time.Duration(viper.GetInt("cache.audio"))*time.Second)
+	return track, nil
+}
+
+// Metadata returns the metadata stored when the track was resolved, falling back to what was queued
+func (p *Direct) Metadata(ctx context.Context, t *Track) (*Track, error) {
+	cached, err := redis_client.RDB.Get(redis_client.Ctx, "trackmeta:"+t.URL).Bytes()
+	if err == nil && len(cached) > 0 {
+		var track Track
+		if json.Unmarshal(cached, &track) == nil {
+			return &track, nil
+		}
+	}
+	return t, nil
+}
+
+// Download makes sure the cached audio of a track exists, fetching the file again if it was cleaned up
+func (p *Direct) Download(ctx context.Context, t *Track) error {
+	if _, err := os.Stat(t.Filename()); err == nil {
+		return nil
+	}
+
+	tmp, hash, err := fetchMedia(ctx, t.URL)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+
+	if hash != t.ID {
+		return errors.New("file has changed since it was queued")
+	}
+	return p.transcode(ctx, tmp, t)
+}
+
+// Playlist is not supported for direct files
+func (p *Direct) Playlist(ctx context.Context, rawURL string) ([]*Track, error) {
+	return nil, ErrUnsupported
+}
+
+// transcode converts a downloaded file into the opus cache file of a track
+func (p *Direct) transcode(ctx context.Context, input string, t *Track) error {
+	return yt.DownloadOnce(ctx, t.Filename(), func(ctx context.Context, tmp string) error {
+		ctx, cancel := yt.WithDownloadTimeout(ctx)
+		defer cancel()
+		cmd := exec.CommandContext(ctx, "ffmpeg", "-y", "-v", "error", "-i", input, "-vn", "-c:a", "libopus", "-b:a", "128k", "-f", "opus", tmp)
+		stderr := &bytes.Buffer{}
+		cmd.Stderr = stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("ffmpeg failed: %s", strings.TrimSpace(stderr.String()))
+		}
+		return nil
+	})
+}
+
+// fetchMedia downloads a file to a temporary path, returning the path and the SHA-256 of its content
+func fetchMedia(ctx context.Context, rawURL string) (string, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return "", "", err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	maxSize := int64(viper.GetInt("sources.direct.max_size")) << 20
+	if maxSize > 0 && resp.ContentLength > maxSize {
+		return "", "", ErrTooLarge
+	}
+
+	// Temporary files live outside the cache directory so cache cleaning never removes them mid download
+	file, err := os.CreateTemp("", "twilight-*")
+	if err != nil {
+		return "", "", err
+	}
+	defer file.Close()
+
+	body := io.Reader(resp.Body)
+	if maxSize > 0 {
+		body = io.LimitReader(resp.Body, maxSize+1)
+	}
+	hash := sha256.New()
+	written, err := io.Copy(io.MultiWriter(file, hash), body)
+	if err == nil && maxSize > 0 && written > maxSize {
+		err = ErrTooLarge
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return "", "", err
+	}
+
+	return file.Name(), hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// IsMediaFile reports whether a path has the extension of an audio or video file
+func IsMediaFile(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	for _, mediaExt := range mediaExtensions {
+		if ext == mediaExt {
+			return true
+		}
+	}
+	return false
+}
+
+// Probe reads the duration and tags of a media file with ffprobe
+func Probe(filename string) (*Track, error) {
+	out, err := exec.Command("ffprobe", "-v", "error", "-print_format", "json", "-show_format", "-show_streams", filename).Output()
+	if err != nil {
+		return nil, fmt.Errorf("ffprobe failed: %w", err)
+	}
+	return parseProbe(out)
+}
+
+// parseProbe builds a track from ffprobe output, reading the title, artist and album from the file tags
+func parseProbe(data []byte) (*Track, error) {
+	var probe ffprobeOutput
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, err
+	}
+
+	hasAudio := false
+	tags := map[string]string{}
+	for _, stream := range probe.Streams {
+		if stream.CodecType != "audio" {
+			continue
+		}
+		hasAudio = true
+		for key, value := range stream.Tags {
+			tags[strings.ToLower(key)] = value // Ogg files keep their tags on the stream
+		}
+	}
+	if !hasAudio {
+		return nil, ErrNotAudio
+	}
+	for key, value := range probe.Format.Tags {
+		tags[strings.ToLower(key)] = value
+	}
+
+	seconds, _ := strconv.ParseFloat(probe.Format.Duration, 64)
+	author := tags["artist"]
+	if author == "" {
+		author = tags["album_artist"]
+	}
+
+	return &Track{
+		Source:   DirectName,
+		Title:    tags["title"],
+		Author:   author,
+		Album:    tags["album"],
+		Duration: time.Duration(seconds * float64(time.Second)),
+	}, nil
+}
+
+// fileTitle returns the name of the file a URL points to, used when a file has no title tag
+func fileTitle(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	name, err := url.PathUnescape(path.Base(u.Path))
+	if err != nil {
+		name = path.Base(u.Path)
+	}
+	return strings.TrimSuffix(name, path.Ext(name))
+}
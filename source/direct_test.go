@@ -0,0 +1,135 @@
+package source
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDirect_Match(t *testing.T) {
+	p := NewDirect()
+	cases := map[string]bool{
+		"https://example.com/music/song.mp3":                          true,
+		"http://example.com/song.FLAC":                                true,
+		"https://cdn.discordapp.com/attachments/1/2/track.ogg?ex=abc": true,
+		"https://example.com/page.html":                               false,
+		"https://example.com/song":                                    false,
+		"ftp://example.com/song.mp3":                                  false,
+	}
+	for rawURL, expected := range cases {
+		u, err := url.Parse(rawURL)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, p.Match(u), rawURL)
+	}
+}
+
+func TestForURL_DirectAfterSites(t *testing.T) {
+	viper.Set("sources.hosts", []string{"vimeo.com"})
+	t.Cleanup(func() { viper.Set("sources.hosts", nil) })
+
+	p, err := ForURL("https://example.com/song.mp3")
+	assert.NoError(t, err)
+	assert.Equal(t, DirectName, p.Name())
+
+	p, err = ForURL("https://vimeo.com/clip.mp4")
+	assert.NoError(t, err)
+	assert.Equal(t, YtDlpName, p.Name())
+}
+
+func TestParseProbe(t *testing.T) {
+	data := []byte(`{
+		"streams": [{"codec_type": "video"}, {"codec_type": "audio", "tags": {"TITLE": "Stream Title"}}],
+		"format": {"duration": "187.250000", "tags": {"ARTIST": "Artist"}}
+	}`)
+
+	track, err := parseProbe(data)
+
+	assert.NoError(t, err)
+	assert.Equal(t, DirectName, track.Source)
+	assert.Equal(t, "Stream Title", track.Title)
+	assert.Equal(t, "Artist", track.Author)
+	assert.Equal(t, 187250*time.Millisecond, track.Duration)
+}
+
+func TestParseProbe_AlbumArtist(t *testing.T) {
+	data := []byte(`{"streams": [{"codec_type": "audio"}], "format": {"duration": "1", "tags": {"album_artist": "Band"}}}`)
+
+	track, err := parseProbe(data)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Band", track.Author)
+	assert.Empty(t, track.Title)
+}
+
+func TestParseProbe_NoAudio(t *testing.T) {
+	data := []byte(`{"streams": [{"codec_type": "video"}], "format": {"duration": "10"}}`)
+
+	_, err := parseProbe(data)
+
+	assert.ErrorIs(t, err, ErrNotAudio)
+}
+
+func TestFileTitle(t *testing.T) {
+	assert.Equal(t, "My Song", fileTitle("https://cdn.discordapp.com/attachments/1/2/My%20Song.mp3?ex=1"))
+	assert.Equal(t, "track", fileTitle("https://example.com/a/track.flac"))
+}
+
+func TestFetchMedia(t *testing.T) {
+	content := "not really audio"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(content))
+	}))
+	defer server.Close()
+	allowLocal(t, server)
+
+	path, hash, err := fetchMedia(context.Background(), server.URL+"/song.mp3")
+
+	assert.NoError(t, err)
+	defer os.Remove(path)
+	sum := sha256.Sum256([]byte(content))
+	assert.Equal(t, hex.EncodeToString(sum[:]), hash)
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, content, string(data))
+}
+
+func TestFetchMedia_TooLarge(t *testing.T) {
+	viper.Set("sources.direct.max_size", 1)
+	t.Cleanup(func() { viper.Set("sources.direct.max_size", nil) })
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Flusher).Flush() // Stream without a content length so the limit is enforced while reading
+		w.Write([]byte(strings.Repeat("a", 2<<20)))
+	}))
+	defer server.Close()
+	allowLocal(t, server)
+
+	_, _, err := fetchMedia(context.Background(), server.URL+"/song.mp3")
+
+	assert.ErrorIs(t, err, ErrTooLarge)
+}
+
+func TestFetchMedia_BadStatus(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	allowLocal(t, server)
+
+	_, _, err := fetchMedia(context.Background(), server.URL+"/song.mp3")
+
+	assert.Error(t, err)
+}
+
+func TestTrack_CacheKeyDirect(t *testing.T) {
+	track := &Track{Source: DirectName, ID: "abc123"}
+	assert.Equal(t, "cache/direct_abc123.opus", track.Filename())
+}
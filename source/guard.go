@@ -0,0 +1,68 @@
+package source
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// ErrPrivateAddress is returned when a link points at an address which isn't on the public internet
+var ErrPrivateAddress = errors.New("address is not public")
+
+// allowPrivate lets tests reach servers on loopback
+var allowPrivate = false
+
+// sharedAddressSpace is the carrier grade NAT range, which netip doesn't count as private
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// publicOnly is a dialer control hook refusing connections to loopback, private, link local and other non public
+// addresses, so links users paste can't reach the network the bot runs within
+func publicOnly(network, address string, _ syscall.RawConn) error {
+	if allowPrivate {
+		return nil
+	}
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !isPublic(addrPort.Addr()) {
+		return ErrPrivateAddress
+	}
+	return nil
+}
+
+// isPublic reports whether an address is reachable on the public internet
+func isPublic(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() && !addr.IsPrivate() && !sharedAddressSpace.Contains(addr)
+}
+
+// newPublicClient returns an HTTP client which only connects to public addresses, checked after DNS resolution
+func newPublicClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second, Control: publicOnly}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil // A proxy would connect on the bot's behalf, past the check
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// radioPort reports whether a link uses http(s) on one of the ports radio detection is allowed to try
+func radioPort(u *url.URL) bool {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+	port := u.Port()
+	if port == "" {
+		return true
+	}
+	n, err := strconv.Atoi(port)
+	return err == nil && slices.Contains(viper.GetIntSlice("sources.radio.ports"), n)
+}
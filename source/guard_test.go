@@ -0,0 +1,85 @@
+package source
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// allowLocal lets the package reach a test server on loopback, including radio detection on its port
+func allowLocal(t *testing.T, server *httptest.Server) {
+	allowPrivate = true
+	u, _ := url.Parse(server.URL)
+	port, _ := strconv.Atoi(u.Port())
+	viper.Set("sources.radio.ports", []int{80, 443, port})
+	t.Cleanup(func() {
+		allowPrivate = false
+		viper.Set("sources.radio.ports", nil)
+	})
+}
+
+func TestIsPublic(t *testing.T) {
+	for address, public := range map[string]bool{
+		"8.8.8.8":          true,
+		"2606:4700::1111":  true,
+		"127.0.0.1":        false,
+		"10.0.0.5":         false,
+		"172.16.3.4":       false,
+		"192.168.1.1":      false,
+		"169.254.169.254":  false,
+		"100.64.0.1":       false,
+		"0.0.0.0":          false,
+		"::1":              false,
+		"fd00::1":          false,
+		"fe80::1":          false,
+		"::ffff:127.0.0.1": false,
+	} {
+		assert.Equal(t, public, isPublic(netip.MustParseAddr(address)), address)
+	}
+}
+
+func TestFetchMedia_RefusesPrivateAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("the request reached a server on loopback")
+	}))
+	defer server.Close()
+
+	_, _, err := fetchMedia(context.Background(), server.URL+"/song.mp3")
+
+	assert.ErrorIs(t, err, ErrPrivateAddress)
+}
+
+func TestOpenRadio_RefusesPrivateAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("the request reached a server on loopback")
+	}))
+	defer server.Close()
+
+	_, err := OpenRadio(context.Background(), server.URL+"/live")
+
+	assert.ErrorIs(t, err, ErrPrivateAddress)
+}
+
+func TestRadioPort(t *testing.T) {
+	viper.Set("sources.radio.ports", []int{80, 443})
+	t.Cleanup(func() { viper.Set("sources.radio.ports", nil) })
+
+	for rawURL, allowed := range map[string]bool{
+		"http://radio.example.com/live":      true,
+		"https://radio.example.com:443/live": true,
+		"http://radio.example.com:8000/live": false,
+		"http://radio.example.com:6379/":     false,
+		"gopher://radio.example.com/live":    false,
+	} {
+		u, err := url.Parse(rawURL)
+		assert.NoError(t, err)
+		assert.Equal(t, allowed, radioPort(u), rawURL)
+	}
+}
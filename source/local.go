@@ -0,0 +1,70 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const LocalName = "local"
+
+var ErrMissingFile = errors.New("file no longer exists")
+
+// Local plays files on the host, it never matches links so only files picked by the bot itself are played
+type Local struct{}
+
+// NewLocal returns the local file provider
+func NewLocal() *Local {
+	return &Local{}
+}
+
+// Name returns the name of the provider
+func (p *Local) Name() string {
+	return LocalName
+}
+
+// Match never matches, links must not be able to reach files on the host
+func (p *Local) Match(u *url.URL) bool {
+	return false
+}
+
+// Resolve probes a file on the host for its metadata
+func (p *Local) Resolve(ctx context.Context, filename string) (*Track, error) {
+	track, err := Probe(filename)
+	if err != nil {
+		return nil, err
+	}
+	track.Source = LocalName
+	track.ID = filename
+	track.URL = filename
+	if track.Title == "" {
+		base := filepath.Base(filename)
+		track.Title = strings.TrimSuffix(base, filepath.Ext(base))
+	}
+	return track, nil
+}
+
+// Metadata probes the file of a track, falling back to what was queued when it can't be read
+func (p *Local) Metadata(ctx context.Context, t *Track) (*Track, error) {
+	track, err := p.Resolve(ctx, t.URL)
+	if err != nil {
+		return t, nil
+	}
+	return track, nil
+}
+
+// Download checks the file is still there, local files are played in place
+func (p *Local) Download(ctx context.Context, t *Track) error {
+	if _, err := os.Stat(t.URL); err != nil {
+		return ErrMissingFile
+	}
+	return nil
+}
+
+// Playlist is not supported for local files
+func (p *Local) Playlist(ctx context.Context, rawURL string) ([]*Track, error) {
+	return nil, ErrUnsupported
+}
@@ -0,0 +1,236 @@
+package source
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+const RadioName = "radio"
+
+var ErrNotStream = errors.New("url is not a live stream")
+
+// ErrHLS is returned by OpenRadio for HLS streams, which ffmpeg reads from their URL itself
+var ErrHLS = errors.New("stream is HLS")
+
+// Extensions of playlist files pointing at a radio stream
+var radioPlaylistExtensions = []string{".pls", ".m3u", ".m3u8"}
+
+var radioClient = newPublicClient(10 * time.Second)
+
+// radioStreamClient reads streams for as long as they play, so only connecting is bound by a timeout
+var radioStreamClient = newPublicClient(0)
+
+const maxPlaylistDepth = 2 // Playlist files followed before giving up on reaching a stream
+
+// Radio plays Icecast and Shoutcast radio along with HLS live streams
+type Radio struct{}
+
+// NewRadio returns the internet radio provider
+func NewRadio() *Radio {
+	return &Radio{}
+}
+
+// Name returns the name of the provider
+func (p *Radio) Name() string {
+	return RadioName
+}
+
+// Match reports whether the URL is a http(s) link to a radio playlist file, bare stream URLs are found by Detect
+func (p *Radio) Match(u *url.URL) bool {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+	ext := strings.ToLower(path.Ext(u.Path))
+	for _, playlistExt := range radioPlaylistExtensions {
+		if ext == playlistExt {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve follows radio playlist files to their stream and names the track after the station
+func (p *Radio) Resolve(ctx context.Context, rawURL string) (*Track, error) {
+	return p.resolve(ctx, rawURL, maxPlaylistDepth)
+}
+
+// resolve resolves a stream, following at most depth playlist files on the way
+func (p *Radio) resolve(ctx context.Context, rawURL string, depth int) (*Track, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Icy-MetaData", "1")
+
+	// Only the headers and the start of the body are read, the stream itself is left to ffmpeg
+	resp, err := radioClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, ErrNotStream
+	}
+
+	switch streamKind(resp) {
+	case kindPlaylist:
+		streamURL := playlistStreamURL(io.LimitReader(resp.Body, 64<<10))
+		if streamURL == "" || depth == 0 {
+			return nil, ErrNotStream
+		}
+		return p.resolve(ctx, streamURL, depth-1)
+	case kindNone:
+		return nil, ErrNotStream
+	}
+
+	title := resp.Header.Get("Icy-Name")
+	if title == "" {
+		title = hostTitle(rawURL)
+	}
+	return &Track{
+		Source: RadioName,
+		ID:     rawURL,
+		URL:    rawURL,
+		Title:  title,
+		Author: resp.Header.Get("Icy-Description"),
+		Live:   true,
+	}, nil
+}
+
+// Metadata returns the track as it was resolved, radio metadata comes from the stream while it plays
+func (p *Radio) Metadata(ctx context.Context, t *Track) (*Track, error) {
+	track := *t
+	track.Live = true
+	return &track, nil
+}
+
+// Download always fails as radio is streamed rather than cached
+func (p *Radio) Download(ctx context.Context, t *Track) error {
+	return ErrLive
+}
+
+// StreamURL returns the stream the track was resolved to
+func (p *Radio) StreamURL(ctx context.Context, t *Track) (string, error) {
+	return t.URL, nil
+}
+
+// OpenRadio connects to an Icecast or Shoutcast stream asking for its ICY titles, returning ErrHLS for HLS streams
+func OpenRadio(ctx context.Context, streamURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, streamURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Icy-MetaData", "1")
+
+	resp, err := radioStreamClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, errors.New("unexpected status " + resp.Status)
+	}
+	if streamKind(resp) == kindHLS {
+		resp.Body.Close()
+		return nil, ErrHLS
+	}
+	return resp, nil
+}
+
+// Playlist is not supported for radio
+func (p *Radio) Playlist(ctx context.Context, rawURL string) ([]*Track, error) {
+	return nil, ErrUnsupported
+}
+
+// Detect returns the provider for a URL like ForURL, checking whether links to unknown sites or media files are radio streams
+func Detect(ctx context.Context, rawURL string) (Provider, error) {
+	p, err := ForURL(rawURL)
+	if err == nil && p.Name() != DirectName {
+		return p, nil
+	}
+
+	// Only plain web ports are probed so pasted links can't be used to scan other services
+	if u, parseErr := url.Parse(rawURL); parseErr != nil || !radioPort(u) {
+		return p, err
+	}
+	radio, _ := Get(RadioName)
+	if _, radioErr := radio.Resolve(ctx, rawURL); radioErr == nil {
+		return radio, nil
+	}
+	return p, err
+}
+
+type kind int
+
+const (
+	kindNone kind = iota
+	kindStream
+	kindHLS
+	kindPlaylist
+)
+
+// streamKind works out from the response headers whether a URL is a live stream, a playlist file or neither
+func streamKind(resp *http.Response) kind {
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/vnd.apple.mpegurl", "application/x-mpegurl":
+		return kindHLS
+	case "audio/x-scpls", "audio/x-mpegurl", "audio/mpegurl":
+		return kindPlaylist
+	}
+
+	for _, header := range []string{"Icy-Name", "Icy-Metaint", "Icy-Br", "Icy-Genre"} {
+		if resp.Header.Get(header) != "" {
+			return kindStream
+		}
+	}
+
+	// Files have a known length, streams never end
+	if (strings.HasPrefix(mediaType, "audio/") || mediaType == "application/ogg") && resp.ContentLength < 0 {
+		return kindStream
+	}
+
+	switch strings.ToLower(path.Ext(resp.Request.URL.Path)) {
+	case ".m3u8":
+		return kindHLS
+	case ".pls", ".m3u":
+		return kindPlaylist
+	}
+	return kindNone
+}
+
+// playlistStreamURL returns the first stream within a PLS or M3U playlist
+func playlistStreamURL(r io.Reader) string {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		// PLS entries look like File1=http://...
+		if key, value, ok := strings.Cut(line, "="); ok && strings.HasPrefix(strings.ToLower(key), "file") {
+			line = strings.TrimSpace(value)
+		}
+		if strings.HasPrefix(line, "http://") || strings.HasPrefix(line, "https://") {
+			return line
+		}
+	}
+	return ""
+}
+
+// hostTitle names a stream after its host when it has no station name
+func hostTitle(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+	return u.Hostname()
+}
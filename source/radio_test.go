@@ -0,0 +1,145 @@
+package source
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// radioServer serves an Icecast style stream, an HLS stream, a PLS playlist pointing at the first and a plain web page
+func radioServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	var server *httptest.Server
+	mux.HandleFunc("/live", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/mpeg")
+		w.Header().Set("Icy-Name", "Test FM")
+		w.Header().Set("Icy-Description", "All tests, all day")
+		if r.Header.Get("Icy-MetaData") == "1" {
+			w.Header().Set("Icy-Metaint", "8192")
+		}
+		w.Write([]byte("audio"))
+	})
+	mux.HandleFunc("/hls", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+		w.Write([]byte("#EXTM3U\n"))
+	})
+	mux.HandleFunc("/listen.pls", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/x-scpls")
+		w.Write([]byte("[playlist]\nNumberOfEntries=1\nFile1=" + server.URL + "/live\nTitle1=Test FM\n"))
+	})
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html></html>"))
+	})
+	server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	allowLocal(t, server)
+	return server
+}
+
+func TestRadio_Match(t *testing.T) {
+	p := NewRadio()
+	for rawURL, expected := range map[string]bool{
+		"http://radio.example.com/listen.pls": true,
+		"https://example.com/live.m3u8":       true,
+		"https://example.com/stream":          false,
+		"ftp://example.com/listen.pls":        false,
+	} {
+		u, err := url.Parse(rawURL)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, p.Match(u), rawURL)
+	}
+}
+
+func TestRadio_ResolveStream(t *testing.T) {
+	server := radioServer(t)
+
+	track, err := NewRadio().Resolve(context.Background(), server.URL+"/live")
+
+	assert.NoError(t, err)
+	assert.True(t, track.Live)
+	assert.Equal(t, RadioName, track.Source)
+	assert.Equal(t, "Test FM", track.Title)
+	assert.Equal(t, "All tests, all day", track.Author)
+	assert.Equal(t, server.URL+"/live", track.URL)
+}
+
+func TestRadio_ResolvePlaylist(t *testing.T) {
+	server := radioServer(t)
+
+	track, err := NewRadio().Resolve(context.Background(), server.URL+"/listen.pls")
+
+	assert.NoError(t, err)
+	assert.Equal(t, server.URL+"/live", track.URL)
+	assert.Equal(t, "Test FM", track.Title)
+}
+
+func TestRadio_ResolveNotStream(t *testing.T) {
+	server := radioServer(t)
+
+	_, err := NewRadio().Resolve(context.Background(), server.URL+"/page")
+
+	assert.ErrorIs(t, err, ErrNotStream)
+}
+
+func TestOpenRadio(t *testing.T) {
+	server := radioServer(t)
+
+	resp, err := OpenRadio(context.Background(), server.URL+"/live")
+
+	if assert.NoError(t, err) {
+		defer resp.Body.Close()
+		assert.Equal(t, "8192", resp.Header.Get("Icy-Metaint"), "ICY titles are asked for")
+	}
+}
+
+func TestOpenRadio_HLS(t *testing.T) {
+	server := radioServer(t)
+
+	_, err := OpenRadio(context.Background(), server.URL+"/hls")
+
+	assert.ErrorIs(t, err, ErrHLS)
+}
+
+func TestRadio_Download(t *testing.T) {
+	assert.ErrorIs(t, NewRadio().Download(context.Background(), &Track{Source: RadioName}), ErrLive)
+}
+
+func TestDetect(t *testing.T) {
+	server := radioServer(t)
+
+	p, err := Detect(context.Background(), server.URL+"/live")
+	assert.NoError(t, err)
+	assert.Equal(t, RadioName, p.Name())
+
+	_, err = Detect(context.Background(), server.URL+"/page")
+	assert.ErrorIs(t, err, ErrUnsupported)
+
+	p, err = Detect(context.Background(), "https://www.youtube.com/watch?v=dQw4w9WgXcQ")
+	assert.NoError(t, err)
+	assert.Equal(t, YouTubeName, p.Name())
+}
+
+func TestPlaylistStreamURL(t *testing.T) {
+	pls := "[playlist]\nFile1=http://radio.example.com:8000/stream\nTitle1=Radio\n"
+	assert.Equal(t, "http://radio.example.com:8000/stream", playlistStreamURL(strings.NewReader(pls)))
+
+	m3u := "#EXTM3U\n#EXTINF:-1,Radio\nhttps://radio.example.com/live.mp3\n"
+	assert.Equal(t, "https://radio.example.com/live.mp3", playlistStreamURL(strings.NewReader(m3u)))
+
+	assert.Empty(t, playlistStreamURL(strings.NewReader("#EXTM3U\n")))
+}
+
+func TestDetect_SkipsOtherPorts(t *testing.T) {
+	server := radioServer(t)
+	viper.Set("sources.radio.ports", []int{80, 443})
+
+	_, err := Detect(context.Background(), server.URL+"/live")
+	assert.ErrorIs(t, err, ErrUnsupported, "streams on ports which aren't allowed aren't probed")
+}
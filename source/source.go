@@ -0,0 +1,141 @@
+package source
+
+import (
+	"Twilight/utils"
+	"Twilight/yt"
+	"context"
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrUnsupported     = errors.New("unsupported source url")
+	ErrUnknownProvider = errors.New("unknown source provider")
+	ErrLive            = errors.New("live tracks are streamed rather than downloaded")
+)
+
+// Provider is a site audio can be played from
+type Provider interface {
+	Name() string                                                  // Unique name of the provider, stored with queued tracks
+	Match(u *url.URL) bool                                         // Reports whether the provider handles the URL
+	Resolve(ctx context.Context, rawURL string) (*Track, error)    // Resolves a URL into a single track along with its metadata
+	Metadata(ctx context.Context, t *Track) (*Track, error)        // Fetches the metadata of a previously resolved track
+	Download(ctx context.Context, t *Track) error                  // Downloads the audio of a track to its cache file
+	Playlist(ctx context.Context, rawURL string) ([]*Track, error) // Expands a playlist URL into its tracks, which may only hold an ID and URL
+}
+
+// Streamer is implemented by providers able to play live tracks, which are streamed straight into ffmpeg without caching
+type Streamer interface {
+	StreamURL(ctx context.Context, t *Track) (string, error) // Returns the URL ffmpeg reads a live track from, resolved again on every reconnect
+}
+
+// Pager is implemented by providers able to list playlists a page at a time, so huge playlists can be expanded lazily
+type Pager interface {
+	PlaylistPage(ctx context.Context, rawURL string, start, count int) (*PlaylistPage, error)    // Lists up to count tracks from start, counting from 1
+	StreamPlaylist(ctx context.Context, rawURL string, start int, track func(*Track) bool) error // Lists the tracks from start in one pass as they are found, until track returns false
+}
+
+// PlaylistPage is a range of the tracks of a playlist along with what describes the playlist as a whole
+type PlaylistPage struct {
+	Title     string
+	Author    string
+	Thumbnail string
+	Total     int      // Tracks within the whole playlist, 0 when unknown
+	Tracks    []*Track // Tracks within the page only
+}
+
+// Track is a single playable item from a provider
+type Track struct {
+	Source    string // Name of the provider the track belongs to
+	ID        string // Identifier of the track within its provider
+	URL       string // Page the track was resolved from
+	Title     string
+	Author    string
+	Album     string // Only known for files with tags
+	Duration  time.Duration
+	Thumbnail string
+	Live      bool         // True for live streams and radio, which have no duration
+	Chapters  []yt.Chapter // Chapter markers in order, nil when the track has none
+}
+
+var unsafeKeyChars = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// CacheKey returns a name for the track which is unique across providers and safe to use as a filename
+func (t *Track) CacheKey() string {
+	if t.Source == YouTubeName {
+		return t.ID // Matches the cache files of tracks queued before providers existed
+	}
+	return t.Source + "_" + unsafeKeyChars.ReplaceAllString(t.ID, "_")
+}
+
+// Filename returns the path of the cached audio file of the track, local files are played where they are
+func (t *Track) Filename() string {
+	if t.Source == LocalName {
+		return t.URL
+	}
+	return utils.GetAudioFile(t.CacheKey())
+}
+
+var (
+	mu        sync.RWMutex
+	providers []Provider
+)
+
+func init() {
+	Register(NewYouTube())
+	Register(NewYtDlp())
+	Register(NewDirect()) // After sites so site links ending in a media extension go to their site first
+	Register(NewRadio())
+	Register(NewLocal())
+}
+
+// Register adds a provider, providers registered first are matched first
+func Register(p Provider) {
+	mu.Lock()
+	defer mu.Unlock()
+	providers = append(providers, p)
+}
+
+// ForURL returns the provider handling a URL, dispatching on its host
+func ForURL(rawURL string) (Provider, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || u.Host == "" {
+		return nil, ErrUnsupported
+	}
+
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, p := range providers {
+		if p.Match(u) {
+			return p, nil
+		}
+	}
+	return nil, ErrUnsupported
+}
+
+// Get returns the provider with the given name, an empty name is YouTube for tracks queued before providers existed
+func Get(name string) (Provider, error) {
+	if name == "" {
+		name = YouTubeName
+	}
+
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, p := range providers {
+		if p.Name() == name {
+			return p, nil
+		}
+	}
+	return nil, ErrUnknownProvider
+}
+
+// hostMatches reports whether host is domain or one of its subdomains
+func hostMatches(host, domain string) bool {
+	host = strings.ToLower(host)
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	return domain != "" && (host == domain || strings.HasSuffix(host, "."+domain))
+}
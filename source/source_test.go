@@ -0,0 +1,112 @@
+package source
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForURL_DispatchesOnHost(t *testing.T) {
+	viper.Set("sources.hosts", []string{"soundcloud.com", "bandcamp.com"})
+	t.Cleanup(func() { viper.Set("sources.hosts", nil) })
+
+	cases := map[string]string{
+		"https://www.youtube.com/watch?v=dQw4w9WgXcQ": YouTubeName,
+		"https://youtu.be/dQw4w9WgXcQ":                YouTubeName,
+		"https://music.youtube.com/watch?v=abc":       YouTubeName,
+		"https://soundcloud.com/artist/track":         YtDlpName,
+		"https://artist.bandcamp.com/track/song":      YtDlpName,
+	}
+	for rawURL, expected := range cases {
+		p, err := ForURL(rawURL)
+		assert.NoError(t, err, rawURL)
+		assert.Equal(t, expected, p.Name(), rawURL)
+	}
+}
+
+func TestForURL_Unsupported(t *testing.T) {
+	viper.Set("sources.hosts", []string{"soundcloud.com"})
+	t.Cleanup(func() { viper.Set("sources.hosts", nil) })
+
+	for _, rawURL := range []string{"https://example.com/song", "not a url", "https://notsoundcloud.com/x"} {
+		_, err := ForURL(rawURL)
+		assert.ErrorIs(t, err, ErrUnsupported, rawURL)
+	}
+}
+
+func TestGet(t *testing.T) {
+	p, err := Get("")
+	assert.NoError(t, err)
+	assert.Equal(t, YouTubeName, p.Name())
+
+	p, err = Get(YtDlpName)
+	assert.NoError(t, err)
+	assert.Equal(t, YtDlpName, p.Name())
+
+	_, err = Get("missing")
+	assert.ErrorIs(t, err, ErrUnknownProvider)
+}
+
+func TestTrack_CacheKey(t *testing.T) {
+	youTube := &Track{Source: YouTubeName, ID: "dQw4w9WgXcQ"}
+	assert.Equal(t, "dQw4w9WgXcQ", youTube.CacheKey())
+	assert.Equal(t, "cache/dQw4w9WgXcQ.opus", youTube.Filename())
+
+	other := &Track{Source: YtDlpName, ID: "soundcloud-123/../456"}
+	assert.Equal(t, "ytdlp_soundcloud-123_456", other.CacheKey())
+}
+
+func TestParseYtDlpTrack(t *testing.T) {
+	data := []byte(`{
+		"id": "123456",
+		"title": "A Song",
+		"uploader": "Uploader",
+		"artist": "Artist",
+		"duration": 215.5,
+		"thumbnail": "https://img/1.jpg",
+		"webpage_url": "https://soundcloud.com/artist/a-song",
+		"extractor_key": "Soundcloud"
+	}`)
+
+	track, err := parseYtDlpTrack(data)
+
+	assert.NoError(t, err)
+	assert.Equal(t, YtDlpName, track.Source)
+	assert.Equal(t, "soundcloud-123456", track.ID)
+	assert.Equal(t, "A Song", track.Title)
+	assert.Equal(t, "Artist", track.Author)
+	assert.Equal(t, 215500*time.Millisecond, track.Duration)
+	assert.Equal(t, "https://soundcloud.com/artist/a-song", track.URL)
+}
+
+func TestParseYtDlpTrack_MissingID(t *testing.T) {
+	_, err := parseYtDlpTrack([]byte(`{"title": "No id"}`))
+	assert.Error(t, err)
+}
+
+func TestParseYtDlpPlaylist(t *testing.T) {
+	out := []byte(`{"id": "1", "title": "One", "url": "https://artist.bandcamp.com/track/one", "ie_key": "Bandcamp"}
+not json
+{"id": "2", "title": "Two", "url": "https://artist.bandcamp.com/track/two", "ie_key": "Bandcamp", "uploader": "Artist"}
+
+`)
+
+	tracks := parseYtDlpPlaylist(out)
+
+	assert.Len(t, tracks, 2)
+	assert.Equal(t, "bandcamp-1", tracks[0].ID)
+	assert.Equal(t, "https://artist.bandcamp.com/track/one", tracks[0].URL)
+	assert.Equal(t, "Artist", tracks[1].Author)
+}
+
+func TestLocal(t *testing.T) {
+	track := &Track{Source: LocalName, ID: "/srv/music/song.mp3", URL: "/srv/music/song.mp3"}
+	assert.Equal(t, "/srv/music/song.mp3", track.Filename())
+	assert.ErrorIs(t, NewLocal().Download(context.Background(), &Track{Source: LocalName, URL: t.TempDir() + "/missing.mp3"}), ErrMissingFile)
+
+	_, err := ForURL("file:///etc/passwd")
+	assert.ErrorIs(t, err, ErrUnsupported)
+}
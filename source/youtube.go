@@ -0,0 +1,138 @@
+package source
+
+import (
+	"Twilight/redis_client"
+	"Twilight/yt"
+	"context"
+	"net/url"
+
+	"github.com/kkdai/youtube/v2"
+)
+
+const YouTubeName = "youtube"
+
+var youTubeHosts = []string{"youtube.com", "youtu.be", "youtube-nocookie.com"}
+
+// YouTube plays videos using the yt package
+type YouTube struct{}
+
+// NewYouTube returns the YouTube provider
+func NewYouTube() *YouTube {
+	return &YouTube{}
+}
+
+// Name returns the name of the provider
+func (p *YouTube) Name() string {
+	return YouTubeName
+}
+
+// Match reports whether the URL is a YouTube link
+func (p *YouTube) Match(u *url.URL) bool {
+	for _, host := range youTubeHosts {
+		if hostMatches(u.Hostname(), host) {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve returns the video of a YouTube URL with its metadata
+func (p *YouTube) Resolve(ctx context.Context, rawURL string) (*Track, error) {
+	videoID, err := youtube.ExtractVideoID(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return p.Metadata(ctx, &Track{Source: YouTubeName, ID: videoID})
+}
+
+// Metadata fetches the metadata of a YouTube video, cached within Redis
+func (p *YouTube) Metadata(ctx context.Context, t *Track) (*Track, error) {
+	video, err := yt.NewYouTubeManager(redis_client.RDB).GetVideoMetadata(ctx, t.ID)
+	if err != nil {
+		return nil, err
+	}
+	track := videoTrack(video)
+	track.ID = t.ID
+	track.URL = "https://www.youtube.com/watch?v=" + t.ID
+	return track, nil
+}
+
+// Download downloads the audio of a YouTube video to the cache
+func (p *YouTube) Download(ctx context.Context, t *Track) error {
+	if t.Live {
+		return ErrLive
+	}
+	return yt.NewYouTubeManager(redis_client.RDB).DownloadAudio(ctx, t.ID)
+}
+
+// StreamURL returns the manifest URL of a live YouTube stream
+func (p *YouTube) StreamURL(ctx context.Context, t *Track) (string, error) {
+	return yt.LiveStreamURL(ctx, t.ID)
+}
+
+// Playlist returns the videos of a YouTube playlist
+func (p *YouTube) Playlist(ctx context.Context, rawURL string) ([]*Track, error) {
+	playlist, err := yt.NewYouTubeManager(redis_client.RDB).GetPlaylist(ctx, rawURL, 1, 0)
+	if err != nil {
+		return nil, err
+	}
+	return playlistTracks(playlist), nil
+}
+
+// PlaylistPage returns up to count videos of a YouTube playlist from start along with its details
+func (p *YouTube) PlaylistPage(ctx context.Context, rawURL string, start, count int) (*PlaylistPage, error) {
+	start = max(start, 1)
+	playlist, err := yt.NewYouTubeManager(redis_client.RDB).GetPlaylist(ctx, rawURL, start, start+max(count, 1)-1)
+	if err != nil {
+		return nil, err
+	}
+	return &PlaylistPage{
+		Title:     playlist.Title,
+		Author:    playlist.Uploader,
+		Thumbnail: playlist.Thumbnail,
+		Total:     playlist.Count,
+		Tracks:    playlistTracks(playlist),
+	}, nil
+}
+
+// StreamPlaylist calls track with each video of a YouTube playlist from start as it is listed, until track returns false
+func (p *YouTube) StreamPlaylist(ctx context.Context, rawURL string, start int, track func(*Track) bool) error {
+	return yt.NewYouTubeManager(redis_client.RDB).StreamPlaylist(ctx, rawURL, start, func(entry yt.PlaylistEntry) bool {
+		return track(entryTrack(entry))
+	})
+}
+
+// playlistTracks converts the entries of a YouTube playlist into tracks
+func playlistTracks(playlist *yt.Playlist) []*Track {
+	tracks := make([]*Track, 0, len(playlist.Entries))
+	for _, entry := range playlist.Entries {
+		tracks = append(tracks, entryTrack(entry))
+	}
+	return tracks
+}
+
+// entryTrack converts an entry of a YouTube playlist into a track
+func entryTrack(entry yt.PlaylistEntry) *Track {
+	return &Track{
+		Source:   YouTubeName,
+		ID:       entry.ID,
+		URL:      "https://www.youtube.com/watch?v=" + entry.ID,
+		Title:    entry.Title,
+		Duration: entry.Duration,
+	}
+}
+
+// videoTrack converts YouTube video metadata into a track
+func videoTrack(video *yt.Video) *Track {
+	return &Track{
+		Source:    YouTubeName,
+		ID:        video.ID,
+		URL:       "https://www.youtube.com/watch?v=" + video.ID,
+		Title:     video.Title,
+		Author:    video.Author,
+		Duration:  video.Duration,
+		Thumbnail: video.Thumbnail,
+		Live:      video.Live,
+		Chapters:  video.Chapters,
+	}
+}
@@ -0,0 +1,52 @@
+package source
+
+import (
+	"Twilight/yt"
+	"Twilight/yt/yttest"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/Strum355/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestYouTube_PlaylistPage(t *testing.T) {
+	log.InitSimpleLogger(&log.Config{Output: io.Discard})
+	backend := yttest.NewBackend().
+		AddVideo(&yt.Video{ID: "a", Title: "First", Duration: time.Minute}).
+		AddVideo(&yt.Video{ID: "b", Title: "Second", Duration: 2 * time.Minute}).
+		AddPlaylist("https://www.youtube.com/playlist?list=PL1", "Mix", "a", "b", "c")
+	yttest.Use(t, backend)
+
+	page, err := NewYouTube().PlaylistPage(context.Background(), "https://www.youtube.com/playlist?list=PL1", 2, 5)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Mix", page.Title)
+	assert.Equal(t, 3, page.Total)
+	if assert.Len(t, page.Tracks, 2) {
+		assert.Equal(t, &Track{Source: YouTubeName, ID: "b", URL: "https://www.youtube.com/watch?v=b", Title: "Second", Duration: 2 * time.Minute}, page.Tracks[0])
+		assert.Equal(t, "c", page.Tracks[1].ID)
+	}
+}
+
+func TestYouTube_StreamPlaylist(t *testing.T) {
+	log.InitSimpleLogger(&log.Config{Output: io.Discard})
+	backend := yttest.NewBackend().
+		AddVideo(&yt.Video{ID: "b", Title: "Second", Duration: 2 * time.Minute}).
+		AddPlaylist("https://www.youtube.com/playlist?list=PL1", "Mix", "a", "b", "c")
+	yttest.Use(t, backend)
+
+	var tracks []*Track
+	err := NewYouTube().StreamPlaylist(context.Background(), "https://www.youtube.com/playlist?list=PL1", 2, func(track *Track) bool {
+		tracks = append(tracks, track)
+		return true
+	})
+
+	assert.NoError(t, err)
+	if assert.Len(t, tracks, 2) {
+		assert.Equal(t, &Track{Source: YouTubeName, ID: "b", URL: "https://www.youtube.com/watch?v=b", Title: "Second", Duration: 2 * time.Minute}, tracks[0])
+		assert.Equal(t, "c", tracks[1].ID)
+	}
+}
@@ -0,0 +1,204 @@
+package source
+
+import (
+	"Twilight/redis_client"
+	"Twilight/yt"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const YtDlpName = "ytdlp"
+
+// YtDlp plays tracks from any site supported by yt-dlp which is listed within sources.hosts
+type YtDlp struct{}
+
+// NewYtDlp returns the generic yt-dlp provider
+func NewYtDlp() *YtDlp {
+	return &YtDlp{}
+}
+
+// ytDlpInfo is the subset of the yt-dlp JSON output used to build tracks
+type ytDlpInfo struct {
+	ID           string            `json:"id"`
+	Title        string            `json:"title"`
+	Uploader     string            `json:"uploader"`
+	Artist       string            `json:"artist"`
+	Duration     float64           `json:"duration"`
+	Thumbnail    string            `json:"thumbnail"`
+	URL          string            `json:"url"`
+	WebpageURL   string            `json:"webpage_url"`
+	ExtractorKey string            `json:"extractor_key"`
+	IEKey        string            `json:"ie_key"`
+	IsLive       bool              `json:"is_live"`
+	Chapters     []yt.YtDlpChapter `json:"chapters"`
+}
+
+// Name returns the name of the provider
+func (p *YtDlp) Name() string {
+	return YtDlpName
+}
+
+// Match reports whether the URL belongs to one of the configured hosts
+func (p *YtDlp) Match(u *url.URL) bool {
+	for _, host := range viper.GetStringSlice("sources.hosts") {
+		if hostMatches(u.Hostname(), host) {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve returns the track at a URL with its metadata
+func (p *YtDlp) Resolve(ctx context.Context, rawURL string) (*Track, error) {
+	cached, err := redis_client.RDB.Get(redis_client.Ctx, "trackmeta:"+rawURL).Bytes()
+	if err == nil && len(cached) > 0 {
+		var track Track
+		if json.Unmarshal(cached, &track) == nil {
+			return &track, nil
+		}
+	}
+
+	out, err := runYtDlp(ctx, "-J", "--no-playlist", rawURL)
+	if err != nil {
+		return nil, err
+	}
+	track, err := parseYtDlpTrack(out)
+	if err != nil {
+		return nil, err
+	}
+	if track.URL == "" {
+		track.URL = rawURL
+	}
+
+	data, _ := json.Marshal(track)
+	redis_client.RDB.Set(redis_client.Ctx, "trackmeta:"+rawURL, data, time.Duration(viper.GetInt("cache.youtube"))*time.Second)
+	return track, nil
+}
+
+// Metadata fetches the metadata of a track from its URL
+func (p *YtDlp) Metadata(ctx context.Context, t *Track) (*Track, error) {
+	return p.Resolve(ctx, t.URL)
+}
+
+// Download downloads the audio of a track to the cache as opus
+func (p *YtDlp) Download(ctx context.Context, t *Track) error {
+	if t.Live {
+		return ErrLive
+	}
+	return yt.DownloadOnce(ctx, t.Filename(), func(ctx context.Context, tmp string) error {
+		ctx, cancel := yt.WithDownloadTimeout(ctx)
+		defer cancel()
+		_, err := runYtDlp(ctx,
+			"-f", "bestaudio/best",
+			"-x",
+			"--audio-format", "opus",
+			"--no-playlist",
+			"-o", tmp,
+			t.URL,
+		)
+		return err
+	})
+}
+
+// StreamURL returns the URL of the best audio of a live track
+func (p *YtDlp) StreamURL(ctx context.Context, t *Track) (string, error) {
+	out, err := runYtDlp(ctx, "-g", "-f", "bestaudio/best", "--no-playlist", t.URL)
+	if err != nil {
+		return "", err
+	}
+	lines := strings.Fields(string(out))
+	if len(lines) == 0 {
+		return "", errors.New("yt-dlp returned no stream url")
+	}
+	return lines[0], nil
+}
+
+// Playlist returns the tracks of a playlist, set or album
+func (p *YtDlp) Playlist(ctx context.Context, rawURL string) ([]*Track, error) {
+	out, err := runYtDlp(ctx, "-j", "--flat-playlist", rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return parseYtDlpPlaylist(out), nil
+}
+
+// runYtDlp runs yt-dlp with the given arguments, returning its output or its classified error output as the error
+func runYtDlp(ctx context.Context, args ...string) ([]byte, error) {
+	// Callers without a deadline of their own are bound by the metadata timeout
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = yt.WithMetadataTimeout(ctx)
+		defer cancel()
+	}
+
+	return yt.RunYtDlp(ctx, args...)
+}
+
+// parseYtDlpTrack builds a track from the JSON yt-dlp prints for a single item
+func parseYtDlpTrack(data []byte) (*Track, error) {
+	var info ytDlpInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, err
+	}
+	if info.ID == "" {
+		return nil, errors.New("yt-dlp returned no id")
+	}
+	return info.track(), nil
+}
+
+// parseYtDlpPlaylist builds tracks from the JSON lines yt-dlp prints for a flat playlist
+func parseYtDlpPlaylist(out []byte) []*Track {
+	var tracks []*Track
+	for _, line := range bytes.Split(out, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var info ytDlpInfo
+		if err := json.Unmarshal(line, &info); err != nil || info.ID == "" {
+			continue
+		}
+		tracks = append(tracks, info.track())
+	}
+	return tracks
+}
+
+// track converts yt-dlp output into a track, prefixing the ID with the extractor so IDs from different sites never clash
+func (info ytDlpInfo) track() *Track {
+	extractor := info.ExtractorKey
+	if extractor == "" {
+		extractor = info.IEKey
+	}
+	id := info.ID
+	if extractor != "" {
+		id = strings.ToLower(extractor) + "-" + info.ID
+	}
+
+	author := info.Artist
+	if author == "" {
+		author = info.Uploader
+	}
+
+	pageURL := info.WebpageURL
+	if pageURL == "" {
+		pageURL = info.URL // Flat playlist entries only carry url
+	}
+
+	return &Track{
+		Source:    YtDlpName,
+		ID:        id,
+		URL:       pageURL,
+		Title:     info.Title,
+		Author:    author,
+		Duration:  time.Duration(info.Duration * float64(time.Second)),
+		Thumbnail: info.Thumbnail,
+		Live:      info.IsLive,
+		Chapters:  yt.ChaptersFromYtDlp(info.Chapters),
+	}
+}
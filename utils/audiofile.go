@@ -5,6 +5,9 @@ import (
 	"strings"
 )
 
+// PartialSuffix marks folders in the cache holding downloads which haven't finished yet
+const PartialSuffix = ".part"
+
 func GetAudioFile(videoID string) string {
 	return fmt.Sprintf("cache/%s.opus", videoID)
 }
@@ -12,3 +15,8 @@ func GetAudioFile(videoID string) string {
 func GetAudioID(filepath string) string {
 	return strings.TrimSuffix(strings.TrimPrefix(filepath, "cache/"), ".opus")
 }
+
+// IsPartial reports whether a cache entry is an unfinished download
+func IsPartial(name string) bool {
+	return strings.HasSuffix(name, PartialSuffix)
+}
@@ -17,3 +17,8 @@ func TestGetAudioID(t *testing.T) {
 	expected := "abc123"
 	assert.Equal(t, expected, GetAudioID(filepath))
 }
+
+func TestIsPartial(t *testing.T) {
+	assert.True(t, IsPartial(".abc123.opus-42"+PartialSuffix))
+	assert.False(t, IsPartial("abc123.opus"))
+}
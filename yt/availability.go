@@ -0,0 +1,28 @@
+package yt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// CheckAvailability checks whether a video can still be played, returning the reason when it cannot
+func CheckAvailability(ctx context.Context, videoID string) (string, error) {
+	_, err := FetchVideoMetadata(ctx, videoID)
+	if err == nil {
+		return "", nil
+	}
+	if reason, ok := unavailableReason(err); ok {
+		return reason, nil
+	}
+	return "", fmt.Errorf("availability check failed: %w", err)
+}
+
+// unavailableReason returns the reason given for a classified failure when it means the video can't be played
+func unavailableReason(err error) (string, bool) {
+	var fetchErr *FetchError
+	if !Unavailable(err) || !errors.As(err, &fetchErr) {
+		return "", false
+	}
+	return fetchErr.Reason, true
+}
@@ -0,0 +1,53 @@
+package yt_test
+
+import (
+	"Twilight/yt"
+	"Twilight/yt/yttest"
+	"context"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// newAvailabilityBackend returns a fake holding a single playable video, failing without retries
+func newAvailabilityBackend(t *testing.T) *yttest.Backend {
+	viper.Set("youtube.retries", 0)
+	t.Cleanup(func() { viper.Set("youtube.retries", 3) })
+
+	backend := yttest.NewBackend().AddVideo(&yt.Video{ID: "abc", Title: "Song"})
+	yttest.Use(t, backend)
+	return backend
+}
+
+func TestCheckAvailability_Playable(t *testing.T) {
+	newAvailabilityBackend(t)
+
+	reason, err := yt.CheckAvailability(context.Background(), "abc")
+
+	assert.NoError(t, err)
+	assert.Empty(t, reason)
+}
+
+func TestCheckAvailability_Unavailable(t *testing.T) {
+	backend := newAvailabilityBackend(t)
+	backend.Fail("private", &yt.FetchError{Kind: yt.ErrPrivate, Reason: "Private video"})
+
+	reason, err := yt.CheckAvailability(context.Background(), "private")
+	assert.NoError(t, err)
+	assert.Equal(t, "Private video", reason)
+
+	reason, err = yt.CheckAvailability(context.Background(), "removed")
+	assert.NoError(t, err)
+	assert.Equal(t, "Video unavailable", reason)
+}
+
+func TestCheckAvailability_TransientFailure(t *testing.T) {
+	backend := newAvailabilityBackend(t)
+	backend.Fail("abc", &yt.FetchError{Kind: yt.ErrTransient, Reason: "HTTP Error 503: Service Unavailable"})
+
+	reason, err := yt.CheckAvailability(context.Background(), "abc")
+
+	assert.ErrorIs(t, err, yt.ErrTransient)
+	assert.Empty(t, reason)
+}
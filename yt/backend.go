@@ -0,0 +1,272 @@
+package yt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kkdai/youtube/v2"
+)
+
+// MetadataBackend fetches the metadata of single videos
+type MetadataBackend interface {
+	Metadata(ctx context.Context, videoID string) (*Video, error) // Unavailable videos fail with a classified error
+}
+
+// DownloadBackend fetches the audio of videos
+type DownloadBackend interface {
+	Download(ctx context.Context, videoID, filename string, policy FormatPolicy) (*AudioFormat, error) // Writes the audio of the video to filename, returning the format picked
+	StreamURL(ctx context.Context, videoID string) (string, error)                                     // Returns the URL ffmpeg reads a live stream from
+}
+
+// PlaylistBackend lists the videos of playlists and searches
+type PlaylistBackend interface {
+	Playlist(ctx context.Context, playlistURL string, start, end int) (*Playlist, error)                     // Lists entries start to end counting from 1, up to the last when end is 0
+	StreamPlaylist(ctx context.Context, playlistURL string, start int, entry func(PlaylistEntry) bool) error // Lists entries from start as they are found, until entry returns false
+	Search(ctx context.Context, query string, limit int) ([]SearchResult, error)
+}
+
+// Backend is everything the package fetches from YouTube, each call is a single attempt with retries and timeouts
+// applied by the caller
+type Backend interface {
+	MetadataBackend
+	DownloadBackend
+	PlaylistBackend
+}
+
+var (
+	backendMu sync.RWMutex
+	backend   Backend = DefaultBackend{}
+)
+
+// SetBackend replaces the backend used to reach YouTube, returning the previous one so tests can restore it
+func SetBackend(b Backend) Backend {
+	backendMu.Lock()
+	defer backendMu.Unlock()
+	previous := backend
+	backend = b
+	return previous
+}
+
+// currentBackend returns the backend used to reach YouTube
+func currentBackend() Backend {
+	backendMu.RLock()
+	defer backendMu.RUnlock()
+	return backend
+}
+
+// DefaultBackend reaches YouTube through the YouTube client, falling back to yt-dlp where the client fails
+type DefaultBackend struct{}
+
+// Metadata fetches basic metadata of a video, trying the YouTube client before yt-dlp
+func (DefaultBackend) Metadata(ctx context.Context, videoID string) (*Video, error) {
+	videoID, _ = youtube.ExtractVideoID(videoID)
+
+	video, err := youTubeMetadata(ctx, videoID)
+	if err == nil {
+		return video, nil
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if err := classifyClientError(err); Unavailable(err) {
+		return nil, err // yt-dlp would only be refused as well
+	}
+
+	output, err := RunYtDlp(ctx, "--dump-single-json", "--skip-download", "--no-playlist", "https://www.youtube.com/watch?v="+videoID)
+	if err != nil {
+		return nil, err
+	}
+	return parseVideo(output)
+}
+
+// Download downloads the audio of a video to filename, trying the YouTube client before yt-dlp
+func (DefaultBackend) Download(ctx context.Context, videoID, filename string, policy FormatPolicy) (*AudioFormat, error) {
+	format, err := youTubeDownload(ctx, videoID, filename, policy)
+	if err == nil {
+		return format, nil
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	out, err := RunYtDlp(ctx,
+		"-f", policy.YtDlpSelector(),
+		"-x",
+		"--audio-format", "opus",
+		"--buffer-size", "16K",
+		"--no-simulate",
+		"--print", ytDlpFormatTemplate,
+		"-o", filename,
+		"https://www.youtube.com/watch?v="+videoID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return parseYtDlpFormat(out), nil
+}
+
+// StreamURL returns the manifest URL of a live stream using yt-dlp
+func (DefaultBackend) StreamURL(ctx context.Context, videoID string) (string, error) {
+	out, err := RunYtDlp(ctx, "-g", "-f", "bestaudio/best", "--no-playlist", "https://www.youtube.com/watch?v="+videoID)
+	if err != nil {
+		return "", err
+	}
+	lines := strings.Fields(string(out))
+	if len(lines) == 0 {
+		return "", errors.New("yt-dlp returned no stream url")
+	}
+	return lines[0], nil
+}
+
+// Playlist lists a range of a playlist along with its details using yt-dlp
+func (DefaultBackend) Playlist(ctx context.Context, playlistURL string, start, end int) (*Playlist, error) {
+	out, err := RunYtDlp(ctx, "-J", "--flat-playlist", "--playlist-items", playlistItems(start, end), playlistURL)
+	if err != nil {
+		return nil, err
+	}
+	return parsePlaylist(out, start)
+}
+
+// StreamPlaylist lists the entries of a playlist from start in a single pass using yt-dlp, as it prints them
+func (DefaultBackend) StreamPlaylist(ctx context.Context, playlistURL string, start int, entry func(PlaylistEntry) bool) error {
+	return StreamYtDlp(ctx, func(line []byte) bool {
+		parsed, ok := parsePlaylistEntry(line)
+		return !ok || entry(parsed)
+	}, "-j", "--flat-playlist", "--lazy-playlist", "--playlist-items", playlistItems(start, 0), playlistURL)
+}
+
+// Search returns up to limit search results for a query using yt-dlp
+func (DefaultBackend) Search(ctx context.Context, query string, limit int) ([]SearchResult, error) {
+	out, err := RunYtDlp(ctx, "-j", "--flat-playlist", fmt.Sprintf("ytsearch%d:%s", limit, query))
+	if err != nil {
+		return nil, err
+	}
+	return parseSearchResults(out), nil
+}
+
+// youTubeDownload downloads audio from a given videoID directly to a file using YouTube client
+func youTubeDownload(ctx context.Context, videoID, filename string, policy FormatPolicy) (*AudioFormat, error) {
+	client := youtube.Client{}
+	video, err := client.GetVideoContext(ctx, "https://www.youtube.com/watch?v="+videoID)
+	if err != nil {
+		return nil, err
+	}
+
+	format := policy.Select(video.Formats)
+	if format == nil {
+		return nil, errNoAudioFormat
+	}
+
+	stream, _, err := client.GetStreamContext(ctx, video, format)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(file, stream)
+	if err != nil {
+		os.Remove(filename)
+		return nil, err
+	}
+
+	return clientAudioFormat(format), nil
+}
+
+// youTubeMetadata fetches basic metadata for a given videoID using YouTube client
+func youTubeMetadata(ctx context.Context, videoID string) (*Video, error) {
+	client := youtube.Client{}
+	v, err := client.GetVideoContext(ctx, videoID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Video{
+		ID:          v.ID,
+		Title:       v.Title,
+		Description: v.Description,
+		Author:      v.Author,
+		Views:       v.Views,
+		Duration:    v.Duration,
+		PublishDate: v.PublishDate,
+		Thumbnail:   v.Thumbnails[0].URL,
+		Live:        v.Duration == 0 && v.HLSManifestURL != "",          // Only live streams are served without a duration
+		Chapters:    chaptersFromDescription(v.Description, v.Duration), // The client doesn't expose chapter markers
+	}, nil
+}
+
+// parseVideo reads the JSON yt-dlp prints for a single video
+func parseVideo(output []byte) (*Video, error) {
+	var data struct {
+		ID          string         `json:"id"`
+		Title       string         `json:"title"`
+		Description string         `json:"description"`
+		Uploader    string         `json:"uploader"`
+		ViewCount   int            `json:"view_count"`
+		Duration    int            `json:"duration"`
+		UploadDate  string         `json:"upload_date"`
+		Thumbnail   string         `json:"thumbnail"`
+		IsLive      bool           `json:"is_live"`
+		Chapters    []YtDlpChapter `json:"chapters"`
+	}
+
+	if err := json.Unmarshal(output, &data); err != nil {
+		return nil, err
+	}
+
+	publishDate, _ := time.Parse("20060102", data.UploadDate)
+
+	return &Video{
+		ID:          data.ID,
+		Title:       data.Title,
+		Description: data.Description,
+		Author:      data.Uploader,
+		Views:       data.ViewCount,
+		Duration:    time.Duration(data.Duration) * time.Second,
+		PublishDate: publishDate,
+		Thumbnail:   data.Thumbnail,
+		Live:        data.IsLive,
+		Chapters:    ChaptersFromYtDlp(data.Chapters),
+	}, nil
+}
+
+// parseSearchResults reads the JSON lines yt-dlp prints for a flat search
+func parseSearchResults(out []byte) []SearchResult {
+	var results []SearchResult
+	for _, line := range bytes.Split(out, []byte("\n")) {
+		var entry struct {
+			ID       string  `json:"id"`
+			Title    string  `json:"title"`
+			Channel  string  `json:"channel"`
+			Uploader string  `json:"uploader"`
+			Duration float64 `json:"duration"`
+		}
+		if len(line) == 0 || json.Unmarshal(line, &entry) != nil || entry.ID == "" {
+			continue
+		}
+		channel := entry.Channel
+		if channel == "" {
+			channel = entry.Uploader
+		}
+		results = append(results, SearchResult{
+			ID:       entry.ID,
+			Title:    entry.Title,
+			Channel:  channel,
+			Duration: time.Duration(entry.Duration * float64(time.Second)),
+		})
+	}
+	return results
+}
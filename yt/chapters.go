@@ -0,0 +1,124 @@
+package yt
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Chapter is a named section of a video
+type Chapter struct {
+	Title string
+	Start time.Duration
+	End   time.Duration // Start of the next chapter, or the end of the video for the last one
+}
+
+// YtDlpChapter is a chapter as yt-dlp prints it within its JSON output
+type YtDlpChapter struct {
+	Title     string  `json:"title"`
+	StartTime float64 `json:"start_time"`
+	EndTime   float64 `json:"end_time"`
+}
+
+// ChaptersFromYtDlp converts the chapters yt-dlp printed, nil when there are none
+func ChaptersFromYtDlp(raw []YtDlpChapter) []Chapter {
+	if len(raw) == 0 {
+		return nil
+	}
+	chapters := make([]Chapter, 0, len(raw))
+	for _, c := range raw {
+		chapters = append(chapters, Chapter{
+			Title: c.Title,
+			Start: time.Duration(c.StartTime * float64(time.Second)),
+			End:   time.Duration(c.EndTime * float64(time.Second)),
+		})
+	}
+	return chapters
+}
+
+// Timestamps such as 1:02:03 or 4:05 at the start or end of a description line, with the title on the other side
+var (
+	leadingTimestamp  = regexp.MustCompile(`^\(?((?:\d+:)?\d{1,2}:\d{2})\)?\s*[-–—:|.]?\s*(.+)$`)
+	trailingTimestamp = regexp.MustCompile(`^(.+?)\s*[-–—:|]?\s*\(?((?:\d+:)?\d{1,2}:\d{2})\)?$`)
+)
+
+// chaptersFromDescription reads chapters from the timestamps of a video description the way YouTube does, which needs
+// at least two timestamps in order with the first at 0:00
+func chaptersFromDescription(description string, duration time.Duration) []Chapter {
+	var chapters []Chapter
+	for _, line := range strings.Split(description, "\n") {
+		line = strings.TrimSpace(line)
+		stamp, title := "", ""
+		if m := leadingTimestamp.FindStringSubmatch(line); m != nil {
+			stamp, title = m[1], m[2]
+		} else if m := trailingTimestamp.FindStringSubmatch(line); m != nil {
+			stamp, title = m[2], m[1]
+		} else {
+			continue
+		}
+
+		start, ok := parseTimestamp(stamp)
+		if !ok || (len(chapters) > 0 && start <= chapters[len(chapters)-1].Start) || (duration > 0 && start >= duration) {
+			continue
+		}
+		chapters = append(chapters, Chapter{Title: strings.TrimSpace(title), Start: start})
+	}
+	if len(chapters) < 2 || chapters[0].Start != 0 {
+		return nil
+	}
+
+	for i := range chapters {
+		if i+1 < len(chapters) {
+			chapters[i].End = chapters[i+1].Start
+		} else {
+			chapters[i].End = duration
+		}
+	}
+	return chapters
+}
+
+// parseTimestamp parses h:mm:ss or m:ss
+func parseTimestamp(stamp string) (time.Duration, bool) {
+	var total time.Duration
+	for _, part := range strings.Split(stamp, ":") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return 0, false
+		}
+		total = total*60 + time.Duration(n)
+	}
+	return total * time.Second, true
+}
+
+// ChapterAt returns the index of the chapter playing at position, false when no chapter covers it
+func ChapterAt(chapters []Chapter, position time.Duration) (int, bool) {
+	for i := len(chapters) - 1; i >= 0; i-- {
+		if position >= chapters[i].Start {
+			return i, chapters[i].End == 0 || position < chapters[i].End || i == len(chapters)-1
+		}
+	}
+	return 0, false
+}
+
+// FindChapter returns the index of the chapter matching a number counting from 1 or a name, preferring exact names
+// over partial ones and ignoring case
+func FindChapter(chapters []Chapter, query string) (int, bool) {
+	query = strings.TrimSpace(query)
+	if n, err := strconv.Atoi(query); err == nil {
+		return n - 1, n >= 1 && n <= len(chapters)
+	}
+
+	lower := strings.ToLower(query)
+	for i, c := range chapters {
+		if strings.ToLower(c.Title) == lower {
+			return i, true
+		}
+	}
+	for i, c := range chapters {
+		if lower != "" && strings.Contains(strings.ToLower(c.Title), lower) {
+			return i, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,97 @@
+package yt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChaptersFromDescription(t *testing.T) {
+	description := "Thanks for listening!\n\n" +
+		"0:00 Intro\n" +
+		"(3:15) - Second Song\n" +
+		"Third Song - 1:02:03\n" +
+		"Follow me at example.com"
+
+	chapters := chaptersFromDescription(description, 2*time.Hour)
+	assert.Equal(t, []Chapter{
+		{Title: "Intro", Start: 0, End: 3*time.Minute + 15*time.Second},
+		{Title: "Second Song", Start: 3*time.Minute + 15*time.Second, End: time.Hour + 2*time.Minute + 3*time.Second},
+		{Title: "Third Song", Start: time.Hour + 2*time.Minute + 3*time.Second, End: 2 * time.Hour},
+	}, chapters)
+}
+
+func TestChaptersFromDescription_NeedsChapterList(t *testing.T) {
+	assert.Nil(t, chaptersFromDescription("The best part is at 1:30", 5*time.Minute), "a single timestamp isn't a chapter list")
+	assert.Nil(t, chaptersFromDescription("0:30 First\n1:30 Second", 5*time.Minute), "chapters must start at 0:00")
+	assert.Nil(t, chaptersFromDescription("", 5*time.Minute))
+
+	chapters := chaptersFromDescription("0:00 First\n0:00 Again\n1:00 Second\n9:00 Too late", 5*time.Minute)
+	assert.Len(t, chapters, 2, "out of order timestamps and those past the end are ignored")
+}
+
+func TestParseVideo_Chapters(t *testing.T) {
+	video, err := parseVideo([]byte(`{"id":"abc","duration":300,"chapters":[
+		{"title":"Intro","start_time":0,"end_time":61.5},
+		{"title":"Outro","start_time":61.5,"end_time":300}
+	]}`))
+	assert.NoError(t, err)
+	assert.Equal(t, []Chapter{
+		{Title: "Intro", Start: 0, End: 61500 * time.Millisecond},
+		{Title: "Outro", Start: 61500 * time.Millisecond, End: 5 * time.Minute},
+	}, video.Chapters)
+
+	video, err = parseVideo([]byte(`{"id":"abc","duration":300,"chapters":null}`))
+	assert.NoError(t, err)
+	assert.Nil(t, video.Chapters)
+}
+
+func TestChapterAt(t *testing.T) {
+	chapters := []Chapter{
+		{Title: "One", Start: 0, End: time.Minute},
+		{Title: "Two", Start: time.Minute, End: 2 * time.Minute},
+	}
+
+	index, ok := ChapterAt(chapters, 30*time.Second)
+	assert.True(t, ok)
+	assert.Equal(t, 0, index)
+
+	index, ok = ChapterAt(chapters, time.Minute)
+	assert.True(t, ok)
+	assert.Equal(t, 1, index)
+
+	index, ok = ChapterAt(chapters, 3*time.Minute)
+	assert.True(t, ok, "the last chapter runs until the audio ends")
+	assert.Equal(t, 1, index)
+
+	_, ok = ChapterAt(chapters[1:], 30*time.Second)
+	assert.False(t, ok)
+}
+
+func TestFindChapter(t *testing.T) {
+	chapters := []Chapter{{Title: "Intro"}, {Title: "Main Theme"}, {Title: "Theme"}}
+
+	tests := []struct {
+		query string
+		index int
+		ok    bool
+	}{
+		{"2", 1, true},
+		{"0", 0, false},
+		{"4", 0, false},
+		{"theme", 2, true},
+		{"MAIN", 1, true},
+		{"outro", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			index, ok := FindChapter(chapters, tt.query)
+			assert.Equal(t, tt.ok, ok)
+			if ok {
+				assert.Equal(t, tt.index, index)
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package yt
+
+import (
+	"Twilight/audiocache"
+	"Twilight/redis_client"
+	"Twilight/utils"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Strum355/log"
+	"github.com/spf13/viper"
+	"golang.org/x/sync/singleflight"
+)
+
+const lockPollInterval = 500 * time.Millisecond
+
+// downloads shares a single in-flight download of each cache file between everyone in this process asking for it
+var downloads singleflight.Group
+
+// flights holds the context of each in-flight download, which is cancelled once every caller waiting on it gave up
+var (
+	flightsMu sync.Mutex
+	flights   = map[string]*flight{}
+)
+
+// flight is the context a shared download runs under along with how many callers still wait on it
+type flight struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	waiters int
+}
+
+// downloadLocker holds a lock on a cache file across every process sharing the cache, swapped out within tests
+var downloadLocker locker = redisLocker{}
+
+// locker takes and releases named locks which expire on their own if the holder dies
+type locker interface {
+	Acquire(key, token string, ttl time.Duration) (bool, error)
+	Release(key, token string) error
+}
+
+// redisLocker implements locker with SET NX and a compare and delete script
+type redisLocker struct{}
+
+// releaseScript deletes the lock only while it is still held by the same token
+const releaseScript = `if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("DEL", KEYS[1]) end return 0`
+
+// Acquire takes the lock if it is free
+func (redisLocker) Acquire(key, token string, ttl time.Duration) (bool, error) {
+	return redis_client.RDB.SetNX(redis_client.Ctx, key, token, ttl).Result()
+}
+
+// Release frees the lock if the token still holds it
+func (redisLocker) Release(key, token string) error {
+	return redis_client.RDB.Eval(redis_client.Ctx, releaseScript, []string{key}, token).Err()
+}
+
+// DownloadOnce makes sure filename exists, running download at most once at a time per file across processes
+func DownloadOnce(ctx context.Context, filename string, download func(ctx context.Context, tmp string) error) error {
+	if _, err := os.Stat(filename); err == nil {
+		return nil
+	}
+
+	// Callers within this process share a single download, other processes wait on its Redis lock in downloadLocked
+	f := joinFlight(ctx, filename)
+	defer leaveFlight(filename, f)
+
+	result := downloads.DoChan(filename, func() (any, error) {
+		return nil, downloadLocked(f.ctx, filename, download)
+	})
+	select {
+	case res := <-result:
+		return res.Err
+	case <-ctx.Done():
+		// Stops waiting straight away, the download is only cancelled once nobody waits on it in leaveFlight
+		return ctx.Err()
+	}
+}
+
+// joinFlight returns the in-flight download of filename, starting a new one if there is none
+func joinFlight(ctx context.Context, filename string) *flight {
+	flightsMu.Lock()
+	defer flightsMu.Unlock()
+
+	f, exists := flights[filename]
+	if !exists {
+		// The download outlives the caller starting it, as long as someone is still waiting on it
+		flightCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		f = &flight{ctx: flightCtx, cancel: cancel}
+		flights[filename] = f
+	}
+	f.waiters++
+	return f
+}
+
+// leaveFlight stops waiting on a download, cancelling it when this was the last caller waiting
+func leaveFlight(filename string, f *flight) {
+	flightsMu.Lock()
+	defer flightsMu.Unlock()
+
+	f.waiters--
+	if f.waiters > 0 {
+		return
+	}
+	f.cancel()
+	if flights[filename] == f {
+		delete(flights, filename)
+		downloads.Forget(filename) // The cancelled call may still be winding down, later callers start afresh
+	}
+}
+
+// downloadLocked downloads filename while holding its lock, or waits for whoever holds it to finish
+func downloadLocked(ctx context.Context, filename string, download func(ctx context.Context, tmp string) error) error {
+	key := "ytlock:" + utils.GetAudioID(filepath.Base(filename))
+	token := newToken()
+	ttl := time.Duration(viper.GetInt("youtube.lock")) * time.Second
+	if ttl <= 0 {
+		ttl = 10 * time.Minute
+	}
+
+	deadline := time.Now().Add(ttl)
+	for {
+		acquired, err := downloadLocker.Acquire(key, token, ttl)
+		if err != nil {
+			// Without Redis only this process is deduplicated, which beats not downloading at all
+			log.WithError(err).WithFields(log.Fields{"file": filename}).Warn("Failed to take download lock")
+			break
+		}
+		if acquired {
+			defer downloadLocker.Release(key, token)
+			break
+		}
+
+		// Another process is downloading the same file
+		select {
+		case <-time.After(lockPollInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if _, err := os.Stat(filename); err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for download of %s", filename)
+		}
+	}
+
+	// The previous holder may have finished between the first check and taking the lock
+	if _, err := os.Stat(filename); err == nil {
+		return nil
+	}
+	return writeAtomically(ctx, filename, download)
+}
+
+// writeAtomically runs download into a temporary folder beside filename and moves the result into place
+func writeAtomically(ctx context.Context, filename string, download func(ctx context.Context, tmp string) error) error {
+	dir := filepath.Dir(filename)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	// The folder shares the cache filesystem so the rename is atomic, its suffix keeps cache cleaning away from it
+	tmpDir, err := os.MkdirTemp(dir, "."+filepath.Base(filename)+"-*"+utils.PartialSuffix)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	tmp := filepath.Join(tmpDir, filepath.Base(filename))
+	if err := download(ctx, tmp); err != nil {
+		return err
+	}
+	if _, err := os.Stat(tmp); err != nil {
+		return fmt.Errorf("download produced no file: %w", err)
+	}
+	if err := os.Rename(tmp, filename); err != nil {
+		return err
+	}
+	audiocache.Add(filename)
+	return nil
+}
+
+// newToken returns a random lock token identifying this holder
+func newToken() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
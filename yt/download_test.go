@@ -0,0 +1,230 @@
+package yt
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Strum355/log"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryLocker is an in memory locker shared by fake instances within a test
+type memoryLocker struct {
+	mu    sync.Mutex
+	locks map[string]string
+	err   error
+}
+
+func (m *memoryLocker) Acquire(key, token string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return false, m.err
+	}
+	if _, held := m.locks[key]; held {
+		return false, nil
+	}
+	m.locks[key] = token
+	return true, nil
+}
+
+func (m *memoryLocker) Release(key, token string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.locks[key] == token {
+		delete(m.locks, key)
+	}
+	return nil
+}
+
+func useLocker(t *testing.T, l locker) {
+	previous := downloadLocker
+	downloadLocker = l
+	t.Cleanup(func() { downloadLocker = previous })
+}
+
+func TestDownloadOnceSharesConcurrentDownloads(t *testing.T) {
+	useLocker(t, &memoryLocker{locks: map[string]string{}})
+	filename := filepath.Join(t.TempDir(), "abc.opus")
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	download := func(_ context.Context, tmp string) error {
+		calls.Add(1)
+		<-release
+		return os.WriteFile(tmp, []byte("audio"), 0o644)
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, 5)
+	for idx := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[idx] = DownloadOnce(context.Background(), filename, download)
+		}()
+	}
+
+	// Nothing may appear at the final path until the download has finished
+	time.Sleep(50 * time.Millisecond)
+	_, err := os.Stat(filename)
+	assert.True(t, os.IsNotExist(err))
+
+	close(release)
+	wg.Wait()
+
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int32(1), calls.Load())
+	data, err := os.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, "audio", string(data))
+
+	// The temporary folder is cleaned up after the rename
+	entries, _ := os.ReadDir(filepath.Dir(filename))
+	assert.Len(t, entries, 1)
+}
+
+func TestDownloadOnceSkipsExistingFile(t *testing.T) {
+	useLocker(t, &memoryLocker{locks: map[string]string{}})
+	filename := filepath.Join(t.TempDir(), "abc.opus")
+	assert.NoError(t, os.WriteFile(filename, []byte("cached"), 0o644))
+
+	err := DownloadOnce(context.Background(), filename, func(_ context.Context, tmp string) error {
+		t.Fatal("download should not run for a cached file")
+		return nil
+	})
+
+	assert.NoError(t, err)
+}
+
+func TestDownloadOnceLeavesNothingOnFailure(t *testing.T) {
+	useLocker(t, &memoryLocker{locks: map[string]string{}})
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "abc.opus")
+
+	err := DownloadOnce(context.Background(), filename, func(_ context.Context, tmp string) error {
+		os.WriteFile(tmp, []byte("half"), 0o644)
+		return errors.New("connection reset")
+	})
+
+	assert.EqualError(t, err, "connection reset")
+	entries, _ := os.ReadDir(dir)
+	assert.Empty(t, entries)
+}
+
+func TestDownloadOnceWaitsForOtherHolder(t *testing.T) {
+	locks := &memoryLocker{locks: map[string]string{"ytlock:abc": "other"}}
+	useLocker(t, locks)
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "abc.opus")
+
+	// Another instance holds the lock and finishes the file a little later
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		os.WriteFile(filename, []byte("audio"), 0o644)
+	}()
+
+	err := DownloadOnce(context.Background(), filename, func(_ context.Context, tmp string) error {
+		t.Fatal("download should not run while another instance holds the lock")
+		return nil
+	})
+
+	assert.NoError(t, err)
+}
+
+func TestDownloadOnceWithoutLocker(t *testing.T) {
+	log.InitSimpleLogger(&log.Config{Output: io.Discard})
+	useLocker(t, &memoryLocker{err: errors.New("redis is down")})
+	filename := filepath.Join(t.TempDir(), "abc.opus")
+
+	err := DownloadOnce(context.Background(), filename, func(_ context.Context, tmp string) error {
+		return os.WriteFile(tmp, []byte("audio"), 0o644)
+	})
+
+	assert.NoError(t, err)
+	assert.FileExists(t, filename)
+}
+
+func TestDownloadOnceCancelsOnceNobodyWaits(t *testing.T) {
+	useLocker(t, &memoryLocker{locks: map[string]string{}})
+	filename := filepath.Join(t.TempDir(), "abc.opus")
+
+	started := make(chan struct{})
+	cancelled := make(chan struct{})
+	download := func(ctx context.Context, tmp string) error {
+		close(started)
+		<-ctx.Done()
+		close(cancelled)
+		return ctx.Err()
+	}
+
+	first, cancelFirst := context.WithCancel(context.Background())
+	second, cancelSecond := context.WithCancel(context.Background())
+	firstErr := make(chan error)
+	secondErr := make(chan error)
+	go func() { firstErr <- DownloadOnce(first, filename, download) }()
+	<-started
+	go func() { secondErr <- DownloadOnce(second, filename, download) }()
+	time.Sleep(20 * time.Millisecond)
+
+	// The download keeps going for whoever is still waiting on it
+	cancelFirst()
+	assert.ErrorIs(t, <-firstErr, context.Canceled)
+	select {
+	case <-cancelled:
+		t.Fatal("download was cancelled while another caller was waiting")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	cancelSecond()
+	assert.ErrorIs(t, <-secondErr, context.Canceled)
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("download wasn't cancelled once nobody was waiting")
+	}
+}
+
+func TestDownloadOnceRejoinsAfterCancel(t *testing.T) {
+	useLocker(t, &memoryLocker{locks: map[string]string{}})
+	filename := filepath.Join(t.TempDir(), "abc.opus")
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var calls atomic.Int32
+	download := func(ctx context.Context, tmp string) error {
+		if calls.Add(1) == 1 {
+			close(started)
+			<-ctx.Done()
+			<-release // The cancelled download is slow to notice
+			return ctx.Err()
+		}
+		return os.WriteFile(tmp, []byte("audio"), 0o644)
+	}
+
+	first, cancelFirst := context.WithCancel(context.Background())
+	firstErr := make(chan error)
+	go func() { firstErr <- DownloadOnce(first, filename, download) }()
+	<-started
+	cancelFirst()
+	assert.ErrorIs(t, <-firstErr, context.Canceled)
+
+	// Queueing the song again while the cancelled download winds down starts a new one instead of sharing its error
+	secondErr := make(chan error)
+	go func() { secondErr <- DownloadOnce(context.Background(), filename, download) }()
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+
+	assert.NoError(t, <-secondErr)
+	assert.FileExists(t, filename)
+	assert.Equal(t, int32(2), calls.Load())
+}
@@ -0,0 +1,182 @@
+package yt
+
+import (
+	"errors"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/kkdai/youtube/v2"
+)
+
+var (
+	ErrPrivate       = errors.New("video is private")
+	ErrAgeRestricted = errors.New("video is age restricted")
+	ErrRegionBlocked = errors.New("video is blocked in this region")
+	ErrRemoved       = errors.New("video has been removed")
+	ErrRateLimited   = errors.New("youtube is rate limiting requests")
+	ErrTransient     = errors.New("temporary failure reaching youtube")
+)
+
+// FetchError is a classified failure along with the reason YouTube or yt-dlp gave for it
+type FetchError struct {
+	Kind   error  // One of the errors above, matched with errors.Is
+	Reason string // Message from YouTube, may be empty
+}
+
+// Error returns the kind of failure followed by its reason
+func (e *FetchError) Error() string {
+	if e.Reason == "" {
+		return e.Kind.Error()
+	}
+	return e.Kind.Error() + ": " + e.Reason
+}
+
+// Unwrap returns the kind of failure
+func (e *FetchError) Unwrap() error {
+	return e.Kind
+}
+
+// errorMarker maps a phrase within an error message to the kind of failure it means
+type errorMarker struct {
+	phrase string
+	kind   error
+}
+
+// Phrases within YouTube and yt-dlp errors, earlier entries win as messages like "Video unavailable. Blocked in your
+// country" match more than one
+var errorMarkers = []errorMarker{
+	{"available in your country", ErrRegionBlocked},
+	{"blocked it in your country", ErrRegionBlocked},
+	{"geo restricted", ErrRegionBlocked},
+	{"confirm your age", ErrAgeRestricted},
+	{"age-restricted", ErrAgeRestricted},
+	{"inappropriate for some users", ErrAgeRestricted},
+	{"private video", ErrPrivate},
+	{"members-only", ErrPrivate},
+	{"not a bot", ErrRateLimited},
+	{"http error 429", ErrRateLimited},
+	{"too many requests", ErrRateLimited},
+	{"video unavailable", ErrRemoved},
+	{"has been removed", ErrRemoved},
+	{"account associated with this video has been terminated", ErrRemoved},
+	{"copyright", ErrRemoved},
+	{"this video is not available", ErrRemoved},
+	{"http error 5", ErrTransient},
+	{"timed out", ErrTransient},
+	{"connection reset", ErrTransient},
+	{"temporary failure", ErrTransient},
+	{"remote end closed", ErrTransient},
+	{"unable to download", ErrTransient},
+}
+
+// Unavailable reports whether err means a video can't be played at all, rather than that fetching it failed for now
+func Unavailable(err error) bool {
+	return errors.Is(err, ErrPrivate) || errors.Is(err, ErrAgeRestricted) || errors.Is(err, ErrRegionBlocked) || errors.Is(err, ErrRemoved)
+}
+
+// Retryable reports whether a failure may succeed when tried again
+func Retryable(err error) bool {
+	return errors.Is(err, ErrRateLimited) || errors.Is(err, ErrTransient)
+}
+
+// Describe returns a short explanation of a failure for users, empty when the failure wasn't classified
+func Describe(err error) string {
+	switch {
+	case errors.Is(err, ErrPrivate):
+		return "the video is private"
+	case errors.Is(err, ErrAgeRestricted):
+		return "the video is age restricted"
+	case errors.Is(err, ErrRegionBlocked):
+		return "the video is blocked in the bot's region"
+	case errors.Is(err, ErrRemoved):
+		return "the video has been removed"
+	case errors.Is(err, ErrRateLimited):
+		return "YouTube is rate limiting the bot, try again in a few minutes"
+	case errors.Is(err, ErrTransient):
+		return "YouTube couldn't be reached, try again shortly"
+	}
+	return ""
+}
+
+// classifyMessage returns the kind of failure a message describes, or nil when it isn't recognised
+func classifyMessage(message string) error {
+	lower := strings.ToLower(message)
+	for _, marker := range errorMarkers {
+		if strings.Contains(lower, marker.phrase) {
+			return marker.kind
+		}
+	}
+	return nil
+}
+
+// classifyClientError classifies an error from the YouTube client, returning it unchanged when it isn't recognised
+func classifyClientError(err error) error {
+	var status *youtube.ErrPlayabiltyStatus
+	var code youtube.ErrUnexpectedStatusCode
+	var netErr net.Error
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, youtube.ErrVideoPrivate):
+		return &FetchError{Kind: ErrPrivate, Reason: "Private video"}
+	case errors.Is(err, youtube.ErrLoginRequired):
+		return &FetchError{Kind: ErrAgeRestricted, Reason: "Age restricted"}
+	case errors.As(err, &status) && status.Status != "OK":
+		reason := status.Reason
+		if reason == "" {
+			reason = "Video unavailable"
+		}
+		kind := classifyMessage(reason)
+		if kind == nil || kind == ErrTransient {
+			kind = ErrRemoved // YouTube refused to play the video, whatever the wording
+		}
+		return &FetchError{Kind: kind, Reason: reason}
+	case errors.As(err, &code) && code == 429:
+		return &FetchError{Kind: ErrRateLimited, Reason: err.Error()}
+	case errors.As(err, &code) && code >= 500:
+		return &FetchError{Kind: ErrTransient, Reason: err.Error()}
+	case errors.As(err, &netErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return &FetchError{Kind: ErrTransient, Reason: err.Error()}
+	}
+	return err
+}
+
+// classifyYtDlpError classifies the error output of yt-dlp, falling back to a plain error holding the output
+func classifyYtDlpError(stderr string) error {
+	reason := ytDlpErrorReason(stderr)
+	if reason == "" {
+		reason = strings.TrimSpace(stderr)
+	}
+	if kind := classifyMessage(reason); kind != nil {
+		return &FetchError{Kind: kind, Reason: reason}
+	}
+	if reason == "" {
+		return errors.New("yt-dlp failed")
+	}
+	return errors.New(reason)
+}
+
+// ytDlpErrorReason extracts the reason from the first error yt-dlp printed, empty when it printed none
+func ytDlpErrorReason(stderr string) string {
+	for _, line := range strings.Split(stderr, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "ERROR:") {
+			continue
+		}
+
+		// Errors look like "ERROR: [youtube] <id>: <reason>", though not every extractor includes the ID
+		reason := strings.TrimSpace(strings.TrimPrefix(line, "ERROR:"))
+		if strings.HasPrefix(reason, "[") {
+			if _, after, ok := strings.Cut(reason, "]"); ok {
+				reason = after
+			}
+			reason = strings.TrimSpace(reason)
+			if id, after, ok := strings.Cut(reason, ": "); ok && !strings.Contains(id, " ") {
+				reason = after
+			}
+		}
+		return strings.TrimSpace(reason)
+	}
+	return ""
+}
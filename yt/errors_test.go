@@ -0,0 +1,151 @@
+package yt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/Strum355/log"
+	"github.com/kkdai/youtube/v2"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyYtDlpError(t *testing.T) {
+	tests := []struct {
+		stderr string
+		kind   error
+		reason string
+	}{
+		{"ERROR: [youtube] abc: Private video. Sign in if you've been granted access to this video", ErrPrivate, "Private video. Sign in if you've been granted access to this video"},
+		{"ERROR: [youtube] abc: Sign in to confirm your age. This video may be inappropriate for some users.", ErrAgeRestricted, "Sign in to confirm your age. This video may be inappropriate for some users."},
+		{"ERROR: [youtube] abc: Video unavailable. The uploader has not made this video available in your country", ErrRegionBlocked, "Video unavailable. The uploader has not made this video available in your country"},
+		{"ERROR: [youtube] abc: Video unavailable. This video has been removed by the uploader", ErrRemoved, "Video unavailable. This video has been removed by the uploader"},
+		{"ERROR: [youtube] abc: Sign in to confirm you're not a bot", ErrRateLimited, "Sign in to confirm you're not a bot"},
+		{"WARNING: retrying\nERROR: unable to download video data: HTTP Error 503: Service Unavailable", ErrTransient, "unable to download video data: HTTP Error 503: Service Unavailable"},
+	}
+	for _, tt := range tests {
+		err := classifyYtDlpError(tt.stderr)
+		assert.ErrorIs(t, err, tt.kind, tt.stderr)
+
+		var fetchErr *FetchError
+		if assert.ErrorAs(t, err, &fetchErr) {
+			assert.Equal(t, tt.reason, fetchErr.Reason)
+		}
+	}
+
+	err := classifyYtDlpError("ERROR: [generic] Unsupported URL: https://example.com")
+	assert.EqualError(t, err, "Unsupported URL: https://example.com")
+	assert.Empty(t, Describe(err))
+
+	assert.EqualError(t, classifyYtDlpError(""), "yt-dlp failed")
+}
+
+func TestClassifyClientError(t *testing.T) {
+	assert.NoError(t, classifyClientError(nil))
+	assert.ErrorIs(t, classifyClientError(fmt.Errorf("fetch: %w", youtube.ErrVideoPrivate)), ErrPrivate)
+	assert.ErrorIs(t, classifyClientError(youtube.ErrLoginRequired), ErrAgeRestricted)
+	assert.ErrorIs(t, classifyClientError(&youtube.ErrPlayabiltyStatus{Status: "UNPLAYABLE", Reason: "The uploader has not made this video available in your country"}), ErrRegionBlocked)
+	assert.ErrorIs(t, classifyClientError(&youtube.ErrPlayabiltyStatus{Status: "ERROR"}), ErrRemoved)
+	assert.ErrorIs(t, classifyClientError(youtube.ErrUnexpectedStatusCode(429)), ErrRateLimited)
+	assert.ErrorIs(t, classifyClientError(youtube.ErrUnexpectedStatusCode(502)), ErrTransient)
+	assert.ErrorIs(t, classifyClientError(io.ErrUnexpectedEOF), ErrTransient)
+
+	err := errors.New("no audio formats")
+	assert.Equal(t, err, classifyClientError(err))
+}
+
+func TestUnavailableAndRetryable(t *testing.T) {
+	removed := &FetchError{Kind: ErrRemoved}
+	limited := fmt.Errorf("download: %w", &FetchError{Kind: ErrRateLimited, Reason: "HTTP Error 429"})
+
+	assert.True(t, Unavailable(removed))
+	assert.False(t, Retryable(removed))
+	assert.False(t, Unavailable(limited))
+	assert.True(t, Retryable(limited))
+	assert.Equal(t, "the video has been removed", Describe(removed))
+	assert.Contains(t, Describe(limited), "rate limiting")
+	assert.Empty(t, Describe(errors.New("boom")))
+}
+
+// useFastRetries shortens the backoff between retries for the rest of the test
+func useFastRetries(t *testing.T, retries int) {
+	log.InitSimpleLogger(&log.Config{Output: io.Discard})
+	prev := retryBackoff
+	retryBackoff = time.Millisecond
+	viper.Set("youtube.retries", retries)
+	t.Cleanup(func() {
+		retryBackoff = prev
+		viper.Set("youtube.retries", 3)
+	})
+}
+
+func TestRetry_RetriesTransientFailures(t *testing.T) {
+	useFastRetries(t, 3)
+
+	calls := 0
+	err := retry(context.Background(), func() error {
+		calls++
+		if calls < 3 {
+			return &FetchError{Kind: ErrTransient}
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+}
+
+func TestRetry_StopsOnPermanentFailure(t *testing.T) {
+	useFastRetries(t, 3)
+
+	calls := 0
+	err := retry(context.Background(), func() error {
+		calls++
+		return &FetchError{Kind: ErrPrivate}
+	})
+	assert.ErrorIs(t, err, ErrPrivate)
+	assert.Equal(t, 1, calls)
+}
+
+func TestRetry_GivesUpAfterRetries(t *testing.T) {
+	useFastRetries(t, 2)
+
+	calls := 0
+	err := retry(context.Background(), func() error {
+		calls++
+		return &FetchError{Kind: ErrRateLimited}
+	})
+	assert.ErrorIs(t, err, ErrRateLimited)
+	assert.Equal(t, 3, calls)
+}
+
+func TestRetry_StopsWhenCancelled(t *testing.T) {
+	useFastRetries(t, 3)
+	retryBackoff = time.Hour
+
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	err := retry(ctx, func() error {
+		calls++
+		cancel()
+		return &FetchError{Kind: ErrTransient}
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestBackoff(t *testing.T) {
+	prev := retryBackoff
+	retryBackoff = time.Second
+	defer func() { retryBackoff = prev }()
+
+	for attempt := range 8 {
+		wait := backoff(attempt)
+		full := min(time.Second<<attempt, maxRetryBackoff)
+		assert.GreaterOrEqual(t, wait, full/2)
+		assert.LessOrEqual(t, wait, full)
+	}
+}
@@ -0,0 +1,165 @@
+package yt
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/kkdai/youtube/v2"
+	"github.com/spf13/viper"
+)
+
+// errNoAudioFormat is returned by the YouTube client download when a video has no audio only stream, leaving it to yt-dlp
+var errNoAudioFormat = errors.New("no audio only format")
+
+// AudioFormat is the stream the audio of a video was downloaded from
+type AudioFormat struct {
+	ID      string // YouTube itag or yt-dlp format ID
+	Codec   string // opus, aac or whatever else the stream was encoded with
+	Bitrate int    // kbps, 0 when unknown
+}
+
+// FormatPolicy decides which audio only stream of a video is downloaded
+type FormatPolicy struct {
+	Codec      string // Preferred codec, opus or aac
+	MaxBitrate int    // Ceiling in kbps, 0 for the best available
+}
+
+// CurrentFormatPolicy returns the format policy configured through viper
+func CurrentFormatPolicy() FormatPolicy {
+	return FormatPolicy{
+		Codec:      strings.ToLower(viper.GetString("youtube.format.codec")),
+		MaxBitrate: max(viper.GetInt("youtube.format.max_bitrate"), 0),
+	}
+}
+
+// Select picks the format to download from those the YouTube client lists, nil when none carries only audio
+func (p FormatPolicy) Select(formats youtube.FormatList) *youtube.Format {
+	audio := formats.Select(audioOnly)
+	preferred := audio.Select(func(f youtube.Format) bool {
+		return formatCodec(f.MimeType) == p.Codec
+	})
+
+	// The preferred codec at the highest bitrate within the ceiling wins, then any codec within it, and when every
+	// stream is above the ceiling the lowest bitrate is picked the same way
+	for _, list := range []youtube.FormatList{preferred, audio} {
+		if f := pickBitrate(list.Select(p.withinCeiling), true); f != nil {
+			return f
+		}
+	}
+	for _, list := range []youtube.FormatList{preferred, audio} {
+		if f := pickBitrate(list, false); f != nil {
+			return f
+		}
+	}
+	return nil
+}
+
+// YtDlpSelector returns the yt-dlp format selector applying the policy, falling back the same way Select does
+func (p FormatPolicy) YtDlpSelector() string {
+	codec := ""
+	switch p.Codec {
+	case "opus":
+		codec = "[acodec=opus]"
+	case "aac":
+		codec = "[acodec^=mp4a]"
+	}
+
+	var selectors []string
+	if p.MaxBitrate > 0 {
+		ceiling := fmt.Sprintf("[abr<=%d]", p.MaxBitrate)
+		if codec != "" {
+			selectors = append(selectors, "bestaudio"+codec+ceiling)
+		}
+		selectors = append(selectors, "bestaudio"+ceiling)
+		if codec != "" {
+			selectors = append(selectors, "worstaudio"+codec)
+		}
+		selectors = append(selectors, "worstaudio")
+	} else {
+		if codec != "" {
+			selectors = append(selectors, "bestaudio"+codec)
+		}
+		selectors = append(selectors, "bestaudio")
+	}
+	return strings.Join(selectors, "/")
+}
+
+// withinCeiling reports whether a format's bitrate is within the ceiling of the policy
+func (p FormatPolicy) withinCeiling(f youtube.Format) bool {
+	return p.MaxBitrate == 0 || formatBitrate(f) <= p.MaxBitrate
+}
+
+// audioOnly reports whether a format carries audio without video
+func audioOnly(f youtube.Format) bool {
+	return f.AudioChannels > 0 && f.Width == 0 && f.Height == 0 && strings.HasPrefix(f.MimeType, "audio/")
+}
+
+// pickBitrate returns the format with the highest or lowest bitrate, nil for an empty list
+func pickBitrate(formats youtube.FormatList, highest bool) *youtube.Format {
+	var picked *youtube.Format
+	for i := range formats {
+		f := &formats[i]
+		if picked == nil || (highest && formatBitrate(*f) > formatBitrate(*picked)) || (!highest && formatBitrate(*f) < formatBitrate(*picked)) {
+			picked = f
+		}
+	}
+	return picked
+}
+
+// formatBitrate returns the bitrate of a format in kbps, preferring its average over its peak
+func formatBitrate(f youtube.Format) int {
+	if f.AverageBitrate > 0 {
+		return f.AverageBitrate / 1000
+	}
+	return f.Bitrate / 1000
+}
+
+// formatCodec returns the codec named in a mime type such as `audio/webm; codecs="opus"`
+func formatCodec(mimeType string) string {
+	_, codecs, ok := strings.Cut(mimeType, "codecs=")
+	if !ok {
+		return ""
+	}
+	return normaliseCodec(strings.Trim(codecs, `" `))
+}
+
+// normaliseCodec maps codec names from YouTube and yt-dlp onto the names a policy uses
+func normaliseCodec(codec string) string {
+	codec = strings.ToLower(codec)
+	if strings.HasPrefix(codec, "mp4a") {
+		return "aac"
+	}
+	return codec
+}
+
+// clientAudioFormat describes a format listed by the YouTube client
+func clientAudioFormat(f *youtube.Format) *AudioFormat {
+	return &AudioFormat{
+		ID:      strconv.Itoa(f.ItagNo),
+		Codec:   formatCodec(f.MimeType),
+		Bitrate: formatBitrate(*f),
+	}
+}
+
+// ytDlpFormatTemplate makes yt-dlp print the format it downloaded in the form parseYtDlpFormat reads
+const ytDlpFormatTemplate = "after_move:%(format_id)s|%(acodec)s|%(abr)s"
+
+// parseYtDlpFormat reads the format yt-dlp printed through ytDlpFormatTemplate, nil when it printed none
+func parseYtDlpFormat(out []byte) *AudioFormat {
+	lines := strings.Fields(strings.TrimSpace(string(out)))
+	if len(lines) == 0 {
+		return nil
+	}
+	parts := strings.Split(lines[len(lines)-1], "|")
+	if len(parts) != 3 {
+		return nil
+	}
+
+	format := &AudioFormat{ID: parts[0], Codec: normaliseCodec(parts[1])}
+	if abr, err := strconv.ParseFloat(parts[2], 64); err == nil {
+		format.Bitrate = int(abr + 0.5)
+	}
+	return format
+}
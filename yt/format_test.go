@@ -0,0 +1,63 @@
+package yt
+
+import (
+	"testing"
+
+	"github.com/kkdai/youtube/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// testFormats mirrors the streams YouTube usually lists for a music video
+var testFormats = youtube.FormatList{
+	{ItagNo: 18, MimeType: `video/mp4; codecs="avc1.42001E, mp4a.40.2"`, Bitrate: 500000, Width: 640, Height: 360, AudioChannels: 2},
+	{ItagNo: 140, MimeType: `audio/mp4; codecs="mp4a.40.2"`, Bitrate: 130000, AverageBitrate: 129000, AudioChannels: 2},
+	{ItagNo: 249, MimeType: `audio/webm; codecs="opus"`, Bitrate: 60000, AverageBitrate: 50000, AudioChannels: 2},
+	{ItagNo: 250, MimeType: `audio/webm; codecs="opus"`, Bitrate: 80000, AverageBitrate: 70000, AudioChannels: 2},
+	{ItagNo: 251, MimeType: `audio/webm; codecs="opus"`, Bitrate: 150000, AverageBitrate: 135000, AudioChannels: 2},
+}
+
+func TestFormatPolicy_Select(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy FormatPolicy
+		itag   int
+	}{
+		{"best opus within ceiling", FormatPolicy{Codec: "opus", MaxBitrate: 160}, 251},
+		{"lower ceiling", FormatPolicy{Codec: "opus", MaxBitrate: 100}, 250},
+		{"preferred aac", FormatPolicy{Codec: "aac", MaxBitrate: 160}, 140},
+		{"other codec within ceiling", FormatPolicy{Codec: "aac", MaxBitrate: 100}, 250},
+		{"lowest above ceiling", FormatPolicy{Codec: "opus", MaxBitrate: 10}, 249},
+		{"no ceiling", FormatPolicy{Codec: "opus"}, 251},
+		{"no preference", FormatPolicy{}, 251},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := tt.policy.Select(testFormats)
+			if assert.NotNil(t, f) {
+				assert.Equal(t, tt.itag, f.ItagNo)
+			}
+		})
+	}
+}
+
+func TestFormatPolicy_SelectSkipsVideo(t *testing.T) {
+	assert.Nil(t, FormatPolicy{Codec: "opus"}.Select(testFormats[:1]))
+}
+
+func TestFormatPolicy_YtDlpSelector(t *testing.T) {
+	assert.Equal(t, "bestaudio[acodec=opus][abr<=160]/bestaudio[abr<=160]/worstaudio[acodec=opus]/worstaudio", FormatPolicy{Codec: "opus", MaxBitrate: 160}.YtDlpSelector())
+	assert.Equal(t, "bestaudio[acodec^=mp4a]/bestaudio", FormatPolicy{Codec: "aac"}.YtDlpSelector())
+	assert.Equal(t, "bestaudio", FormatPolicy{}.YtDlpSelector())
+}
+
+func TestClientAudioFormat(t *testing.T) {
+	assert.Equal(t, &AudioFormat{ID: "251", Codec: "opus", Bitrate: 135}, clientAudioFormat(&testFormats[4]))
+	assert.Equal(t, &AudioFormat{ID: "140", Codec: "aac", Bitrate: 129}, clientAudioFormat(&testFormats[1]))
+}
+
+func TestParseYtDlpFormat(t *testing.T) {
+	assert.Equal(t, &AudioFormat{ID: "251", Codec: "opus", Bitrate: 136}, parseYtDlpFormat([]byte("251|opus|135.6\n")))
+	assert.Equal(t, &AudioFormat{ID: "140", Codec: "aac"}, parseYtDlpFormat([]byte("140|mp4a.40.2|NA\n")))
+	assert.Nil(t, parseYtDlpFormat(nil))
+	assert.Nil(t, parseYtDlpFormat([]byte("garbage")))
+}
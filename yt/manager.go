@@ -1,94 +1,180 @@
 package yt
 
 import (
-	"Twilight/redis_client"
+	"Twilight/db_client"
 	"Twilight/utils"
-	"bytes"
-	"encoding/json"
-	"os"
-	"os/exec"
+	"context"
+	"errors"
+	"fmt"
 	"time"
 
+	"github.com/Strum355/log"
+	"github.com/kkdai/youtube/v2"
 	"github.com/redis/go-redis/v9"
 	"github.com/spf13/viper"
 )
 
 type YouTubeManager struct {
-	redis        *redis.Client
+	store        Store
+	tiers        []MetadataTier // Caches metadata is read through, fastest first
 	cacheYoutube time.Duration
-	cacheAudio   time.Duration
 }
 
-// NewYouTubeManager creates a YouTubeManager with Redis cache
+// NewYouTubeManager creates a YouTubeManager reading metadata through the in-process, Redis and Postgres caches
 func NewYouTubeManager(rdb *redis.Client) *YouTubeManager {
+	ym := NewYouTubeManagerWithStore(NewRedisStore(rdb))
+	return ym.WithTiers(sharedMemoryTier(), ym.tiers[0], DBTier(db_client.DB))
+}
+
+// NewYouTubeManagerWithStore creates a YouTubeManager caching within the given store alone
+func NewYouTubeManagerWithStore(store Store) *YouTubeManager {
 	Yt := time.Duration(viper.GetInt("cache.youtube")) * time.Second
-	Audio := time.Duration(viper.GetInt("cache.audio")) * time.Second
 	return &YouTubeManager{
-		redis:        rdb,
+		store:        store,
+		tiers:        []MetadataTier{StoreTier(store, Yt)},
 		cacheYoutube: Yt,
-		cacheAudio:   Audio,
 	}
 }
 
-// GetVideoMetadata fetches YouTube video metadata given videoID
-func (ym *YouTubeManager) GetVideoMetadata(videoID string) (*Video, error) {
-	// Try Redis
-	cached, err := ym.redis.Get(redis_client.Ctx, "ytmeta:"+videoID).Result()
-	if err == nil && cached != "" {
-		var video Video
-		json.Unmarshal([]byte(cached), &video)
-		return &video, nil
+// WithTiers replaces the caches metadata is read through, fastest first
+func (ym *YouTubeManager) WithTiers(tiers ...MetadataTier) *YouTubeManager {
+	ym.tiers = tiers
+	return ym
+}
+
+// GetVideoMetadata fetches YouTube video metadata given videoID, reading through each tier before YouTube
+func (ym *YouTubeManager) GetVideoMetadata(ctx context.Context, videoID string) (*Video, error) {
+	if id, err := youtube.ExtractVideoID(videoID); err == nil {
+		videoID = id
 	}
 
-	// Fetch from Youtube
-	video, err := FetchVideoMetadata(videoID)
+	for i, tier := range ym.tiers {
+		video, err := tier.Get(ctx, videoID)
+		if err != nil {
+			if !errors.Is(err, ErrNotCached) {
+				log.WithError(err).WithFields(log.Fields{"video_id": videoID}).Warn("Failed to read cached video metadata")
+			}
+			continue
+		}
+
+		// Copied into the faster tiers, and served while stale rather than waiting on the refresh
+		ym.put(ctx, ym.tiers[:i], video)
+		if video.Stale() {
+			ym.refreshLater(videoID, video)
+		}
+		return video, nil
+	}
+
+	return ym.refresh(ctx, videoID, nil)
+}
+
+// refresh fetches the metadata of a video from YouTube and stores it within every tier, keeping the format recorded
+// within previous
+func (ym *YouTubeManager) refresh(ctx context.Context, videoID string, previous *Video) (*Video, error) {
+	video, err := FetchVideoMetadata(ctx, videoID)
 	if err != nil {
 		return nil, err
 	}
+	if video.ID == "" {
+		video.ID = videoID
+	}
+	if previous != nil {
+		video.Format = previous.Format
+	}
+	video.FetchedAt = time.Now()
 
-	// Store in Redis
-	data, _ := json.Marshal(video)
-	ym.redis.Set(redis_client.Ctx, "ytmeta:"+videoID, data, ym.cacheYoutube)
-
+	ym.put(ctx, ym.tiers, video)
 	return video, nil
 }
 
-// DownloadAudio caches and downloads YouTube audio given videoID
-func (ym *YouTubeManager) DownloadAudio(videoID string) error {
-	ym.redis.Set(redis_client.Ctx, "ytvideo:"+videoID, true, ym.cacheAudio)
-	filename := utils.GetAudioFile(videoID)
+// refreshLater refreshes a stale video in the background, once at a time however often it is read meanwhile
+func (ym *YouTubeManager) refreshLater(videoID string, previous *Video) {
+	go refreshes.Do(videoID, func() (any, error) {
+		_, err := ym.refresh(context.Background(), videoID, previous)
+		if err != nil {
+			log.WithError(err).WithFields(log.Fields{"video_id": videoID}).Warn("Failed to refresh stale video metadata")
+		}
+		return nil, err
+	})
+}
+
+// put stores a video within each of the given tiers
+func (ym *YouTubeManager) put(ctx context.Context, tiers []MetadataTier, video *Video) {
+	for _, tier := range tiers {
+		if err := tier.Put(ctx, video); err != nil {
+			log.WithError(err).WithFields(log.Fields{"video_id": video.ID}).Warn("Failed to cache video metadata")
+		}
+	}
+}
 
-	if _, err := os.Stat(filename); os.IsNotExist(err) {
-		if err := DownloadAudioFile(videoID); err != nil {
+// DownloadAudio caches and downloads YouTube audio given videoID, sharing the download with concurrent requests
+func (ym *YouTubeManager) DownloadAudio(ctx context.Context, videoID string) error {
+	return DownloadOnce(ctx, utils.GetAudioFile(videoID), func(ctx context.Context, tmp string) error {
+		format, err := DownloadAudioFile(ctx, videoID, tmp)
+		if err != nil {
 			return err
 		}
+		ym.recordFormat(ctx, videoID, format)
+		return nil
+	})
+}
+
+// recordFormat stores the format the audio of a video was downloaded in alongside its cached metadata
+func (ym *YouTubeManager) recordFormat(ctx context.Context, videoID string, format *AudioFormat) {
+	if format == nil {
+		return
+	}
+	video, err := ym.GetVideoMetadata(ctx, videoID)
+	if err != nil {
+		log.WithError(err).WithFields(log.Fields{"video_id": videoID}).Warn("Failed to record downloaded format")
+		return
 	}
 
-	return nil
+	video.Format = format
+	ym.put(ctx, ym.tiers, video)
 }
 
 // GetPlaylistVideoIDs returns all video IDs from a YouTube playlist URL
-func (ym *YouTubeManager) GetPlaylistVideoIDs(playlistURL string) ([]string, error) {
-	cmd := exec.Command("yt-dlp", "-j", "--flat-playlist", playlistURL)
-	out, err := cmd.Output()
+func (ym *YouTubeManager) GetPlaylistVideoIDs(ctx context.Context, playlistURL string) ([]string, error) {
+	playlist, err := ym.GetPlaylist(ctx, playlistURL, 1, 0)
 	if err != nil {
 		return nil, err
 	}
 
-	lines := bytes.Split(out, []byte("\n"))
-	videoIDs := []string{}
-	for _, line := range lines {
-		if len(line) == 0 {
-			continue
-		}
-		var entry struct {
-			ID string `json:"id"`
-		}
-		if err := json.Unmarshal(line, &entry); err != nil {
-			continue
-		}
+	videoIDs := make([]string, 0, len(playlist.Entries))
+	for _, entry := range playlist.Entries {
 		videoIDs = append(videoIDs, entry.ID)
 	}
-
 	return videoIDs, nil
 }
+
+// SearchResult is a video found by a YouTube search
+type SearchResult struct {
+	ID       string
+	Title    string
+	Channel  string
+	Duration time.Duration
+}
+
+// SearchVideoID returns the videoID of the top YouTube search result for a query
+func (ym *YouTubeManager) SearchVideoID(ctx context.Context, query string) (string, error) {
+	results, err := ym.SearchVideos(ctx, query, 1)
+	if err != nil {
+		return "", err
+	}
+	return results[0].ID, nil
+}
+
+// SearchVideos returns up to limit YouTube search results for a query, best first
+func (ym *YouTubeManager) SearchVideos(ctx context.Context, query string, limit int) ([]SearchResult, error) {
+	results, err := fetch(ctx, func(ctx context.Context) ([]SearchResult, error) {
+		return currentBackend().Search(ctx, query, limit)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("no results for %q", query)
+	}
+	return results, nil
+}
@@ -0,0 +1,205 @@
+package yt_test
+
+import (
+	"Twilight/utils"
+	"Twilight/yt"
+	"Twilight/yt/yttest"
+	"context"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Strum355/log"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// newManager returns a manager caching in memory, backed by a fake holding a single video
+func newManager(t *testing.T) (*yt.YouTubeManager, *yttest.Backend, *yttest.Store) {
+	log.InitSimpleLogger(&log.Config{Output: io.Discard})
+	viper.Set("cache.youtube", 3600)
+	viper.Set("youtube.retries", 0)
+	t.Cleanup(func() { viper.Set("youtube.retries", 3) })
+
+	backend := yttest.NewBackend().AddVideo(&yt.Video{ID: "abc", Title: "Song", Author: "Artist", Duration: 3 * time.Minute})
+	yttest.Use(t, backend)
+	store := yttest.NewStore()
+	return yt.NewYouTubeManagerWithStore(store), backend, store
+}
+
+func TestGetVideoMetadata_CachesFetchedVideos(t *testing.T) {
+	ym, backend, _ := newManager(t)
+
+	for range 3 {
+		video, err := ym.GetVideoMetadata(context.Background(), "abc")
+		assert.NoError(t, err)
+		assert.Equal(t, "Song", video.Title)
+		assert.Equal(t, 3*time.Minute, video.Duration)
+	}
+	assert.Equal(t, 1, backend.Calls("Metadata", "abc"))
+}
+
+func TestGetVideoMetadata_RefetchesExpiredVideos(t *testing.T) {
+	ym, backend, store := newManager(t)
+
+	_, err := ym.GetVideoMetadata(context.Background(), "abc")
+	assert.NoError(t, err)
+	store.Advance(2 * time.Hour)
+	_, err = ym.GetVideoMetadata(context.Background(), "abc")
+	assert.NoError(t, err)
+
+	assert.Equal(t, 2, backend.Calls("Metadata", "abc"))
+}
+
+func TestGetVideoMetadata_DoesNotCacheFailures(t *testing.T) {
+	ym, backend, store := newManager(t)
+
+	_, err := ym.GetVideoMetadata(context.Background(), "missing")
+	assert.ErrorIs(t, err, yt.ErrRemoved)
+	_, err = ym.GetVideoMetadata(context.Background(), "missing")
+	assert.ErrorIs(t, err, yt.ErrRemoved)
+
+	assert.Equal(t, 2, backend.Calls("Metadata", "missing"))
+	assert.Zero(t, store.Keys())
+}
+
+func TestGetVideoMetadata_RetriesTemporaryFailures(t *testing.T) {
+	ym, backend, _ := newManager(t)
+	viper.Set("youtube.retries", 1)
+	backend.Fail("abc", &yt.FetchError{Kind: yt.ErrRateLimited})
+
+	_, err := ym.GetVideoMetadata(context.Background(), "abc")
+	assert.ErrorIs(t, err, yt.ErrRateLimited)
+	assert.Equal(t, 2, backend.Calls("Metadata", "abc"))
+}
+
+func TestDownloadAudio_WritesGeneratedAudio(t *testing.T) {
+	ym, backend, _ := newManager(t)
+	t.Chdir(t.TempDir())
+
+	assert.NoError(t, ym.DownloadAudio(context.Background(), "abc"))
+	assert.NoError(t, ym.DownloadAudio(context.Background(), "abc"))
+
+	data, err := os.ReadFile(utils.GetAudioFile("abc"))
+	assert.NoError(t, err)
+	assert.Equal(t, "RIFF", string(data[:4]))
+	assert.Equal(t, 1, backend.Calls("Download", "abc"))
+
+	video, err := ym.GetVideoMetadata(context.Background(), "abc")
+	assert.NoError(t, err)
+	if assert.NotNil(t, video.Format) {
+		assert.Equal(t, "pcm", video.Format.Codec)
+		assert.Equal(t, 1536, video.Format.Bitrate)
+	}
+}
+
+func TestPlaylistAndSearch(t *testing.T) {
+	ym, backend, _ := newManager(t)
+	backend.AddPlaylist("https://www.youtube.com/playlist?list=PL1", "Mix", "abc", "def")
+	backend.AddSearch("artist song", yt.SearchResult{ID: "abc"}, yt.SearchResult{ID: "def"})
+
+	videoIDs, err := ym.GetPlaylistVideoIDs(context.Background(), "https://www.youtube.com/playlist?list=PL1")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"abc", "def"}, videoIDs)
+
+	videoID, err := ym.SearchVideoID(context.Background(), "artist song")
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", videoID)
+
+	_, err = ym.SearchVideoID(context.Background(), "nothing")
+	assert.Error(t, err)
+}
+
+func TestCheckAvailability_UsesBackend(t *testing.T) {
+	_, backend, _ := newManager(t)
+	backend.AddVideo(&yt.Video{ID: "private"}).Fail("private", &yt.FetchError{Kind: yt.ErrPrivate, Reason: "Private video"})
+
+	reason, err := yt.CheckAvailability(context.Background(), "abc")
+	assert.NoError(t, err)
+	assert.Empty(t, reason)
+
+	reason, err = yt.CheckAvailability(context.Background(), "private")
+	assert.NoError(t, err)
+	assert.Equal(t, "Private video", reason)
+}
+
+func TestStreamPlaylist(t *testing.T) {
+	ym, backend, _ := newManager(t)
+	backend.AddPlaylist("https://www.youtube.com/playlist?list=PL2", "Long", "a", "b", "c", "d", "e")
+
+	var ids []string
+	err := ym.StreamPlaylist(context.Background(), "https://www.youtube.com/playlist?list=PL2", 3, func(entry yt.PlaylistEntry) bool {
+		ids = append(ids, entry.ID)
+		return true
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"c", "d", "e"}, ids)
+	assert.Equal(t, 1, backend.Calls("StreamPlaylist", "https://www.youtube.com/playlist?list=PL2"))
+}
+
+func TestStreamPlaylist_StopsEarly(t *testing.T) {
+	ym, backend, _ := newManager(t)
+	backend.AddPlaylist("https://www.youtube.com/playlist?list=PL3", "Long", "a", "b", "c", "d")
+
+	entries := 0
+	err := ym.StreamPlaylist(context.Background(), "https://www.youtube.com/playlist?list=PL3", 1, func(entry yt.PlaylistEntry) bool {
+		entries++
+		return false
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, entries)
+}
+
+func TestGetVideoMetadata_ReadsThroughTiers(t *testing.T) {
+	_, backend, store := newManager(t)
+	ctx := context.Background()
+
+	first := yt.NewYouTubeManagerWithStore(store).WithTiers(yt.NewMemoryTier(10), yt.StoreTier(store, time.Hour))
+	_, err := first.GetVideoMetadata(ctx, "abc")
+	assert.NoError(t, err)
+
+	// Another process shares the store but starts with an empty memory tier, which is filled from the store
+	memory := yt.NewMemoryTier(10)
+	second := yt.NewYouTubeManagerWithStore(store).WithTiers(memory, yt.StoreTier(store, time.Hour))
+	video, err := second.GetVideoMetadata(ctx, "abc")
+	assert.NoError(t, err)
+	assert.Equal(t, "Song", video.Title)
+	assert.False(t, video.FetchedAt.IsZero())
+	assert.Equal(t, 1, memory.Len())
+	assert.Equal(t, 1, backend.Calls("Metadata", "abc"))
+}
+
+func TestGetVideoMetadata_RefreshesStaleVideosInBackground(t *testing.T) {
+	ym, backend, store := newManager(t)
+	viper.Set("metadata.stale_after", 1)
+	t.Cleanup(func() { viper.Set("metadata.stale_after", nil) })
+	ctx := context.Background()
+
+	stale := &yt.Video{ID: "abc", Title: "Old Title", Views: 10, FetchedAt: time.Now().Add(-2 * time.Hour)}
+	assert.NoError(t, yt.StoreTier(store, time.Hour).Put(ctx, stale))
+
+	video, err := ym.GetVideoMetadata(ctx, "abc")
+	assert.NoError(t, err)
+	assert.Equal(t, "Old Title", video.Title) // Served straight away while it is refreshed
+
+	assert.Eventually(t, func() bool {
+		cached, err := yt.StoreTier(store, time.Hour).Get(ctx, "abc")
+		return err == nil && cached.Title == "Song"
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, 1, backend.Calls("Metadata", "abc"))
+}
+
+func TestGetVideoMetadata_AcceptsURLs(t *testing.T) {
+	ym, backend, _ := newManager(t)
+	backend.AddVideo(&yt.Video{ID: "dQw4w9WgXcQ", Title: "Classic"})
+
+	for _, videoID := range []string{"https://www.youtube.com/watch?v=dQw4w9WgXcQ", "dQw4w9WgXcQ"} {
+		video, err := ym.GetVideoMetadata(context.Background(), videoID)
+		assert.NoError(t, err)
+		assert.Equal(t, "Classic", video.Title)
+	}
+	assert.Equal(t, 1, backend.Calls("Metadata", "dQw4w9WgXcQ"))
+}
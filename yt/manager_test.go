@@ -0,0 +1,23 @@
+package yt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSearchResults(t *testing.T) {
+	out := []byte(`{"id": "a1", "title": "Song (Official Video)", "channel": "Artist", "duration": 213.0}
+{"id": "b2", "title": "Song (Live)", "uploader": "Fan", "duration": 250.5}
+not json
+{"title": "No id"}
+`)
+
+	results := parseSearchResults(out)
+
+	assert.Len(t, results, 2)
+	assert.Equal(t, SearchResult{ID: "a1", Title: "Song (Official Video)", Channel: "Artist", Duration: 213 * time.Second}, results[0])
+	assert.Equal(t, "Fan", results[1].Channel)
+	assert.Equal(t, 250500*time.Millisecond, results[1].Duration)
+}
@@ -0,0 +1,336 @@
+package yt
+
+import (
+	"Twilight/redis_client"
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Strum355/log"
+	"github.com/spf13/viper"
+	"golang.org/x/sync/singleflight"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MetadataTier is one level of the cache video metadata is read through, checked fastest first before YouTube is
+// reached
+type MetadataTier interface {
+	Get(ctx context.Context, videoID string) (*Video, error) // Fails with ErrNotCached when the tier doesn't hold the video
+	Put(ctx context.Context, video *Video) error
+}
+
+// refreshes shares a single background refresh of each stale video between everyone reading it
+var refreshes singleflight.Group
+
+// Stale reports whether metadata was fetched long enough ago to be refreshed, it is still served in the meantime
+func (v *Video) Stale() bool {
+	staleAfter := time.Duration(viper.GetInt("metadata.stale_after")) * time.Hour
+	return staleAfter > 0 && time.Since(v.FetchedAt) > staleAfter
+}
+
+// MemoryTier keeps the metadata of the most recently read videos within this process
+type MemoryTier struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List // Most recently read first
+	items map[string]*list.Element
+}
+
+// NewMemoryTier creates an in-process tier holding up to size videos
+func NewMemoryTier(size int) *MemoryTier {
+	return &MemoryTier{size: max(size, 1), order: list.New(), items: map[string]*list.Element{}}
+}
+
+// sharedMemoryTier is the in-process tier shared by every YouTubeManager
+var sharedMemoryTier = sync.OnceValue(func() *MemoryTier {
+	return NewMemoryTier(viper.GetInt("metadata.memory_size"))
+})
+
+// Get returns a copy of the video, marking it as the most recently read
+func (m *MemoryTier) Get(ctx context.Context, videoID string) (*Video, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	elem, ok := m.items[videoID]
+	if !ok {
+		return nil, ErrNotCached
+	}
+	m.order.MoveToFront(elem)
+	video := *elem.Value.(*Video)
+	return &video, nil
+}
+
+// Put stores a copy of the video, evicting the least recently read video once the tier is full
+func (m *MemoryTier) Put(ctx context.Context, video *Video) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	copied := *video
+	if elem, ok := m.items[video.ID]; ok {
+		elem.Value = &copied
+		m.order.MoveToFront(elem)
+		return nil
+	}
+
+	m.items[video.ID] = m.order.PushFront(&copied)
+	for m.order.Len() > m.size {
+		oldest := m.order.Back()
+		m.order.Remove(oldest)
+		delete(m.items, oldest.Value.(*Video).ID)
+	}
+	return nil
+}
+
+// Len returns how many videos the tier holds
+func (m *MemoryTier) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.order.Len()
+}
+
+// storeTier keeps metadata within a Store such as Redis, expiring after ttl
+type storeTier struct {
+	store Store
+	ttl   time.Duration
+}
+
+// StoreTier creates a tier keeping metadata within store for ttl
+func StoreTier(store Store, ttl time.Duration) MetadataTier {
+	return storeTier{store: store, ttl: ttl}
+}
+
+// Get returns the video stored under its ytmeta key
+func (t storeTier) Get(ctx context.Context, videoID string) (*Video, error) {
+	cached, err := t.store.Get(ctx, "ytmeta:"+videoID)
+	if err != nil {
+		return nil, err
+	}
+	if cached == "" {
+		return nil, ErrNotCached
+	}
+
+	var video Video
+	if err := json.Unmarshal([]byte(cached), &video); err != nil {
+		return nil, err
+	}
+	return &video, nil
+}
+
+// Put stores the video under its ytmeta key
+func (t storeTier) Put(ctx context.Context, video *Video) error {
+	data, err := json.Marshal(video)
+	if err != nil {
+		return err
+	}
+	return t.store.Set(ctx, "ytmeta:"+video.ID, data, t.ttl)
+}
+
+// readAtPrecision is how far behind read_at may lag, retention is counted in days so a read is recorded once a day
+const readAtPrecision = 24 * time.Hour
+
+// dbTier keeps metadata durably within the songs table
+type dbTier struct {
+	db *gorm.DB
+}
+
+// DBTier creates a tier keeping metadata within the songs table, which does nothing without a database
+func DBTier(db *gorm.DB) MetadataTier {
+	return dbTier{db: db}
+}
+
+// songRow is the metadata of a video within the songs table, which playlists share
+type songRow struct {
+	ID          string `gorm:"primaryKey"`
+	Title       string
+	Author      string
+	Views       int
+	Description string
+	Duration    int64 // Seconds
+	PublishDate time.Time
+	URL         string
+	Thumbnail   string
+	Live        bool
+	FetchedAt   *time.Time // Unset for songs stored before metadata was cached, which count as stale
+	Chapters    string     // JSON list of chapters, empty when the video has none
+	Format      string     // JSON of the format the cached audio was downloaded in, empty until it is downloaded
+	ReadAt      *time.Time // Last read or write for a listener to within a day, unset for songs only added to playlists
+}
+
+// readRecorded reports whether the row was marked read recently enough that another read needn't be written
+func (r songRow) readRecorded(now time.Time) bool {
+	return r.ReadAt != nil && now.Sub(*r.ReadAt) < readAtPrecision
+}
+
+// TableName returns the table songs are stored within
+func (songRow) TableName() string {
+	return "songs"
+}
+
+// Get returns the video stored within the songs table
+func (t dbTier) Get(ctx context.Context, videoID string) (*Video, error) {
+	if t.db == nil {
+		return nil, ErrNotCached
+	}
+
+	var row songRow
+	err := t.db.WithContext(ctx).Where("id = ?", videoID).Take(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotCached
+	}
+	if err != nil {
+		return nil, err
+	}
+	if now := time.Now(); !row.readRecorded(now) {
+		if err := t.db.WithContext(ctx).Model(&songRow{}).Where("id = ?", videoID).Update("read_at", now).Error; err != nil {
+			log.WithError(err).WithFields(log.Fields{"video_id": videoID}).Warn("Failed to record video metadata read")
+		}
+	}
+
+	video := &Video{
+		ID:          row.ID,
+		Title:       row.Title,
+		Description: row.Description,
+		Author:      row.Author,
+		Views:       row.Views,
+		Duration:    time.Duration(row.Duration) * time.Second,
+		PublishDate: row.PublishDate,
+		Thumbnail:   row.Thumbnail,
+		Live:        row.Live,
+	}
+	if row.FetchedAt != nil {
+		video.FetchedAt = *row.FetchedAt
+	}
+	if row.Chapters != "" {
+		if err := json.Unmarshal([]byte(row.Chapters), &video.Chapters); err != nil {
+			return nil, err
+		}
+	}
+	if row.Format != "" {
+		if err := json.Unmarshal([]byte(row.Format), &video.Format); err != nil {
+			return nil, err
+		}
+	}
+	return video, nil
+}
+
+// Put inserts or updates the video within the songs table, leaving availability and anything playlists set alone
+func (t dbTier) Put(ctx context.Context, video *Video) error {
+	if t.db == nil {
+		return nil
+	}
+
+	fetchedAt, readAt := video.FetchedAt, time.Now()
+	chapters := ""
+	if len(video.Chapters) > 0 {
+		data, err := json.Marshal(video.Chapters)
+		if err != nil {
+			return err
+		}
+		chapters = string(data)
+	}
+	format := ""
+	if video.Format != nil {
+		data, err := json.Marshal(video.Format)
+		if err != nil {
+			return err
+		}
+		format = string(data)
+	}
+	row := songRow{
+		ID:          video.ID,
+		Title:       video.Title,
+		Author:      video.Author,
+		Views:       video.Views,
+		Description: video.Description,
+		Duration:    int64(video.Duration.Seconds()),
+		PublishDate: video.PublishDate,
+		URL:         "https://www.youtube.com/watch?v=" + video.ID,
+		Thumbnail:   video.Thumbnail,
+		Live:        video.Live,
+		FetchedAt:   &fetchedAt,
+		Chapters:    chapters,
+		Format:      format,
+		ReadAt:      &readAt,
+	}
+	updates := clause.AssignmentColumns([]string{"title", "author", "views", "description", "duration", "publish_date", "thumbnail", "live", "fetched_at", "chapters", "read_at"})
+	// Background refreshes don't know the format, which is kept until the audio is downloaded again
+	updates = append(updates, clause.Assignment{
+		Column: clause.Column{Name: "format"},
+		Value:  gorm.Expr("COALESCE(NULLIF(excluded.format, ''), songs.format)"),
+	})
+	return t.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: updates,
+	}).Create(&row).Error
+}
+
+// StartMetadataRefresh refreshes a batch of the stalest playlist songs every interval, deleting unused songs
+func StartMetadataRefresh(db *gorm.DB, interval time.Duration, batchSize int) {
+	if db == nil {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			routineMetadataRefresh(db, batchSize)
+			if days := viper.GetInt("metadata.retention"); days > 0 {
+				pruneUnusedSongs(db, time.Duration(days)*24*time.Hour)
+			}
+		}
+	}()
+}
+
+// routineMetadataRefresh refreshes the stale playlist songs fetched longest ago, skipping songs which can no longer be
+// played
+func routineMetadataRefresh(db *gorm.DB, batchSize int) {
+	staleAfter := time.Duration(viper.GetInt("metadata.stale_after")) * time.Hour
+	if staleAfter <= 0 {
+		return // Stored metadata never goes stale
+	}
+	var videoIDs []string
+	err := db.Model(&songRow{}).
+		Where("unavailable_reason = ''").
+		// Other songs are refreshed when read while stale, refreshing them here would keep every song ever played current
+		Where("id IN (SELECT song_id FROM playlists) OR id IN (SELECT song_id FROM guild_playlist_songs)").
+		Where("fetched_at IS NULL OR fetched_at < ?", time.Now().Add(-staleAfter)).
+		Order("fetched_at NULLS FIRST").
+		Limit(batchSize).
+		Pluck("id", &videoIDs).Error
+	if err != nil {
+		log.WithError(err).Error("Failed to load songs for metadata refresh")
+		return
+	}
+
+	ym := NewYouTubeManager(redis_client.RDB)
+	refreshed := 0
+	for _, videoID := range videoIDs {
+		if _, err := ym.refresh(context.Background(), videoID, nil); err != nil {
+			log.WithError(err).WithFields(log.Fields{"video_id": videoID}).Warn("Failed to refresh video metadata")
+			continue
+		}
+		refreshed++
+	}
+	if len(videoIDs) > 0 {
+		log.WithFields(log.Fields{"refreshed": refreshed, "failed": len(videoIDs) - refreshed}).Info("Refreshed video metadata")
+	}
+}
+
+// pruneUnusedSongs deletes the songs outside every playlist which haven't been read within retention
+func pruneUnusedSongs(db *gorm.DB, retention time.Duration) {
+	result := db.Exec(`
+		DELETE FROM songs
+		WHERE (read_at IS NULL OR read_at < ?)
+		  AND NOT EXISTS (SELECT 1 FROM playlists WHERE song_id = songs.id)
+		  AND NOT EXISTS (SELECT 1 FROM guild_playlist_songs WHERE song_id = songs.id)
+	`, time.Now().Add(-retention))
+	if result.Error != nil {
+		log.WithError(result.Error).Error("Failed to delete unused songs")
+		return
+	}
+	if result.RowsAffected > 0 {
+		log.WithFields(log.Fields{"deleted": result.RowsAffected}).Info("Deleted unused songs")
+	}
+}
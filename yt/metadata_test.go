@@ -0,0 +1,72 @@
+package yt
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryTier_EvictsLeastRecentlyRead(t *testing.T) {
+	tier := NewMemoryTier(2)
+	ctx := context.Background()
+
+	tier.Put(ctx, &Video{ID: "a"})
+	tier.Put(ctx, &Video{ID: "b"})
+	_, err := tier.Get(ctx, "a")
+	assert.NoError(t, err)
+	tier.Put(ctx, &Video{ID: "c"})
+
+	assert.Equal(t, 2, tier.Len())
+	_, err = tier.Get(ctx, "b")
+	assert.ErrorIs(t, err, ErrNotCached)
+	_, err = tier.Get(ctx, "a")
+	assert.NoError(t, err)
+}
+
+func TestMemoryTier_ReturnsCopies(t *testing.T) {
+	tier := NewMemoryTier(1)
+	ctx := context.Background()
+
+	video := &Video{ID: "a", Title: "Before"}
+	tier.Put(ctx, video)
+	video.Title = "After"
+
+	cached, _ := tier.Get(ctx, "a")
+	assert.Equal(t, "Before", cached.Title)
+	cached.Title = "Changed"
+	cached, _ = tier.Get(ctx, "a")
+	assert.Equal(t, "Before", cached.Title)
+}
+
+func TestVideoStale(t *testing.T) {
+	viper.Set("metadata.stale_after", 24)
+	t.Cleanup(func() { viper.Set("metadata.stale_after", nil) })
+
+	assert.False(t, (&Video{FetchedAt: time.Now().Add(-time.Hour)}).Stale())
+	assert.True(t, (&Video{FetchedAt: time.Now().Add(-48 * time.Hour)}).Stale())
+	assert.True(t, (&Video{}).Stale())
+
+	viper.Set("metadata.stale_after", 0)
+	assert.False(t, (&Video{}).Stale())
+}
+
+func TestSongRowReadRecorded(t *testing.T) {
+	now := time.Now()
+	hourAgo := now.Add(-time.Hour)
+	daysAgo := now.Add(-48 * time.Hour)
+
+	assert.True(t, songRow{ReadAt: &hourAgo}.readRecorded(now))
+	assert.False(t, songRow{ReadAt: &daysAgo}.readRecorded(now))
+	assert.False(t, songRow{}.readRecorded(now))
+}
+
+func TestDBTier_WithoutDatabase(t *testing.T) {
+	tier := DBTier(nil)
+
+	_, err := tier.Get(context.Background(), "a")
+	assert.ErrorIs(t, err, ErrNotCached)
+	assert.NoError(t, tier.Put(context.Background(), &Video{ID: "a"}))
+}
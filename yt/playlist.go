@@ -0,0 +1,119 @@
+package yt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Playlist is a YouTube playlist along with the entries within the range it was listed for
+type Playlist struct {
+	ID        string
+	Title     string
+	Uploader  string
+	Thumbnail string
+	Count     int             // Videos within the whole playlist, 0 when YouTube didn't say
+	Entries   []PlaylistEntry // Videos within the listed range only
+}
+
+// PlaylistEntry is a video as listed within a playlist, without fetching its full metadata
+type PlaylistEntry struct {
+	ID       string
+	Title    string
+	Duration time.Duration
+	Index    int // Position within the playlist, counting from 1
+}
+
+// playlistItems returns the --playlist-items range of yt-dlp for entries start to end, counting from 1 and up to the
+// last entry when end is 0
+func playlistItems(start, end int) string {
+	start = max(start, 1)
+	if end <= 0 {
+		return fmt.Sprintf("%d:", start)
+	}
+	return fmt.Sprintf("%d:%d", start, end)
+}
+
+// parsePlaylist reads the JSON yt-dlp prints for a flat playlist, numbering entries from start
+func parsePlaylist(out []byte, start int) (*Playlist, error) {
+	var data struct {
+		ID            string `json:"id"`
+		Title         string `json:"title"`
+		Uploader      string `json:"uploader"`
+		Channel       string `json:"channel"`
+		PlaylistCount int    `json:"playlist_count"`
+		Thumbnails    []struct {
+			URL string `json:"url"`
+		} `json:"thumbnails"`
+		Entries []struct {
+			ID       string  `json:"id"`
+			Title    string  `json:"title"`
+			Duration float64 `json:"duration"`
+		} `json:"entries"`
+	}
+	if err := json.Unmarshal(out, &data); err != nil {
+		return nil, err
+	}
+
+	playlist := &Playlist{
+		ID:       data.ID,
+		Title:    data.Title,
+		Uploader: data.Uploader,
+		Count:    data.PlaylistCount,
+	}
+	if playlist.Uploader == "" {
+		playlist.Uploader = data.Channel
+	}
+	if len(data.Thumbnails) > 0 {
+		playlist.Thumbnail = data.Thumbnails[len(data.Thumbnails)-1].URL // Largest last
+	}
+
+	index := max(start, 1)
+	for _, entry := range data.Entries {
+		if entry.ID != "" {
+			playlist.Entries = append(playlist.Entries, PlaylistEntry{
+				ID:       entry.ID,
+				Title:    entry.Title,
+				Duration: time.Duration(entry.Duration * float64(time.Second)),
+				Index:    index,
+			})
+		}
+		index++
+	}
+	return playlist, nil
+}
+
+// parsePlaylistEntry reads a line yt-dlp prints for each entry of a flat playlist, false for lines without a video
+func parsePlaylistEntry(line []byte) (PlaylistEntry, bool) {
+	var data struct {
+		ID            string  `json:"id"`
+		Title         string  `json:"title"`
+		Duration      float64 `json:"duration"`
+		PlaylistIndex int     `json:"playlist_index"`
+	}
+	if err := json.Unmarshal(line, &data); err != nil || data.ID == "" {
+		return PlaylistEntry{}, false
+	}
+	return PlaylistEntry{
+		ID:       data.ID,
+		Title:    data.Title,
+		Duration: time.Duration(data.Duration * float64(time.Second)),
+		Index:    data.PlaylistIndex,
+	}, true
+}
+
+// GetPlaylist returns a playlist with its entries from start to end, counting from 1 and up to the last entry when
+// end is 0
+func (ym *YouTubeManager) GetPlaylist(ctx context.Context, playlistURL string, start, end int) (*Playlist, error) {
+	return fetch(ctx, func(ctx context.Context) (*Playlist, error) {
+		return currentBackend().Playlist(ctx, playlistURL, start, end)
+	})
+}
+
+// StreamPlaylist calls entry with each entry of a playlist from start, counting from 1, as yt-dlp lists them until
+// entry returns false
+func (ym *YouTubeManager) StreamPlaylist(ctx context.Context, playlistURL string, start int, entry func(PlaylistEntry) bool) error {
+	// Not retried, a second attempt would hand entry the entries already listed again
+	return currentBackend().StreamPlaylist(ctx, playlistURL, start, entry)
+}
@@ -0,0 +1,57 @@
+package yt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlaylistItems(t *testing.T) {
+	assert.Equal(t, "1:100", playlistItems(1, 100))
+	assert.Equal(t, "101:", playlistItems(101, 0))
+	assert.Equal(t, "1:5", playlistItems(0, 5))
+}
+
+func TestParsePlaylist(t *testing.T) {
+	out := []byte(`{
+		"id": "PL1",
+		"title": "Road Trip",
+		"channel": "Someone",
+		"playlist_count": 5000,
+		"thumbnails": [{"url": "small.jpg"}, {"url": "large.jpg"}],
+		"entries": [
+			{"id": "a1", "title": "First", "duration": 213.0},
+			{"title": "[Deleted video]"},
+			{"id": "c3", "title": "Third", "duration": 95.5}
+		]
+	}`)
+
+	playlist, err := parsePlaylist(out, 101)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Road Trip", playlist.Title)
+	assert.Equal(t, "Someone", playlist.Uploader)
+	assert.Equal(t, "large.jpg", playlist.Thumbnail)
+	assert.Equal(t, 5000, playlist.Count)
+	assert.Equal(t, []PlaylistEntry{
+		{ID: "a1", Title: "First", Duration: 213 * time.Second, Index: 101},
+		{ID: "c3", Title: "Third", Duration: 95500 * time.Millisecond, Index: 103},
+	}, playlist.Entries)
+}
+
+func TestParsePlaylist_Invalid(t *testing.T) {
+	_, err := parsePlaylist([]byte("not json"), 1)
+	assert.Error(t, err)
+}
+
+func TestParsePlaylistEntry(t *testing.T) {
+	entry, ok := parsePlaylistEntry([]byte(`{"id": "a", "title": "One", "duration": 61.5, "playlist_index": 101}`))
+	assert.True(t, ok)
+	assert.Equal(t, PlaylistEntry{ID: "a", Title: "One", Duration: 61500 * time.Millisecond, Index: 101}, entry)
+
+	_, ok = parsePlaylistEntry([]byte(`{"title": "[Deleted video]"}`))
+	assert.False(t, ok)
+	_, ok = parsePlaylistEntry([]byte("WARNING: not json"))
+	assert.False(t, ok)
+}
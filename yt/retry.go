@@ -0,0 +1,42 @@
+package yt
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+
+	"github.com/Strum355/log"
+	"github.com/spf13/viper"
+)
+
+const maxRetryBackoff = 30 * time.Second
+
+// retryBackoff is the wait before the first retry, doubling with each attempt after it, shortened within tests
+var retryBackoff = time.Second
+
+// retry runs op until it succeeds, fails for good or runs out of attempts, waiting out a jittered backoff after each
+// temporary failure
+func retry(ctx context.Context, op func() error) error {
+	attempts := max(viper.GetInt("youtube.retries"), 0) + 1
+	for attempt := 0; ; attempt++ {
+		err := op()
+		if err == nil || !Retryable(err) || attempt+1 >= attempts || ctx.Err() != nil {
+			return err
+		}
+
+		wait := backoff(attempt)
+		log.WithError(err).WithFields(log.Fields{"attempt": attempt + 1, "wait": wait.String()}).Warn("Retrying YouTube fetch")
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// backoff returns the wait before the given retry, picked at random from the upper half of an exponential backoff so
+// downloads failing together don't all retry at once
+func backoff(attempt int) time.Duration {
+	wait := min(retryBackoff<<attempt, maxRetryBackoff)
+	return wait/2 + rand.N(wait/2+1)
+}
@@ -0,0 +1,42 @@
+package yt
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrNotCached is returned by a Store for keys it holds no value for
+var ErrNotCached = errors.New("not cached")
+
+// Store caches values for the YouTubeManager, Redis outside of tests
+type Store interface {
+	Get(ctx context.Context, key string) (string, error) // Fails with ErrNotCached for missing or expired keys
+	Set(ctx context.Context, key string, value any, ttl time.Duration) error
+}
+
+// redisStore implements Store on a Redis client
+type redisStore struct {
+	rdb *redis.Client
+}
+
+// NewRedisStore returns a Store keeping values within Redis
+func NewRedisStore(rdb *redis.Client) Store {
+	return redisStore{rdb: rdb}
+}
+
+// Get returns the value of key
+func (s redisStore) Get(ctx context.Context, key string) (string, error) {
+	value, err := s.rdb.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrNotCached
+	}
+	return value, err
+}
+
+// Set stores value under key for ttl, forever when ttl is 0
+func (s redisStore) Set(ctx context.Context, key string, value any, ttl time.Duration) error {
+	return s.rdb.Set(ctx, key, value, ttl).Err()
+}
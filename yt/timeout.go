@@ -0,0 +1,27 @@
+package yt
+
+import (
+	"context"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// WithMetadataTimeout bounds a metadata fetch, search or playlist listing by the configured timeout
+func WithMetadataTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, "youtube.timeout.metadata", 30*time.Second)
+}
+
+// WithDownloadTimeout bounds a single audio download by the configured timeout
+func WithDownloadTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, "youtube.timeout.download", 5*time.Minute)
+}
+
+// withTimeout applies the timeout in seconds under key, using fallback when it isn't set
+func withTimeout(ctx context.Context, key string, fallback time.Duration) (context.Context, context.CancelFunc) {
+	timeout := time.Duration(viper.GetInt(key)) * time.Second
+	if timeout <= 0 {
+		timeout = fallback
+	}
+	return context.WithTimeout(ctx, timeout)
+}
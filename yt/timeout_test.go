@@ -0,0 +1,31 @@
+package yt
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithMetadataTimeout(t *testing.T) {
+	viper.Set("youtube.timeout.metadata", 2)
+	t.Cleanup(func() { viper.Set("youtube.timeout.metadata", nil) })
+
+	ctx, cancel := WithMetadataTimeout(context.Background())
+	defer cancel()
+
+	deadline, ok := ctx.Deadline()
+	assert.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(2*time.Second), deadline, 100*time.Millisecond)
+}
+
+func TestWithDownloadTimeout_Fallback(t *testing.T) {
+	ctx, cancel := WithDownloadTimeout(context.Background())
+	defer cancel()
+
+	deadline, ok := ctx.Deadline()
+	assert.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(5*time.Minute), deadline, 100*time.Millisecond)
+}
@@ -1,76 +1,88 @@
 package yt
 
 import (
-	"Twilight/utils"
+	"bufio"
 	"bytes"
-	"encoding/json"
-	"errors"
-	"fmt"
-	"io"
-	"os"
+	"context"
 	"os/exec"
 	"time"
-
-	"github.com/kkdai/youtube/v2"
 )
 
-// DownloadAudioFile downloads the audio from a given videoID directly to a file
-func DownloadAudioFile(videoID string) error {
-	filename := utils.GetAudioFile(videoID)
-
-	err := youTubeDownload(videoID, filename)
-	if err == nil {
-		return nil
-	}
-
-	cmd := exec.Command("yt-dlp",
-		"-f", "bestaudio/best",
-		"-x",
-		"--audio-format", "opus",
-		"--buffer-size", "16K",
-		"-o", filename,
-		"https://www.youtube.com/watch?v="+videoID,
-	)
+// DownloadAudioFile downloads the audio of a video to a file with the configured format policy, returning the format
+// picked and retrying temporary failures
+func DownloadAudioFile(ctx context.Context, videoID, filename string) (*AudioFormat, error) {
+	policy := CurrentFormatPolicy()
+	var format *AudioFormat
+	err := retry(ctx, func() error {
+		ctx, cancel := WithDownloadTimeout(ctx)
+		defer cancel()
+
+		var err error
+		format, err = currentBackend().Download(ctx, videoID, filename, policy)
+		return err
+	})
+	return format, err
+}
 
+// RunYtDlp runs yt-dlp with the given arguments, returning its output or its classified error output as the error
+func RunYtDlp(ctx context.Context, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "yt-dlp", args...)
 	stderr := &bytes.Buffer{}
 	cmd.Stderr = stderr
 
-	err = cmd.Run()
-	if err != nil {
-		return errors.New(stderr.String())
+	out, err := cmd.Output()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
 	}
-
-	return nil
-}
-
-// youTubeDownload downloads audio from a given videoID directly to a file using YouTube client
-func youTubeDownload(videoID, filename string) error {
-	client := youtube.Client{}
-	video, err := client.GetVideo("https://www.youtube.com/watch?v=" + videoID)
 	if err != nil {
-		return err
+		return nil, classifyYtDlpError(stderr.String())
 	}
+	return out, nil
+}
 
-	formats := video.Formats.WithAudioChannels()
+// StreamYtDlp runs yt-dlp with the given arguments, calling line with each line of its output as it is printed until
+// line returns false, which stops yt-dlp
+func StreamYtDlp(ctx context.Context, line func([]byte) bool, args ...string) error {
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
-	stream, _, err := client.GetStream(video, &formats[0])
+	cmd := exec.CommandContext(ctx, "yt-dlp", args...)
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	stdout, err := cmd.StdoutPipe()
 	if err != nil {
 		return err
 	}
-	defer stream.Close()
-
-	file, err := os.Create(filename)
-	if err != nil {
+	if err := cmd.Start(); err != nil {
 		return err
 	}
-	defer file.Close()
 
-	_, err = io.Copy(file, stream)
-	if err != nil {
-		os.Remove(filename)
-		return err
+	stopped := false
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if !line(scanner.Bytes()) {
+			stopped = true
+			break
+		}
+	}
+	scanErr := scanner.Err()
+	if stopped || scanErr != nil {
+		cancel() // Kills yt-dlp rather than leaving it blocked writing output nobody reads
 	}
 
+	err = cmd.Wait()
+	switch {
+	case parent.Err() != nil:
+		return parent.Err()
+	case stopped:
+		return nil
+	case scanErr != nil:
+		return scanErr
+	case err != nil:
+		return classifyYtDlpError(stderr.String())
+	}
 	return nil
 }
 
@@ -83,68 +95,36 @@ type Video struct {
 	Duration    time.Duration
 	PublishDate time.Time
 	Thumbnail   string
+	Live        bool         // True for live streams, which have no duration and can't be downloaded
+	Format      *AudioFormat // Stream the cached audio was downloaded from, nil until it is downloaded
+	FetchedAt   time.Time    // When the metadata was fetched from YouTube, zero for metadata stored before it was recorded
+	Chapters    []Chapter    // Chapter markers in order, nil when the video has none
 }
 
-// FetchVideoMetadata fetches basic metadata for a given videoID
-func FetchVideoMetadata(videoID string) (*Video, error) {
-	videoID, _ = youtube.ExtractVideoID(videoID)
-	url := fmt.Sprintf("https://www.youtube.com/watch?v=%s", videoID)
-
-	video, err := youTubeMetadata(videoID)
-	if err == nil {
-		return video, nil
-	}
-
-	cmd := exec.Command("yt-dlp", "--dump-single-json", "--skip-download", "--no-playlist", url)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return nil, fmt.Errorf("yt-dlp failed: %w", err)
-	}
-
-	var data struct {
-		ID          string `json:"id"`
-		Title       string `json:"title"`
-		Description string `json:"description"`
-		Uploader    string `json:"uploader"`
-		ViewCount   int    `json:"view_count"`
-		Duration    int    `json:"duration"`
-		UploadDate  string `json:"upload_date"`
-		Thumbnail   string `json:"thumbnail"`
-	}
-
-	if err := json.Unmarshal(output, &data); err != nil {
-		return nil, err
-	}
-
-	publishDate, _ := time.Parse("20060102", data.UploadDate)
+// fetch runs a metadata fetch, giving each attempt the metadata timeout and retrying temporary failures
+func fetch[T any](ctx context.Context, op func(ctx context.Context) (T, error)) (T, error) {
+	var result T
+	err := retry(ctx, func() error {
+		ctx, cancel := WithMetadataTimeout(ctx)
+		defer cancel()
 
-	return &Video{
-		ID:          data.ID,
-		Title:       data.Title,
-		Description: data.Description,
-		Author:      data.Uploader,
-		Views:       data.ViewCount,
-		Duration:    time.Duration(data.Duration) * time.Second,
-		PublishDate: publishDate,
-		Thumbnail:   data.Thumbnail,
-	}, nil
+		var err error
+		result, err = op(ctx)
+		return err
+	})
+	return result, err
 }
 
-// youTubeMetadata fetches basic metadata for a given videoID using YouTube client
-func youTubeMetadata(videoID string) (*Video, error) {
-	client := youtube.Client{}
-	v, err := client.GetVideo(videoID)
-	if err != nil {
-		return nil, err
-	}
+// FetchVideoMetadata fetches basic metadata for a given videoID, retrying temporary failures
+func FetchVideoMetadata(ctx context.Context, videoID string) (*Video, error) {
+	return fetch(ctx, func(ctx context.Context) (*Video, error) {
+		return currentBackend().Metadata(ctx, videoID)
+	})
+}
 
-	return &Video{
-		ID:          v.ID,
-		Title:       v.Title,
-		Author:      v.Author,
-		Views:       v.Views,
-		Duration:    v.Duration,
-		PublishDate: v.PublishDate,
-		Thumbnail:   v.Thumbnails[0].URL,
-	}, nil
+// LiveStreamURL returns the URL ffmpeg can read a live stream from, these expire after a few hours
+func LiveStreamURL(ctx context.Context, videoID string) (string, error) {
+	return fetch(ctx, func(ctx context.Context) (string, error) {
+		return currentBackend().StreamURL(ctx, videoID)
+	})
 }
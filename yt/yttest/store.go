@@ -0,0 +1,89 @@
+package yttest
+
+import (
+	"Twilight/yt"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Store is an in memory stand-in for the Redis cache behind a yt.YouTubeManager
+type Store struct {
+	mu      sync.Mutex
+	now     func() time.Time
+	entries map[string]entry
+}
+
+// entry is a stored value along with when it expires, zero for never
+type entry struct {
+	value   string
+	expires time.Time
+}
+
+// NewStore returns an empty store
+func NewStore() *Store {
+	return &Store{now: time.Now, entries: map[string]entry{}}
+}
+
+// Get returns the value of key, failing with yt.ErrNotCached once it expired
+func (s *Store) Get(ctx context.Context, key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[key]
+	if !ok || (!e.expires.IsZero() && !s.now().Before(e.expires)) {
+		delete(s.entries, key)
+		return "", yt.ErrNotCached
+	}
+	return e.value, nil
+}
+
+// Set stores value under key for ttl, forever when ttl is 0, formatting values the way Redis does
+func (s *Store) Set(ctx context.Context, key string, value any, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := entry{value: format(value)}
+	if ttl > 0 {
+		e.expires = s.now().Add(ttl)
+	}
+	s.entries[key] = e
+	return nil
+}
+
+// Advance moves the clock of the store forward, expiring keys as Redis would
+func (s *Store) Advance(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	s.now = func() time.Time { return now.Add(d) }
+}
+
+// Keys returns how many keys hold a value that hasn't expired
+func (s *Store) Keys() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count := 0
+	for _, e := range s.entries {
+		if e.expires.IsZero() || s.now().Before(e.expires) {
+			count++
+		}
+	}
+	return count
+}
+
+// format converts a value to the string Redis would store for it
+func format(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	default:
+		return fmt.Sprint(v)
+	}
+}
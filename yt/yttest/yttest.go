@@ -0,0 +1,244 @@
+// Package yttest provides fakes of the yt backends and Redis cache so code reaching YouTube can be tested offline
+package yttest
+
+import (
+	"Twilight/yt"
+	"context"
+	"encoding/binary"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	sampleRate  = 48000
+	channels    = 2
+	audioLength = time.Second // Length of every generated audio file, whatever the duration of the video
+)
+
+// Backend is a fake yt.Backend serving fixture metadata and writing generated audio files
+type Backend struct {
+	mu        sync.Mutex
+	videos    map[string]*yt.Video
+	playlists map[string]*yt.Playlist
+	results   map[string][]yt.SearchResult
+	errors    map[string]error
+	calls     map[string]int
+}
+
+// NewBackend returns a fake backend without any videos
+func NewBackend() *Backend {
+	return &Backend{
+		videos:    map[string]*yt.Video{},
+		playlists: map[string]*yt.Playlist{},
+		results:   map[string][]yt.SearchResult{},
+		errors:    map[string]error{},
+		calls:     map[string]int{},
+	}
+}
+
+// Use makes b the backend of the yt package until the test ends
+func Use(t testing.TB, b yt.Backend) {
+	previous := yt.SetBackend(b)
+	t.Cleanup(func() { yt.SetBackend(previous) })
+}
+
+// AddVideo serves the metadata of a video, its audio is generated when downloaded
+func (b *Backend) AddVideo(video *yt.Video) *Backend {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.videos[video.ID] = video
+	return b
+}
+
+// AddPlaylist serves a playlist of the given videos, entries take their title and duration from videos added before
+func (b *Backend) AddPlaylist(playlistURL, title string, videoIDs ...string) *Backend {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	playlist := &yt.Playlist{ID: playlistURL, Title: title, Uploader: "yttest", Count: len(videoIDs)}
+	for i, videoID := range videoIDs {
+		entry := yt.PlaylistEntry{ID: videoID, Index: i + 1}
+		if video, ok := b.videos[videoID]; ok {
+			entry.Title, entry.Duration = video.Title, video.Duration
+		}
+		playlist.Entries = append(playlist.Entries, entry)
+	}
+	b.playlists[playlistURL] = playlist
+	return b
+}
+
+// AddSearch serves search results for a query
+func (b *Backend) AddSearch(query string, results ...yt.SearchResult) *Backend {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.results[query] = results
+	return b
+}
+
+// Fail makes every call about a video ID, playlist URL or search query fail with err
+func (b *Backend) Fail(key string, err error) *Backend {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.errors[key] = err
+	return b
+}
+
+// Calls returns how many times a method was called for a video ID, playlist URL or search query
+func (b *Backend) Calls(method, key string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.calls[method+":"+key]
+}
+
+// record counts a call and returns the failure set for its key
+func (b *Backend) record(method, key string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.calls[method+":"+key]++
+	return b.errors[key]
+}
+
+// video returns the fixture of a video, failing like YouTube does for videos it doesn't know
+func (b *Backend) video(videoID string) (*yt.Video, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	video, ok := b.videos[videoID]
+	if !ok {
+		return nil, &yt.FetchError{Kind: yt.ErrRemoved, Reason: "Video unavailable"}
+	}
+	copied := *video
+	return &copied, nil
+}
+
+// Metadata returns the fixture of a video
+func (b *Backend) Metadata(ctx context.Context, videoID string) (*yt.Video, error) {
+	if err := b.record("Metadata", videoID); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return b.video(videoID)
+}
+
+// Download writes a second of silence to filename for any known video that isn't live
+func (b *Backend) Download(ctx context.Context, videoID, filename string, policy yt.FormatPolicy) (*yt.AudioFormat, error) {
+	if err := b.record("Download", videoID); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	video, err := b.video(videoID)
+	if err != nil {
+		return nil, err
+	}
+	if video.Live {
+		return nil, errors.New("live streams can't be downloaded")
+	}
+	if err := os.WriteFile(filename, SilentWAV(audioLength), 0o644); err != nil {
+		return nil, err
+	}
+	return &yt.AudioFormat{ID: "yttest", Codec: "pcm", Bitrate: sampleRate * channels * 16 / 1000}, nil
+}
+
+// StreamURL returns a made up manifest URL for live videos
+func (b *Backend) StreamURL(ctx context.Context, videoID string) (string, error) {
+	if err := b.record("StreamURL", videoID); err != nil {
+		return "", err
+	}
+	video, err := b.video(videoID)
+	if err != nil {
+		return "", err
+	}
+	if !video.Live {
+		return "", errors.New("video isn't live")
+	}
+	return "https://yttest.invalid/live/" + videoID + ".m3u8", nil
+}
+
+// Playlist returns a range of a playlist, failing for unknown playlists
+func (b *Backend) Playlist(ctx context.Context, playlistURL string, start, end int) (*yt.Playlist, error) {
+	if err := b.record("Playlist", playlistURL); err != nil {
+		return nil, err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	playlist, ok := b.playlists[playlistURL]
+	if !ok {
+		return nil, &yt.FetchError{Kind: yt.ErrRemoved, Reason: "The playlist does not exist"}
+	}
+
+	start = max(start, 1)
+	if end <= 0 || end > len(playlist.Entries) {
+		end = len(playlist.Entries)
+	}
+	page := *playlist
+	page.Entries = nil
+	if start <= end {
+		page.Entries = append([]yt.PlaylistEntry(nil), playlist.Entries[start-1:end]...)
+	}
+	return &page, nil
+}
+
+// StreamPlaylist lists a playlist from start one entry at a time, failing for unknown playlists
+func (b *Backend) StreamPlaylist(ctx context.Context, playlistURL string, start int, entry func(yt.PlaylistEntry) bool) error {
+	if err := b.record("StreamPlaylist", playlistURL); err != nil {
+		return err
+	}
+	b.mu.Lock()
+	playlist, ok := b.playlists[playlistURL]
+	var entries []yt.PlaylistEntry
+	if ok && start <= len(playlist.Entries) {
+		entries = append(entries, playlist.Entries[max(start, 1)-1:]...)
+	}
+	b.mu.Unlock()
+	if !ok {
+		return &yt.FetchError{Kind: yt.ErrRemoved, Reason: "The playlist does not exist"}
+	}
+
+	for _, e := range entries {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if !entry(e) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// Search returns up to limit results served for the query, none for unknown queries
+func (b *Backend) Search(ctx context.Context, query string, limit int) ([]yt.SearchResult, error) {
+	if err := b.record("Search", query); err != nil {
+		return nil, err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	results := b.results[query]
+	return append([]yt.SearchResult(nil), results[:min(limit, len(results))]...), nil
+}
+
+// SilentWAV returns a 48kHz stereo WAV file of silence lasting d, which ffmpeg plays like any downloaded audio
+func SilentWAV(d time.Duration) []byte {
+	samples := int(d.Seconds() * sampleRate)
+	dataSize := samples * channels * 2
+	blockAlign := channels * 2
+
+	wav := make([]byte, 44+dataSize)
+	copy(wav[0:], "RIFF")
+	binary.LittleEndian.PutUint32(wav[4:], uint32(36+dataSize))
+	copy(wav[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(wav[16:], 16) // Size of the format chunk
+	binary.LittleEndian.PutUint16(wav[20:], 1)  // PCM
+	binary.LittleEndian.PutUint16(wav[22:], channels)
+	binary.LittleEndian.PutUint32(wav[24:], sampleRate)
+	binary.LittleEndian.PutUint32(wav[28:], uint32(sampleRate*blockAlign))
+	binary.LittleEndian.PutUint16(wav[32:], uint16(blockAlign))
+	binary.LittleEndian.PutUint16(wav[34:], 16) // Bits per sample
+	copy(wav[36:], "data")
+	binary.LittleEndian.PutUint32(wav[40:], uint32(dataSize))
+	return wav
+}
@@ -0,0 +1,54 @@
+package yttest
+
+import (
+	"Twilight/yt"
+	"context"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSilentWAV(t *testing.T) {
+	wav := SilentWAV(500 * time.Millisecond)
+
+	assert.Equal(t, "RIFF", string(wav[0:4]))
+	assert.Equal(t, "WAVE", string(wav[8:12]))
+	assert.Equal(t, uint32(len(wav)-8), binary.LittleEndian.Uint32(wav[4:]))
+	assert.Equal(t, uint32(48000*2*2/2), binary.LittleEndian.Uint32(wav[40:]))
+}
+
+func TestStore_Expiry(t *testing.T) {
+	store := NewStore()
+	ctx := context.Background()
+
+	store.Set(ctx, "short", true, time.Minute)
+	store.Set(ctx, "forever", []byte("value"), 0)
+
+	value, err := store.Get(ctx, "short")
+	assert.NoError(t, err)
+	assert.Equal(t, "1", value)
+
+	store.Advance(time.Hour)
+	_, err = store.Get(ctx, "short")
+	assert.ErrorIs(t, err, yt.ErrNotCached)
+
+	value, err = store.Get(ctx, "forever")
+	assert.NoError(t, err)
+	assert.Equal(t, "value", value)
+	assert.Equal(t, 1, store.Keys())
+}
+
+func TestBackend_Download(t *testing.T) {
+	backend := NewBackend().AddVideo(&yt.Video{ID: "live", Live: true})
+
+	_, err := backend.Download(context.Background(), "missing", t.TempDir()+"/a.opus", yt.FormatPolicy{})
+	assert.ErrorIs(t, err, yt.ErrRemoved)
+	_, err = backend.Download(context.Background(), "live", t.TempDir()+"/b.opus", yt.FormatPolicy{})
+	assert.Error(t, err)
+
+	url, err := backend.StreamURL(context.Background(), "live")
+	assert.NoError(t, err)
+	assert.Contains(t, url, "live")
+}